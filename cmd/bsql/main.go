@@ -11,7 +11,7 @@ import (
 	"github.com/abiosoft/readline"
 	"github.com/sirupsen/logrus"
 
-	_ "github.com/luw2007/bitable-mysql-driver/driver"
+	"github.com/luw2007/bitable-mysql-driver/driver"
 )
 
 var (
@@ -24,12 +24,18 @@ type Handler struct {
 }
 
 func NewHandler(dsn string) *Handler {
-	db, err := sql.Open("bitable", dsn)
+	cfg, err := driver.ParseDSN(dsn)
+	if err != nil {
+		logrus.WithError(err).Fatal("parse dsn error")
+	}
+	if *debugFlag && driver.ParseLogLevel(cfg.LogLevel) > driver.LevelDebug {
+		cfg.LogLevel = "debug"
+	}
+	connector, err := driver.NewConnector(cfg)
 	if err != nil {
 		logrus.WithError(err).Fatal("connect open api error")
-		panic(err)
 	}
-	return &Handler{Conn: db}
+	return &Handler{Conn: sql.OpenDB(connector)}
 }
 
 func (h Handler) SQL(sql string) {
@@ -96,7 +102,8 @@ func main() {
 	logrus.SetOutput(os.Stdout)
 
 	if flag.NArg() == 0 {
-		logrus.Fatal("need dsn like: bitable://<app_id>:<app_secret>@open.feishu.cn/<app_token>")
+		logrus.Fatal("need dsn like: bitable://<app_id>:<app_secret>@open.feishu.cn/<app_token>, " +
+			"the host may be feishu, lark or host:port/path of a private deployment")
 	}
 	NewHandler(flag.Args()[0]).Start()
 }
@@ -19,11 +19,12 @@ var (
 	SRCAppToken = flag.String("f", "", "from bitable app token")
 	SRCTable    = flag.String("fd", "", "from bitable table name, optional")
 	DSTAppToken = flag.String("t", "", "to bitable app token")
+	domainFlag  = flag.String("domain", "feishu", "open api: feishu, lark, host[:port][/path] or url of a private deployment")
 )
 
 func main() {
 	flag.Parse()
-	domain := "https://open.feishu.cn"
+	domain := lark.BaseURL(*domainFlag)
 	if appID == "" || appSecret == "" || *SRCAppToken == "" || *DSTAppToken == "" {
 		flag.CommandLine.Usage()
 		return
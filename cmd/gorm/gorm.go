@@ -40,13 +40,17 @@ var (
 	appSecret  = os.Getenv("APP_SECRET")
 	appToken   = os.Getenv("APP_TOKEN")
 	testTable1 = os.Getenv("TABLE_1") + "5"
-
-	dsn = fmt.Sprintf("bitable://%s:%s@open.feishu.cn/%s?log_level=trace", appID, appSecret, appToken)
+	// DOMAIN is feishu, lark or the host[:port][/path] of a private deployment
+	domain = os.Getenv("DOMAIN")
 )
 
 func main() {
 	logrus.SetLevel(logrus.DebugLevel)
 	logrus.SetOutput(os.Stdout)
+	if domain == "" {
+		domain = "feishu"
+	}
+	dsn := fmt.Sprintf("bitable://%s:%s@%s/%s?log_level=trace", appID, appSecret, domain, appToken)
 	sqlDB, err := sql.Open("bitable", dsn)
 	if err != nil {
 		panic(err)
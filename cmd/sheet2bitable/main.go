@@ -17,11 +17,12 @@ var (
 
 	sheetTokenFlag = flag.String("s", "", "sheet token")
 	VerboseFlag    = flag.Bool("v", false, "verbose")
+	domainFlag     = flag.String("domain", "feishu", "open api: feishu, lark, host[:port][/path] or url of a private deployment")
 )
 
 func main() {
 	flag.Parse()
-	domain := "https://open.feishu.cn"
+	domain := lark.BaseURL(*domainFlag)
 	s := lark.NewSheetsClient(appID, appSecret, domain, "trace")
 	sheets, err := s.GetAPP(context.Background(), *sheetTokenFlag)
 	if err != nil {
@@ -5,9 +5,9 @@ import (
 	"database/sql/driver"
 	"errors"
 	"fmt"
+	"sync"
 
 	"github.com/pingcap/parser"
-	"github.com/sirupsen/logrus"
 
 	"github.com/luw2007/bitable-mysql-driver/internal/lark"
 )
@@ -17,26 +17,104 @@ func WithUserToken(ctx context.Context, userToken string) context.Context {
 	return lark.WithUserToken(ctx, userToken)
 }
 
-// Conn for db open
+// Conn for db open, a Conn has its own parser and session, and shares the lark client of the connector.
 type Conn struct {
+	// schemaVersion change after a DDL, it's the first field to be aligned for atomic
+	schemaVersion uint64
 	*lark.BiTable
 	parser    *parser.Parser
 	AppID     string
 	AppSecret string
 	AppToken  string
+
+	cfg *Config
+	// mu guard the parser and the session, rows may call into the Conn from other goroutines
+	mu     sync.Mutex
+	closed bool
+	// bad is set when the credentials are rejected after getting new tokens
+	bad   bool
+	plans *planCache
+	// userToken is set by `SET @@user_access_token`, nil for the identity of the DSN
+	userToken  *string
+	userTokens *userTokenSource
+}
+
+// newConn create a Conn of the normalized cfg.
+func newConn(client *lark.BiTable, cfg *Config) *Conn {
+	return &Conn{
+		BiTable:   client,
+		parser:    newParser(cfg),
+		AppID:     cfg.AppID,
+		AppSecret: cfg.AppSecret,
+		AppToken:  cfg.AppToken,
+		cfg:       cfg,
+	}
+}
+
+// config return the configuration of the Conn, the defaults for a Conn created without one.
+func (c *Conn) config() *Config {
+	if c.cfg == nil {
+		return NewConfig()
+	}
+	return c.cfg
+}
+
+// sessionContext add the user_access_token of the session to ctx, the token of ctx is used first.
+func (c *Conn) sessionContext(ctx context.Context) (context.Context, error) {
+	if lark.UserTokenOf(ctx) != "" {
+		return ctx, nil
+	}
+	token, err := c.sessionUserToken(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("[bitable driver] %w", err)
+	}
+	if token != "" {
+		return lark.WithUserToken(ctx, token), nil
+	}
+	return ctx, nil
+}
+
+// sessionUserToken return the user_access_token of the session, empty for the tenant_access_token.
+func (c *Conn) sessionUserToken(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	token := c.userToken
+	c.mu.Unlock()
+	switch {
+	case token != nil:
+		return *token, nil
+	case c.userTokens != nil:
+		return c.userTokens.token(ctx)
+	default:
+		return c.config().UserToken, nil
+	}
+}
+
+// setUserToken change the identity of the session, nil restore the identity of the DSN.
+func (c *Conn) setUserToken(token *string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.userToken = token
+}
+
+// newParser create a sql parser, window functions are enabled by cfg.WindowFunc,
+// their names like rank and row_number are keywords then.
+func newParser(cfg *Config) *parser.Parser {
+	p := parser.New()
+	p.EnableWindowFunc(cfg.WindowFunc)
+	return p
 }
 
 // Ping check client connection
 func (c *Conn) Ping(ctx context.Context) error {
-	_, err := c.GetApp(ctx, c.AppToken)
-	return err
+	_, err := c.GetApp(ctx, c.appToken())
+	return c.checkError(err, "", false)
 }
 
 // QueryContext the context timeout and return when the context is canceled.
 func (c *Conn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
 	stmt, err := c.PrepareContext(ctx, query)
 	if err != nil {
-		return nil, fmt.Errorf("[bitable driver] %w", err)
+		return nil, err
 	}
 	return stmt.(driver.StmtQueryContext).QueryContext(ctx, args)
 }
@@ -57,18 +135,90 @@ func (c *Conn) Prepare(query string) (driver.Stmt, error) {
 
 // PrepareContext statement for prepare exec
 func (c *Conn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
-	logrus.Debugf("[bitable driver]  do prepare, sql: %s", query)
+	c.log(ctx, LevelDebug, "[bitable driver] prepare", "sql", redact(query))
+	if !c.IsValid() {
+		return nil, driver.ErrBadConn
+	}
 
-	return &bitableStatement{
+	ctx, err := c.sessionContext(ctx)
+	if err != nil {
+		return nil, c.checkError(err, query, false)
+	}
+	stmt := &bitableStatement{
 		conn:  c,
 		ctx:   ctx,
 		query: query,
-	}, nil
+	}
+	if err := stmt.prepare(ctx); err != nil {
+		return nil, c.checkError(err, query, false)
+	}
+	return stmt, nil
 }
 
-// Close db connection close
+// Close release the plans, the lark client is shared by the other Conns of the connector.
 func (c *Conn) Close() error {
-	return errors.New("can't close connection")
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	c.plans = nil
+	return nil
+}
+
+// ResetSession implement driver.SessionResetter, restore the app token changed by `USE` and
+// the identity changed by `SET @@user_access_token`.
+func (c *Conn) ResetSession(context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed || c.bad {
+		return driver.ErrBadConn
+	}
+	c.AppToken = c.config().AppToken
+	c.userToken = nil
+	return nil
+}
+
+// IsValid implement driver.Validator.
+func (c *Conn) IsValid() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return !c.closed && !c.bad
+}
+
+// checkError convert err to an *Error of query. The Conn is bad when the tenant or app token is still rejected
+// after getting new tokens, the pool open a new Conn. ErrBadConn is reported unless written, database/sql
+// run the statement again on a new Conn and the changes sent would be applied twice.
+// A rejected user token is the identity of the caller, the Conn stays good and the token of the DSN is refreshed.
+func (c *Conn) checkError(err error, query string, written bool) error {
+	err = newError(err, query)
+	var e *Error
+	if !errors.As(err, &e) || !errors.Is(err, ErrInvalidToken) {
+		return err
+	}
+	if !lark.TenantTokenRejected(err) {
+		if c.userTokens != nil {
+			c.userTokens.reset()
+		}
+		return e
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.bad = true
+	e.badConn = !written
+	return e
+}
+
+// appToken return the app token of the session.
+func (c *Conn) appToken() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.AppToken
+}
+
+// useApp change the app token of the session.
+func (c *Conn) useApp(appToken string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.AppToken = appToken
 }
 
 // Begin tx begin
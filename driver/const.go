@@ -9,6 +9,11 @@ const (
 const (
 	maxLoopTimes = 100
 	loadOneTime  = "ONE_TIME"
+
+	tableIDPrefix = "tbl"
+
+	// sessionUserToken is the session variable of the user_access_token
+	sessionUserToken = "user_access_token"
 )
 
 type ViewType string
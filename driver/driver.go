@@ -1,15 +1,10 @@
 package driver
 
 import (
+	"context"
 	"database/sql"
 	"database/sql/driver"
-	"errors"
-	"fmt"
-	"net/url"
-	"time"
-
-	"github.com/pingcap/parser"
-	"github.com/sirupsen/logrus"
+	"strings"
 
 	"github.com/luw2007/bitable-mysql-driver/internal/lark"
 )
@@ -19,54 +14,81 @@ type Driver struct {
 }
 
 func init() {
-	logrus.Debug("Initializing bitable driver")
 	sql.Register("bitable", &Driver{})
 }
 
 // Open for implement driver interface
-func (driver *Driver) Open(name string) (driver.Conn, error) {
-	u, err := url.Parse(name)
+func (d *Driver) Open(name string) (driver.Conn, error) {
+	c, err := d.OpenConnector(name)
 	if err != nil {
-		return nil, fmt.Errorf("parse dsn error: %w", err)
-	}
-	if u.Scheme != BitableSchema {
-		return nil, fmt.Errorf("[bitable driver]  unsupported scheme %s", u.Scheme)
+		return nil, err
 	}
+	return c.Connect(context.Background())
+}
 
-	logrus.Debug("[bitable driver]  exec open")
-
-	domain := fmt.Sprintf("https://%s", u.Hostname())
-
-	appID := u.User.Username()
-	appSecret, ok := u.User.Password()
-	if len(appID) == 0 || !ok {
-		return nil, errors.New("[bitable driver]  needed username and password")
-	}
-	logLevel := "info"
-	querys := u.Query()
-	if l := querys.Get("log_level"); l != "" {
-		logLevel = l
+// OpenConnector implement driver.DriverContext, the Conns of the connector share the lark client of the DSN.
+func (d *Driver) OpenConnector(name string) (driver.Connector, error) {
+	cfg, err := ParseDSN(name)
+	if err != nil {
+		return nil, err
 	}
-	if debug := querys.Get("debug"); debug != "" {
-		logLevel = "trace"
+	return d.newConnector(cfg)
+}
+
+func (d *Driver) newConnector(cfg *Config) (driver.Connector, error) {
+	if err := cfg.normalize(); err != nil {
+		return nil, err
 	}
-	timeoutStr := querys.Get("timeout")
-	if timeoutStr == "" {
-		timeoutStr = "5s"
+	rootCAs, err := cfg.rootCAs()
+	if err != nil {
+		return nil, err
 	}
-	ts, err := time.ParseDuration(timeoutStr)
+	telemetry, err := cfg.telemetry()
 	if err != nil {
-		return nil, fmt.Errorf("parse timeout err, timeout:%s, %w", timeoutStr, err)
+		return nil, err
 	}
+	client := lark.NewClient(lark.ClientConfig{
+		AppID:      cfg.AppID,
+		AppSecret:  cfg.AppSecret,
+		BaseURL:    cfg.baseURL(),
+		LogLevel:   cfg.LogLevel,
+		Timeout:    cfg.Timeout,
+		MaxRetries: cfg.MaxRetries,
+		HTTPClient: cfg.HTTPClient,
+		RootCAs:    rootCAs,
+		Telemetry:  telemetry,
+		Logger:     cfg.Logger,
+	})
+	client.Logger().Log(context.Background(), LevelDebug, "[bitable driver] open connector",
+		"domain", cfg.Domain, "app_token", cfg.AppToken, "log_level", cfg.LogLevel)
+	if len(cfg.unknownOptions) > 0 {
+		client.Logger().Log(context.Background(), LevelWarn, "[bitable driver] unknown dsn options are ignored",
+			"options", strings.Join(cfg.unknownOptions, ","))
+	}
+	return &connector{
+		driver:     d,
+		cfg:        cfg,
+		client:     client,
+		userTokens: newUserTokenSource(client, cfg),
+	}, nil
+}
 
-	logrus.Debugf("[bitable driver]  log level %v", logLevel)
+// connector hand out Conns with their own parser and session.
+type connector struct {
+	driver     *Driver
+	cfg        *Config
+	client     *lark.BiTable
+	userTokens *userTokenSource
+}
 
-	conn := &Conn{
-		BiTable:   lark.NewLarkClient(appID, appSecret, domain, logLevel, ts),
-		AppID:     appID,
-		AppSecret: appSecret,
-		parser:    parser.New(),
-		AppToken:  u.Path[1:],
-	}
+// Connect implement driver.Connector.
+func (c *connector) Connect(context.Context) (driver.Conn, error) {
+	conn := newConn(c.client, c.cfg)
+	conn.userTokens = c.userTokens
 	return conn, nil
 }
+
+// Driver implement driver.Connector.
+func (c *connector) Driver() driver.Driver {
+	return c.driver
+}
@@ -32,8 +32,10 @@ func TestMain(m *testing.M) {
 
 	code := m.Run()
 
-	// clean table2
-	cleanTable(testTable2)
+	// clean table2, only when run against a real bitable
+	if appID != "" {
+		cleanTable(testTable2)
+	}
 	os.Exit(code)
 }
 
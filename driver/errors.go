@@ -0,0 +1,109 @@
+package driver
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"regexp"
+
+	"github.com/luw2007/bitable-mysql-driver/internal/lark"
+)
+
+// the sentinel errors of the open api codes, check them with errors.Is.
+var (
+	ErrAppNotFound      = errors.New("app not found")
+	ErrTableNotFound    = errors.New("table not found")
+	ErrViewNotFound     = errors.New("view not found")
+	ErrFieldNotFound    = errors.New("field not found")
+	ErrRecordNotFound   = errors.New("record not found")
+	ErrPermissionDenied = errors.New("permission denied")
+	ErrRateLimited      = errors.New("rate limited")
+	ErrInvalidToken     = errors.New("invalid access token")
+)
+
+// apiCodes are the open api codes of the sentinel errors.
+var apiCodes = map[error][]int64{
+	ErrAppNotFound:      {1254003, 1254040},
+	ErrTableNotFound:    {1254004, 1254041},
+	ErrViewNotFound:     {1254005, 1254042},
+	ErrFieldNotFound:    {1254009, 1254044, 1254045},
+	ErrRecordNotFound:   {1254006, 1254043},
+	ErrPermissionDenied: {1254301, 1254302, 91403, 99991672, 99991679},
+	ErrRateLimited:      {1254290, 99991400},
+	ErrInvalidToken:     {99991661, 99991663, 99991664, 99991668, 99991677},
+}
+
+// Error is an error of the driver, Code, LogID and Endpoint are set when the open api failed.
+type Error struct {
+	Code     int64  // the code of the open api, 0 for the errors of the driver
+	Message  string // the message without the prefix of the driver
+	LogID    string // the log id of the request, for the support of the open platform
+	Endpoint string // the method and path of the request, like `GET /open-apis/bitable/v1/apps/:app_token`
+	SQL      string // the statement failed, normalized without the values like the logs
+	Err      error  // the cause
+
+	// badConn is set when the credentials of the Conn are rejected before a change is sent,
+	// database/sql run the statement again on a new Conn
+	badConn bool
+}
+
+func (e *Error) Error() string {
+	return "[bitable driver] " + e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is match the sentinel errors by the code of the open api.
+func (e *Error) Is(target error) bool {
+	for _, code := range apiCodes[target] {
+		if e.Code == code {
+			return true
+		}
+	}
+	switch target {
+	case ErrRateLimited:
+		var apiErr *lark.APIError
+		return errors.As(e.Err, &apiErr) && apiErr.RateLimited()
+	case ErrInvalidToken:
+		return lark.InvalidToken(e.Err)
+	case driver.ErrBadConn:
+		return e.badConn
+	}
+	return false
+}
+
+var driverPrefix = regexp.MustCompile(`\[bitable driver]\s*`)
+
+// newError convert err to an *Error of query, the prefix of the driver is kept once.
+func newError(err error, query string) error {
+	if err == nil {
+		return nil
+	}
+	var e *Error
+	if errors.As(err, &e) && e == err {
+		if e.SQL == "" && query != "" {
+			e.SQL = redact(query)
+		}
+		return e
+	}
+	e = &Error{Message: driverPrefix.ReplaceAllString(err.Error(), ""), Err: err}
+	if query != "" {
+		e.SQL = redact(query)
+	}
+	var inner *Error
+	var apiErr *lark.APIError
+	switch {
+	case errors.As(err, &inner):
+		e.Code, e.LogID, e.Endpoint = inner.Code, inner.LogID, inner.Endpoint
+	case errors.As(err, &apiErr):
+		e.Code, e.LogID, e.Endpoint = apiErr.Code, apiErr.LogID, apiErr.Method+" "+apiErr.Path
+	}
+	return e
+}
+
+// unknownColumn is the error of a column not in the table.
+func unknownColumn(name interface{}, clause string) error {
+	return &Error{Message: fmt.Sprintf("unknown column '%v' in '%s'", name, clause), Err: ErrFieldNotFound}
+}
@@ -0,0 +1,645 @@
+package driver
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pingcap/parser/ast"
+	"github.com/pingcap/parser/mysql"
+	"github.com/pingcap/parser/opcode"
+	"github.com/pingcap/parser/test_driver"
+)
+
+// evalEnv resolve the column values of the current row while evaluating an expression.
+type evalEnv struct {
+	index  map[string]int
+	values []driver.Value
+}
+
+func newEvalEnv(columns []string) *evalEnv {
+	index := make(map[string]int, len(columns))
+	for i, name := range columns {
+		if _, ok := index[name]; !ok {
+			index[name] = i
+		}
+	}
+	return &evalEnv{index: index}
+}
+
+func (e *evalEnv) lookup(column *ast.ColumnName) (interface{}, error) {
+	if e != nil {
+		if i, ok := e.index[column.Name.O]; ok {
+			return normalizeValue(e.values[i]), nil
+		}
+	}
+	return nil, &Error{Message: fmt.Sprintf("unknown column '%s'", column.Name.O), Err: ErrFieldNotFound}
+}
+
+// normalizeValue convert a driver value to the value kinds used by eval:
+// nil, int64, float64, string and time.Time.
+func normalizeValue(v interface{}) interface{} {
+	switch n := v.(type) {
+	case []byte:
+		return string(n)
+	case bool:
+		if n {
+			return int64(1)
+		}
+		return int64(0)
+	case int:
+		return int64(n)
+	case int8:
+		return int64(n)
+	case int16:
+		return int64(n)
+	case int32:
+		return int64(n)
+	case uint:
+		return int64(n)
+	case uint8:
+		return int64(n)
+	case uint16:
+		return int64(n)
+	case uint32:
+		return int64(n)
+	case uint64:
+		// an unsigned integer over the range of BIGINT is kept
+		if n > math.MaxInt64 {
+			return n
+		}
+		return int64(n)
+	case float32:
+		return float64(n)
+	case *test_driver.MyDecimal:
+		f, err := strconv.ParseFloat(n.String(), 64)
+		if err != nil {
+			return n.String()
+		}
+		return f
+	}
+	return v
+}
+
+// eval evaluate expr against the row of env with MySQL semantics.
+func (stmt *bitableStatement) eval(expr ast.ExprNode, env *evalEnv) (interface{}, error) {
+	switch e := expr.(type) {
+	case *test_driver.ParamMarkerExpr:
+		if v, ok := stmt.args[e.Offset]; ok {
+			return normalizeValue(v.Value), nil
+		}
+		return normalizeValue(e.GetValue()), nil
+	case *test_driver.ValueExpr:
+		if e.Kind() == test_driver.KindNull {
+			return nil, nil
+		}
+		return normalizeValue(e.GetValue()), nil
+	case *ast.ColumnNameExpr:
+		return env.lookup(e.Name)
+	case *outerColumnExpr:
+		return e.env.lookup(e.Name)
+	case *ast.SubqueryExpr:
+		return stmt.evalScalarSubquery(e)
+	case *ast.ExistsSubqueryExpr:
+		return stmt.evalExists(e)
+	case *ast.CompareSubqueryExpr:
+		return stmt.evalCompareSubquery(e, env)
+	case *ast.ParenthesesExpr:
+		return stmt.eval(e.Expr, env)
+	case *ast.UnaryOperationExpr:
+		v, err := stmt.eval(e.V, env)
+		if err != nil || v == nil {
+			return nil, err
+		}
+		switch e.Op {
+		case opcode.Minus:
+			if i, ok := v.(int64); ok {
+				if i == math.MinInt64 {
+					return nil, fmt.Errorf("BIGINT value is out of range in '-(%d)'", i)
+				}
+				return -i, nil
+			}
+			return -toFloat(v), nil
+		case opcode.Plus:
+			return v, nil
+		case opcode.Not:
+			return boolValue(!isTrue(v)), nil
+		case opcode.BitNeg:
+			return int64(^uint64(toInt(v))), nil
+		}
+		return nil, fmt.Errorf("not supported op %s", e.Op)
+	case *ast.BinaryOperationExpr:
+		return stmt.evalBinary(e, env)
+	case *ast.IsNullExpr:
+		v, err := stmt.eval(e.Expr, env)
+		if err != nil {
+			return nil, err
+		}
+		return boolValue((v == nil) != e.Not), nil
+	case *ast.IsTruthExpr:
+		v, err := stmt.eval(e.Expr, env)
+		if err != nil {
+			return nil, err
+		}
+		truth := v != nil && isTrue(v) == (e.True != 0)
+		return boolValue(truth != e.Not), nil
+	case *ast.BetweenExpr:
+		v, err := stmt.eval(e.Expr, env)
+		if err != nil {
+			return nil, err
+		}
+		left, err := stmt.eval(e.Left, env)
+		if err != nil {
+			return nil, err
+		}
+		right, err := stmt.eval(e.Right, env)
+		if err != nil {
+			return nil, err
+		}
+		if v == nil || left == nil || right == nil {
+			return nil, nil
+		}
+		between := compareValues(v, left) >= 0 && compareValues(v, right) <= 0
+		return boolValue(between != e.Not), nil
+	case *ast.PatternInExpr:
+		return stmt.evalIn(e, env)
+	case *ast.PatternLikeExpr:
+		v, err := stmt.eval(e.Expr, env)
+		if err != nil {
+			return nil, err
+		}
+		pattern, err := stmt.eval(e.Pattern, env)
+		if err != nil || v == nil || pattern == nil {
+			return nil, err
+		}
+		re, err := likeRegexp(toString(pattern), e.Escape)
+		if err != nil {
+			return nil, err
+		}
+		return boolValue(re.MatchString(toString(v)) != e.Not), nil
+	case *ast.PatternRegexpExpr:
+		v, err := stmt.eval(e.Expr, env)
+		if err != nil {
+			return nil, err
+		}
+		pattern, err := stmt.eval(e.Pattern, env)
+		if err != nil || v == nil || pattern == nil {
+			return nil, err
+		}
+		re, err := regexp.Compile("(?i)" + toString(pattern))
+		if err != nil {
+			return nil, err
+		}
+		return boolValue(re.MatchString(toString(v)) != e.Not), nil
+	case *ast.CaseExpr:
+		return stmt.evalCase(e, env)
+	case *ast.FuncCastExpr:
+		v, err := stmt.eval(e.Expr, env)
+		if err != nil || v == nil {
+			return nil, err
+		}
+		return castValue(v, e.Tp.Tp, mysql.HasUnsignedFlag(e.Tp.Flag))
+	case *ast.TimeUnitExpr:
+		return e.Unit, nil
+	case *ast.TrimDirectionExpr:
+		return e.Direction, nil
+	case *ast.FuncCallExpr:
+		args := make([]interface{}, 0, len(e.Args))
+		for _, a := range e.Args {
+			v, err := stmt.eval(a, env)
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, v)
+		}
+		return callFunction(e.FnName.L, args)
+	}
+	return nil, fmt.Errorf("not supported expression %T", expr)
+}
+
+func (stmt *bitableStatement) evalBinary(e *ast.BinaryOperationExpr, env *evalEnv) (interface{}, error) {
+	l, err := stmt.eval(e.L, env)
+	if err != nil {
+		return nil, err
+	}
+	r, err := stmt.eval(e.R, env)
+	if err != nil {
+		return nil, err
+	}
+	switch e.Op {
+	case opcode.LogicAnd:
+		// false AND NULL is false
+		if (l != nil && !isTrue(l)) || (r != nil && !isTrue(r)) {
+			return int64(0), nil
+		}
+		if l == nil || r == nil {
+			return nil, nil
+		}
+		return int64(1), nil
+	case opcode.LogicOr:
+		// true OR NULL is true
+		if (l != nil && isTrue(l)) || (r != nil && isTrue(r)) {
+			return int64(1), nil
+		}
+		if l == nil || r == nil {
+			return nil, nil
+		}
+		return int64(0), nil
+	case opcode.NullEQ:
+		if l == nil || r == nil {
+			return boolValue(l == nil && r == nil), nil
+		}
+		return boolValue(compareValues(l, r) == 0), nil
+	}
+	if l == nil || r == nil {
+		return nil, nil
+	}
+	switch e.Op {
+	case opcode.LogicXor:
+		return boolValue(isTrue(l) != isTrue(r)), nil
+	case opcode.EQ:
+		return boolValue(compareValues(l, r) == 0), nil
+	case opcode.NE:
+		return boolValue(compareValues(l, r) != 0), nil
+	case opcode.LT:
+		return boolValue(compareValues(l, r) < 0), nil
+	case opcode.LE:
+		return boolValue(compareValues(l, r) <= 0), nil
+	case opcode.GT:
+		return boolValue(compareValues(l, r) > 0), nil
+	case opcode.GE:
+		return boolValue(compareValues(l, r) >= 0), nil
+	case opcode.Plus, opcode.Minus, opcode.Mul, opcode.Div, opcode.IntDiv, opcode.Mod:
+		return arithmetic(e.Op, l, r)
+	case opcode.And:
+		return toInt(l) & toInt(r), nil
+	case opcode.Or:
+		return toInt(l) | toInt(r), nil
+	case opcode.Xor:
+		return toInt(l) ^ toInt(r), nil
+	case opcode.LeftShift:
+		return int64(uint64(toInt(l)) << uint64(toInt(r))), nil
+	case opcode.RightShift:
+		return int64(uint64(toInt(l)) >> uint64(toInt(r))), nil
+	}
+	return nil, fmt.Errorf("not supported op %s", e.Op)
+}
+
+func (stmt *bitableStatement) evalIn(e *ast.PatternInExpr, env *evalEnv) (interface{}, error) {
+	if e.Sel != nil {
+		return stmt.evalInSubquery(e, env)
+	}
+	v, err := stmt.eval(e.Expr, env)
+	if err != nil || v == nil {
+		return nil, err
+	}
+	hasNull := false
+	for _, item := range e.List {
+		iv, err := stmt.eval(item, env)
+		if err != nil {
+			return nil, err
+		}
+		if iv == nil {
+			hasNull = true
+			continue
+		}
+		if compareValues(v, iv) == 0 {
+			return boolValue(!e.Not), nil
+		}
+	}
+	if hasNull {
+		return nil, nil
+	}
+	return boolValue(e.Not), nil
+}
+
+func (stmt *bitableStatement) evalCase(e *ast.CaseExpr, env *evalEnv) (interface{}, error) {
+	var value interface{}
+	if e.Value != nil {
+		v, err := stmt.eval(e.Value, env)
+		if err != nil {
+			return nil, err
+		}
+		value = v
+	}
+	for _, when := range e.WhenClauses {
+		cond, err := stmt.eval(when.Expr, env)
+		if err != nil {
+			return nil, err
+		}
+		matched := false
+		if e.Value != nil {
+			matched = value != nil && cond != nil && compareValues(value, cond) == 0
+		} else {
+			matched = cond != nil && isTrue(cond)
+		}
+		if matched {
+			return stmt.eval(when.Result, env)
+		}
+	}
+	if e.ElseClause != nil {
+		return stmt.eval(e.ElseClause, env)
+	}
+	return nil, nil
+}
+
+func boolValue(b bool) int64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func isTrue(v interface{}) bool {
+	switch n := v.(type) {
+	case nil:
+		return false
+	case int64:
+		return n != 0
+	case time.Time:
+		return !n.IsZero()
+	}
+	return toFloat(v) != 0
+}
+
+func isNumber(v interface{}) bool {
+	switch v.(type) {
+	case int64, uint64, float64:
+		return true
+	}
+	return false
+}
+
+// toFloat convert v to float64, a string use its numeric prefix like MySQL.
+func toFloat(v interface{}) float64 {
+	switch n := v.(type) {
+	case int64:
+		return float64(n)
+	case uint64:
+		return float64(n)
+	case float64:
+		return n
+	case string:
+		return parseNumberPrefix(n)
+	case time.Time:
+		f, _ := strconv.ParseFloat(n.Format("20060102150405"), 64)
+		return f
+	}
+	return 0
+}
+
+func toInt(v interface{}) int64 {
+	switch n := v.(type) {
+	case int64:
+		return n
+	case uint64:
+		return int64(n)
+	}
+	return int64(math.Round(toFloat(v)))
+}
+
+var numberPrefix = regexp.MustCompile(`^\s*[-+]?(\d+(\.\d*)?|\.\d+)([eE][-+]?\d+)?`)
+
+func parseNumberPrefix(s string) float64 {
+	f, _ := strconv.ParseFloat(strings.TrimSpace(numberPrefix.FindString(s)), 64)
+	return f
+}
+
+func toString(v interface{}) string {
+	switch n := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return n
+	case int64:
+		return strconv.FormatInt(n, 10)
+	case uint64:
+		return strconv.FormatUint(n, 10)
+	case float64:
+		return strconv.FormatFloat(n, 'f', -1, 64)
+	case time.Time:
+		if n.Hour() == 0 && n.Minute() == 0 && n.Second() == 0 && n.Nanosecond() == 0 {
+			return n.Format("2006-01-02")
+		}
+		return n.Format("2006-01-02 15:04:05")
+	}
+	return fmt.Sprint(v)
+}
+
+var timeLayouts = []string{
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	"2006/01/02 15:04:05",
+	"2006/01/02",
+	"20060102150405",
+	"20060102",
+	time.RFC3339Nano,
+}
+
+// toTime convert v to time.Time, return false when v isn't a valid date.
+func toTime(v interface{}) (time.Time, bool) {
+	switch n := v.(type) {
+	case time.Time:
+		return n, true
+	case string:
+		s := strings.TrimSpace(n)
+		for _, layout := range timeLayouts {
+			if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+				return t, true
+			}
+		}
+	case int64, float64:
+		return toTime(toString(n))
+	}
+	return time.Time{}, false
+}
+
+// compareValues compare two not null values, numbers win over strings and dates win over strings.
+func compareValues(a, b interface{}) int {
+	if ta, ok := a.(time.Time); ok {
+		if tb, ok := toTime(b); ok {
+			return compareTime(ta, tb)
+		}
+	}
+	if tb, ok := b.(time.Time); ok {
+		if ta, ok := toTime(a); ok {
+			return compareTime(ta, tb)
+		}
+	}
+	if isNumber(a) || isNumber(b) {
+		ia, aInt := a.(int64)
+		ib, bInt := b.(int64)
+		if aInt && bInt {
+			return compareOrdered(ia < ib, ia > ib)
+		}
+		fa, fb := toFloat(a), toFloat(b)
+		return compareOrdered(fa < fb, fa > fb)
+	}
+	sa, sb := strings.ToLower(toString(a)), strings.ToLower(toString(b))
+	return strings.Compare(sa, sb)
+}
+
+func compareTime(a, b time.Time) int {
+	return compareOrdered(a.Before(b), a.After(b))
+}
+
+func compareOrdered(less, greater bool) int {
+	if less {
+		return -1
+	}
+	if greater {
+		return 1
+	}
+	return 0
+}
+
+// arithmetic compute l op r, the integers are exact and their overflow is an error like MySQL.
+func arithmetic(op opcode.Op, l, r interface{}) (interface{}, error) {
+	il, lInt := l.(int64)
+	ir, rInt := r.(int64)
+	if lInt && rInt {
+		var v int64
+		overflow := false
+		switch op {
+		case opcode.Plus:
+			v = il + ir
+			overflow = (ir > 0 && v < il) || (ir < 0 && v > il)
+		case opcode.Minus:
+			v = il - ir
+			overflow = (ir < 0 && v < il) || (ir > 0 && v > il)
+		case opcode.Mul:
+			v = il * ir
+			overflow = il != 0 && (v/il != ir || (il == -1 && ir == math.MinInt64))
+		case opcode.IntDiv:
+			if ir == 0 {
+				return nil, nil
+			}
+			v = il / ir
+			overflow = il == math.MinInt64 && ir == -1
+		case opcode.Mod:
+			if ir == 0 {
+				return nil, nil
+			}
+			if ir == -1 {
+				return int64(0), nil
+			}
+			return il % ir, nil
+		default:
+			return arithmeticFloat(op, toFloat(l), toFloat(r)), nil
+		}
+		if overflow {
+			var b strings.Builder
+			op.Format(&b)
+			return nil, fmt.Errorf("BIGINT value is out of range in '(%d %s %d)'", il, b.String(), ir)
+		}
+		return v, nil
+	}
+	return arithmeticFloat(op, toFloat(l), toFloat(r)), nil
+}
+
+func arithmeticFloat(op opcode.Op, fl, fr float64) interface{} {
+	switch op {
+	case opcode.Plus:
+		return fl + fr
+	case opcode.Minus:
+		return fl - fr
+	case opcode.Mul:
+		return fl * fr
+	case opcode.Div:
+		if fr == 0 {
+			return nil
+		}
+		return fl / fr
+	case opcode.IntDiv:
+		if fr == 0 {
+			return nil
+		}
+		return int64(fl / fr)
+	case opcode.Mod:
+		if fr == 0 {
+			return nil
+		}
+		return math.Mod(fl, fr)
+	}
+	return nil
+}
+
+// likeRegexp translate a LIKE pattern to a case-insensitive regexp.
+func likeRegexp(pattern string, escape byte) (*regexp.Regexp, error) {
+	if escape == 0 {
+		escape = '\\'
+	}
+	buff := strings.Builder{}
+	buff.WriteString("(?is)^")
+	runes := []rune(pattern)
+	for i := 0; i < len(runes); i++ {
+		c := runes[i]
+		switch {
+		case c == rune(escape) && i+1 < len(runes):
+			i++
+			buff.WriteString(regexp.QuoteMeta(string(runes[i])))
+		case c == '%':
+			buff.WriteString(".*")
+		case c == '_':
+			buff.WriteString(".")
+		default:
+			buff.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	buff.WriteString("$")
+	return regexp.Compile(buff.String())
+}
+
+func castValue(v interface{}, tp byte, unsigned bool) (interface{}, error) {
+	switch tp {
+	case mysql.TypeVarString, mysql.TypeVarchar, mysql.TypeString, mysql.TypeBlob:
+		return toString(v), nil
+	case mysql.TypeLonglong:
+		var i int64
+		switch n := v.(type) {
+		case string:
+			if unsigned {
+				// the integers over the range of BIGINT are exact
+				if u, err := strconv.ParseUint(strings.TrimSpace(n), 10, 64); err == nil {
+					return normalizeValue(u), nil
+				}
+			}
+			i = int64(parseNumberPrefix(n))
+		case float64:
+			i = int64(math.Round(n))
+		default:
+			i = toInt(v)
+		}
+		if unsigned {
+			// the negative integers wrap around like MySQL, CAST(-1 AS UNSIGNED) is 18446744073709551615
+			return normalizeValue(uint64(i)), nil
+		}
+		return i, nil
+	case mysql.TypeNewDecimal, mysql.TypeDouble, mysql.TypeFloat:
+		return toFloat(v), nil
+	case mysql.TypeDate:
+		t, ok := toTime(v)
+		if !ok {
+			return nil, nil
+		}
+		return truncateDay(t), nil
+	case mysql.TypeDatetime, mysql.TypeTimestamp:
+		t, ok := toTime(v)
+		if !ok {
+			return nil, nil
+		}
+		return t, nil
+	}
+	return nil, errors.New("not supported cast type")
+}
+
+func truncateDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
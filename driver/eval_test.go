@@ -0,0 +1,113 @@
+package driver
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEvalFunctions(t *testing.T) {
+	db := newMockDB(t, newMockTable())
+	tests := []struct {
+		expr string
+		want interface{}
+	}{
+		{"1 + 2 * 3", int64(7)},
+		{"7 / 2", 3.5},
+		{"7 DIV 2", int64(3)},
+		{"1 / 0", nil},
+		{"'3' + 1", 4.0},
+		{"NULL + 1", nil},
+		{"CONCAT('a', 'b', 1)", "ab1"},
+		{"CONCAT('a', NULL)", nil},
+		{"CONCAT_WS('-', 'a', NULL, 'b')", "a-b"},
+		{"UPPER('abc')", "ABC"},
+		{"CHAR_LENGTH('多维表格')", int64(4)},
+		{"SUBSTRING('hello', 2, 3)", "ell"},
+		{"SUBSTRING('hello', -3)", "llo"},
+		{"TRIM('  x  ')", "x"},
+		{"TRIM(LEADING 'x' FROM 'xxyxx')", "yxx"},
+		{"LPAD('7', 3, '0')", "007"},
+		{"RPAD('7', 3, 'ab')", "7ab"},
+		{"LPAD('7', 9223372036854775807, '0')", nil},
+		{"RPAD('7', 67108865, '0')", nil},
+		{"REPEAT('ab', 3)", "ababab"},
+		{"REPEAT('ab', 9223372036854775807)", nil},
+		{"REPEAT('ab', 33554433)", nil},
+		{"LENGTH(REPEAT('ab', 33554432))", int64(67108864)},
+		{"LOCATE('b', 'abcb', 3)", int64(4)},
+		{"REPLACE('a-b-c', '-', '+')", "a+b+c"},
+		{"ROUND(2.567, 2)", 2.57},
+		{"ROUND(2.5)", int64(3)},
+		{"FLOOR(-1.5)", int64(-2)},
+		{"CEIL(1e20)", 1e20},
+		{"FLOOR(-1e20)", -1e20},
+		{"ROUND(1e20)", 1e20},
+		{"ROUND(-9.3e18, -2)", -9.3e18},
+		{"TRUNCATE(1e19, 0)", 1e19},
+		{"TRUNCATE(-9.2e18, 0)", int64(-9200000000000000000)},
+		{"MOD(10, 3)", int64(1)},
+		{"GREATEST(1, 3, 2)", int64(3)},
+		{"IF(1 > 2, 'yes', 'no')", "no"},
+		{"IFNULL(NULL, 0)", int64(0)},
+		{"NULLIF(1, 1)", nil},
+		{"COALESCE(NULL, NULL, 'c')", "c"},
+		{"CASE 2 WHEN 1 THEN 'one' WHEN 2 THEN 'two' END", "two"},
+		{"CASE WHEN 1 > 2 THEN 'a' ELSE 'b' END", "b"},
+		{"'abc' LIKE 'A%'", int64(1)},
+		{"'a_c' LIKE 'a\\_c'", int64(1)},
+		{"2 BETWEEN 1 AND 3", int64(1)},
+		{"3 IN (1, 2, NULL)", nil},
+		{"NULL IS NULL", int64(1)},
+		{"CAST('12abc' AS SIGNED)", int64(12)},
+		{"CAST(-1 AS UNSIGNED)", uint64(18446744073709551615)},
+		{"CAST(-1 AS UNSIGNED) > 0", int64(1)},
+		{"CAST(3 AS UNSIGNED)", int64(3)},
+		{"CAST('18446744073709551614' AS UNSIGNED)", uint64(18446744073709551614)},
+		{"9223372036854775807 - 1", int64(9223372036854775806)},
+		{"-9223372036854775807 - 1", int64(-9223372036854775808)},
+		{"4611686018427387904 * -2", int64(-9223372036854775808)},
+		{"DATE_FORMAT('2021-03-04 05:06:07', '%Y-%m-%d %H:%i:%s %W')", "2021-03-04 05:06:07 Thursday"},
+		{"DATE_FORMAT(DATE_ADD('2021-01-31', INTERVAL 1 MONTH), '%Y-%m-%d')", "2021-02-28"},
+		{"DATEDIFF('2021-03-01', '2021-02-27')", int64(2)},
+		{"YEAR('2021-03-04')", int64(2021)},
+		{"WEEKDAY('2021-03-04')", int64(3)},
+		{"TIMESTAMPDIFF(MONTH, '2021-01-31', '2021-03-30')", int64(1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			_, res := queryAll(t, db, "SELECT "+tt.expr)
+			assert.Len(t, res, 1)
+			assert.Equal(t, tt.want, res[0][0])
+		})
+	}
+
+	for expr, want := range map[string]string{
+		"9223372036854775807 + 1":                 "'(9223372036854775807 + 1)'",
+		"-9223372036854775807 - 2":                "'(-9223372036854775807 - 2)'",
+		"4611686018427387904 * 2":                 "'(4611686018427387904 * 2)'",
+		"(-9223372036854775807 - 1) DIV -1":       "'(-9223372036854775808 DIV -1)'",
+		"-(-9223372036854775807 - 1)":             "'-(-9223372036854775808)'",
+		"CAST(9223372036854775807 AS SIGNED) + 1": "'(9223372036854775807 + 1)'",
+	} {
+		_, err := db.Query("SELECT " + expr)
+		assert.EqualError(t, err, "[bitable driver] BIGINT value is out of range in "+want, expr)
+	}
+}
+
+func TestSelectExpressions(t *testing.T) {
+	table := newSalesTable()
+	table.fields = append(table.fields, newMockField("created", FieldTypeDate))
+	table.records[0].Fields["created"] = float64(time.Date(2021, 3, 4, 0, 0, 0, 0, time.Local).UnixNano() / 1e6)
+	db := newMockDB(t, table)
+
+	columns, res := queryAll(t, db, "SELECT amount * 2 AS twice, CONCAT(name, '@'), DATE_FORMAT(created, '%Y-%m'), IFNULL(created, 'none') AS c FROM tbl")
+	assert.Equal(t, []string{"record_id", "twice", "CONCAT(name, '@')", "DATE_FORMAT(created, '%Y-%m')", "c"}, columns)
+	assert.Equal(t, []interface{}{"rec1", 6.0, "apple@", "2021-03", time.Date(2021, 3, 4, 0, 0, 0, 0, time.Local)}, res[0])
+	assert.Equal(t, []interface{}{"rec2", 2.0, "banana@", nil, "none"}, res[1])
+	assert.Equal(t, `["amount","name","created"]`, *table.listRequests()[0].FieldNames)
+
+	_, err := db.Query("SELECT missing + 1 FROM tbl")
+	assert.Error(t, err)
+}
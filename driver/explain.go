@@ -0,0 +1,164 @@
+package driver
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/pingcap/parser/ast"
+	"github.com/pingcap/parser/format"
+
+	"github.com/luw2007/bitable-mysql-driver/internal/lark"
+)
+
+// explainColumns are the columns of EXPLAIN.
+var explainColumns = []string{"id", "operator", "table", "detail", "api_calls"}
+
+// explainPlan collect the operators of a statement in the order they run, the statement isn't executed.
+type explainPlan struct {
+	steps [][]interface{}
+}
+
+// explainStmt return the plan of a SELECT, UNION, UPDATE or DELETE, `EXPLAIN table` describe the table.
+// The fields of the tables are loaded and the uncorrelated subqueries run to build the filter formula.
+func (stmt *bitableStatement) explainStmt(r *rows, s *ast.ExplainStmt) (driver.Rows, error) {
+	if s.Analyze {
+		return nil, errors.New("[bitable driver] EXPLAIN ANALYZE is not supported")
+	}
+	stmt.explain = &explainPlan{}
+	defer func() { stmt.explain = nil }()
+	var source driver.Rows
+	var err error
+	switch n := s.Stmt.(type) {
+	case *ast.ShowStmt:
+		return stmt.showStmt(r, n)
+	case *ast.SelectStmt:
+		source, err = stmt.selectStmt(r, n)
+	case *ast.UnionStmt:
+		source, err = stmt.unionStmt(r, n, true)
+	case *ast.UpdateStmt:
+		_, err = stmt.updateStmt(r, n)
+	case *ast.DeleteStmt:
+		_, err = stmt.deleteStmt(r, n)
+	default:
+		return nil, fmt.Errorf("[bitable driver] EXPLAIN is not supported for %s", statementType(n))
+	}
+	if source != nil {
+		// nothing is read, the records are loaded lazily
+		_ = source.Close()
+	}
+	if err != nil {
+		return nil, err
+	}
+	items := make([]interface{}, 0, len(stmt.explain.steps))
+	for i, step := range stmt.explain.steps {
+		items = append(items, append([]interface{}{int64(i + 1)}, step...))
+	}
+	return newRowsFactory(r.Clone(explainColumns, items)), nil
+}
+
+// explainf add an operator to the plan when explaining, the details are `key: value` joined by `, `.
+func (stmt *bitableStatement) explainf(operator, table, apiCalls string, details ...string) {
+	if stmt.explain == nil {
+		return
+	}
+	stmt.explain.steps = append(stmt.explain.steps,
+		[]interface{}{operator, table, strings.Join(details, ", "), apiCalls})
+}
+
+// explainRecords add the list requests of a table, the empty params are omitted,
+// the records of recordIDs are read by id instead.
+func (stmt *bitableStatement) explainRecords(table, view, filter, sort, fieldNames string, recordIDs []string, limit int64) {
+	if stmt.explain == nil {
+		return
+	}
+	if recordIDs != nil {
+		stmt.explainf("BatchGetRecords", table, estimatePages(int64(len(recordIDs)), lark.MaxBatchGetRecords),
+			"record_ids: "+strings.Join(recordIDs, ", "))
+		return
+	}
+	pageSize := stmt.conn.config().PageSize
+	var details []string
+	for _, param := range [][2]string{{"view", view}, {"filter", filter}, {"sort", sort}, {"field_names", fieldNames}} {
+		if param[1] != "" {
+			details = append(details, param[0]+": "+param[1])
+		}
+	}
+	details = append(details, fmt.Sprintf("page_size: %d", pageSize))
+	if limit > 0 {
+		details = append(details, fmt.Sprintf("limit: %d", limit))
+	}
+	stmt.explainf("ListRecords", table, estimatePages(limit, pageSize), details...)
+}
+
+// estimatePages estimate the list requests reading limit records, one per page without a limit.
+func estimatePages(limit, pageSize int64) string {
+	if limit <= 0 {
+		return fmt.Sprintf("1 per %d records", pageSize)
+	}
+	return fmt.Sprintf("%d", (limit+pageSize-1)/pageSize)
+}
+
+// restore format node as SQL for EXPLAIN.
+func restore(node ast.Node) string {
+	var sb strings.Builder
+	flags := format.RestoreStringSingleQuotes | format.RestoreKeyWordUppercase | format.RestoreNameBackQuotes
+	if err := node.Restore(format.NewRestoreCtx(flags, &sb)); err != nil {
+		return fmt.Sprintf("%T", node)
+	}
+	return sb.String()
+}
+
+// explainOrderBy format the items sorted in driver.
+func explainOrderBy(items []orderItem) string {
+	keys := make([]string, 0, len(items))
+	for _, item := range items {
+		key := item.field
+		if item.expr != nil {
+			key = restore(item.expr)
+		}
+		if item.desc {
+			key += " DESC"
+		}
+		keys = append(keys, key)
+	}
+	return strings.Join(keys, ", ")
+}
+
+// explainColumnList format the projected columns, an alias or an expression evaluated in driver is `expr AS name`.
+func explainColumnList(columns []selectColumn, withRecordID bool) string {
+	list := make([]string, 0, len(columns)+1)
+	if withRecordID {
+		list = append(list, FieldKeyRecordID)
+	}
+	for _, column := range columns {
+		switch {
+		case column.expr != nil:
+			list = append(list, restore(column.expr)+" AS "+column.name)
+		case column.field != column.name:
+			list = append(list, column.field+" AS "+column.name)
+		default:
+			list = append(list, column.name)
+		}
+	}
+	return strings.Join(list, ", ")
+}
+
+// explainChanges add the requests of an UPDATE or DELETE, the records matching filter are listed,
+// or the records of recordIDs are read by id, then the records matching rest are filtered in driver.
+func (stmt *bitableStatement) explainChanges(operator, table string, plan *changePlan, limit int64,
+	apiCalls string, details ...string) {
+	recordLimit := limit
+	if plan.rest != nil || plan.byID {
+		recordLimit = 0
+	}
+	stmt.explainRecords(table, plan.view, plan.filter, "", "", plan.recordIDs, recordLimit)
+	if plan.rest != nil {
+		stmt.explainf("Filter", table, "0", "residual: "+restore(plan.rest))
+	}
+	if limit > 0 && recordLimit == 0 {
+		stmt.explainf("Limit", table, "0", fmt.Sprintf("limit: %d", limit))
+	}
+	stmt.explainf(operator, table, apiCalls, details...)
+}
@@ -0,0 +1,76 @@
+package driver
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExplain(t *testing.T) {
+	tests := []struct {
+		query string
+		args  []interface{}
+		want  [][]interface{}
+	}{
+		{
+			"EXPLAIN SELECT name, amount * 2 AS twice FROM tbl WHERE owner = ? ORDER BY amount DESC LIMIT 10",
+			[]interface{}{"alice"},
+			[][]interface{}{
+				{int64(1), "ListRecords", "tbl", `filter: CurrentValue.[owner] = "alice", sort: ["amount DESC"], page_size: 50, limit: 10`, "1"},
+				{int64(2), "Project", "tbl", "columns: record_id, name, `amount`*2 AS twice", "0"},
+			},
+		},
+		{
+			"EXPLAIN SELECT * FROM tbl WHERE record_id = 'rec1'",
+			nil,
+			[][]interface{}{{int64(1), "BatchGetRecords", "tbl", "record_ids: rec1", "1"}},
+		},
+		{
+			"EXPLAIN SELECT DISTINCT owner FROM tbl ORDER BY UPPER(owner) LIMIT 2",
+			nil,
+			[][]interface{}{
+				{int64(1), "ListRecords", "tbl", `field_names: ["owner"], page_size: 50`, "1 per 50 records"},
+				{int64(2), "Sort", "tbl", "order by: UPPER(`owner`)", "0"},
+				{int64(3), "Project", "tbl", "columns: owner", "0"},
+				{int64(4), "Distinct", "tbl", "limit: 2", "0"},
+			},
+		},
+		{
+			"EXPLAIN SELECT name FROM tbl AS a WHERE EXISTS (SELECT 1 FROM tbl AS b WHERE b.owner = a.owner)",
+			nil,
+			[][]interface{}{
+				{int64(1), "ListRecords", "tbl", `field_names: ["name","owner"], page_size: 50`, "1 per 50 records"},
+				{int64(2), "Filter", "tbl", "residual: EXISTS (SELECT 1 FROM `tbl` AS `b` WHERE `b`.`owner`=`a`.`owner`)", "0"},
+				{int64(3), "Project", "tbl", "columns: record_id, name", "0"},
+			},
+		},
+		{
+			"EXPLAIN UPDATE tbl SET owner = 'dave', amount = 1 WHERE name = 'apple' LIMIT 120",
+			nil,
+			[][]interface{}{
+				{int64(1), "ListRecords", "tbl", `filter: CurrentValue.[name] = "apple", page_size: 50, limit: 120`, "3"},
+				{int64(2), "BatchUpdateRecords", "tbl", "fields: amount, owner", "1 per page"},
+			},
+		},
+		{
+			"EXPLAIN DELETE FROM tbl WHERE record_id IN ('rec1', 'rec2') AND amount > 1",
+			nil,
+			[][]interface{}{
+				{int64(1), "BatchGetRecords", "tbl", "record_ids: rec1, rec2", "1"},
+				{int64(2), "Filter", "tbl", "residual: `amount`>1", "0"},
+				{int64(3), "DeleteRecord", "tbl", "", "1 per record"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			table := newSalesTable()
+			columns, res := queryAll(t, newMockDB(t, table), tt.query, tt.args...)
+			assert.Equal(t, explainColumns, columns)
+			assert.Equal(t, tt.want, res)
+			assert.Empty(t, table.listRequests(), "the statement isn't executed")
+			assert.Empty(t, table.batchGetRequests())
+			assert.Empty(t, table.writeFields())
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package driver
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pingcap/parser/test_driver"
+)
+
+// the literals of filter formulas, every value is quoted or formatted here so a value never changes the formula.
+// The formula documents no escape in a string literal or a field reference, so the values with a double quote
+// or a backslash and the names with `]` or a backslash are errNotPushable, and the condition is filtered in driver.
+
+// formulaString quote s as a string literal, errNotPushable when s has a double quote or a backslash.
+func formulaString(s string) (string, error) {
+	if strings.ContainsAny(s, `"\`) {
+		return "", errNotPushable
+	}
+	return `"` + s + `"`, nil
+}
+
+// formulaField reference the field of the current record, errNotPushable when name has `]` or a backslash.
+func formulaField(name string) (string, error) {
+	if strings.ContainsAny(name, `]\`) {
+		return "", errNotPushable
+	}
+	return "CurrentValue.[" + name + "]", nil
+}
+
+// formulaNumber format a float without exponent, which the formula doesn't support.
+func formulaNumber(f float64) (string, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return "", fmt.Errorf("invalid number %v in filter", f)
+	}
+	return strconv.FormatFloat(f, 'f', -1, 64), nil
+}
+
+// formulaValue format a SQL value or a placeholder argument as a literal, ErrNullValue for NULL.
+func formulaValue(v interface{}) (string, error) {
+	switch v := v.(type) {
+	case nil:
+		return "", ErrNullValue
+	case string:
+		return formulaString(v)
+	case []byte:
+		return formulaString(string(v))
+	case bool:
+		if v {
+			return filterTrue, nil
+		}
+		return filterFalse, nil
+	case int:
+		return strconv.FormatInt(int64(v), 10), nil
+	case int8:
+		return strconv.FormatInt(int64(v), 10), nil
+	case int16:
+		return strconv.FormatInt(int64(v), 10), nil
+	case int32:
+		return strconv.FormatInt(int64(v), 10), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case uint:
+		return strconv.FormatUint(uint64(v), 10), nil
+	case uint8:
+		return strconv.FormatUint(uint64(v), 10), nil
+	case uint16:
+		return strconv.FormatUint(uint64(v), 10), nil
+	case uint32:
+		return strconv.FormatUint(uint64(v), 10), nil
+	case uint64:
+		return strconv.FormatUint(v, 10), nil
+	case float32:
+		return formulaNumber(float64(v))
+	case float64:
+		return formulaNumber(v)
+	case *test_driver.MyDecimal:
+		f, err := strconv.ParseFloat(v.String(), 64)
+		if err != nil {
+			return "", err
+		}
+		return formulaNumber(f)
+	case time.Time:
+		// dates are compared by the timestamp in milliseconds, like the inserted value
+		return strconv.FormatInt(v.UnixNano()/1e6, 10), nil
+	default:
+		return formulaString(fmt.Sprint(v))
+	}
+}
@@ -0,0 +1,53 @@
+//go:build go1.18
+// +build go1.18
+
+package driver
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// FuzzFilterPlaceholder check the placeholder values are pushed as a plain string literal or filtered in driver,
+// a value never changes the formula.
+func FuzzFilterPlaceholder(f *testing.F) {
+	for _, seed := range [][2]string{
+		{"apple", "alice"},
+		{`a"b`, `c\`},
+		{`\"),OR(TRUE(`, `"`},
+		{`]`, `\\"`},
+	} {
+		f.Add(seed[0], seed[1])
+	}
+	f.Fuzz(func(t *testing.T, name, owner string) {
+		if !utf8.ValidString(name) || !utf8.ValidString(owner) {
+			t.Skip()
+		}
+		table := newSalesTable()
+		_, res := queryAll(t, newMockDB(t, table), "SELECT name FROM tbl WHERE name = ? AND owner IN (?, 'x')", name, owner)
+		filter := *table.listRequests()[0].Filter
+
+		var want []string
+		if !strings.ContainsAny(name, `"\`) {
+			want = append(want, `CurrentValue.[name] = "`+name+`"`)
+		}
+		if !strings.ContainsAny(owner, `"\`) {
+			want = append(want, `OR(CurrentValue.[owner] = "`+owner+`",CurrentValue.[owner] = "x")`)
+		}
+		switch len(want) {
+		case 0:
+			if filter != "" || len(res) != 0 {
+				t.Fatalf("name %q and owner %q are pushed to the formula %s or match %v", name, owner, filter, res)
+			}
+		case 1:
+			if filter != want[0] {
+				t.Fatalf("name %q and owner %q changed the formula %s", name, owner, filter)
+			}
+		default:
+			if filter != "AND("+strings.Join(want, ",")+")" {
+				t.Fatalf("name %q and owner %q changed the formula %s", name, owner, filter)
+			}
+		}
+	})
+}
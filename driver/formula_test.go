@@ -0,0 +1,138 @@
+package driver
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormulaValue(t *testing.T) {
+	for _, c := range []struct {
+		value interface{}
+		want  string
+	}{
+		{"apple", `"apple"`},
+		{[]byte("a b"), `"a b"`},
+		{int64(-3), "-3"},
+		{uint8(7), "7"},
+		{1e21, "1000000000000000000000"},
+		{0.5, "0.5"},
+		{true, "TRUE()"},
+		{time.Unix(1600000000, 0), "1600000000000"},
+	} {
+		v, err := formulaValue(c.value)
+		assert.NoError(t, err)
+		assert.Equal(t, c.want, v)
+	}
+	_, err := formulaValue(nil)
+	assert.Equal(t, ErrNullValue, err)
+	_, err = formulaValue(math.NaN())
+	assert.Error(t, err)
+
+	for _, v := range []interface{}{`say "hi"`, `a\b`, []byte(`"),TRUE(`)} {
+		_, err = formulaValue(v)
+		assert.Equal(t, errNotPushable, err, "%s", v)
+	}
+
+	name, err := formulaField("a b")
+	assert.NoError(t, err)
+	assert.Equal(t, "CurrentValue.[a b]", name)
+	for _, v := range []string{"a]b", `a\b`} {
+		_, err = formulaField(v)
+		assert.Equal(t, errNotPushable, err, v)
+	}
+}
+
+func TestSelectEscapedFilter(t *testing.T) {
+	table := newSalesTable()
+	table.fields = append(table.fields, newMockField("a]b", FieldTypeText))
+	db := newMockDB(t, table)
+	table.records[1].Fields["a]b"] = `x"y`
+	_, res := queryAll(t, db, "SELECT name FROM tbl WHERE `a]b` = 'x\"y' AND owner IN (?, 'bob') AND amount < 3", `\"),TRUE(`)
+	assert.Equal(t, [][]interface{}{{"rec2", "banana"}}, res)
+	assert.Equal(t, "CurrentValue.[amount] < 3", *table.listRequests()[0].Filter,
+		"the quotes and backslashes are filtered in driver")
+
+	_, res = queryAll(t, db, "SELECT name FROM tbl WHERE record_id = ?", "rec2")
+	assert.Equal(t, [][]interface{}{{"rec2", "banana"}}, res)
+	_, res = queryAll(t, db, "SELECT name FROM tbl WHERE record_id = ?", `rec2" OR "1`)
+	assert.Empty(t, res)
+	assert.Equal(t, []string{`rec2" OR "1`}, table.batchGetRequests()[1], "the id isn't part of a formula")
+}
+
+func TestFormulaIn(t *testing.T) {
+	table := newSalesTable()
+	db := newMockDB(t, table)
+	_, res := queryAll(t, db, "SELECT name FROM tbl WHERE owner IN ('ali')")
+	assert.Empty(t, res, "IN isn't a substring match")
+	assert.Equal(t, `CurrentValue.[owner] = "ali"`, *table.listRequests()[0].Filter)
+	queryAll(t, db, "SELECT name FROM tbl WHERE owner NOT IN ('alice', 'bob')")
+	assert.Equal(t, `AND(CurrentValue.[owner] != "alice",CurrentValue.[owner] != "bob")`, *table.listRequests()[1].Filter)
+}
+
+func TestFormulaResidual(t *testing.T) {
+	t.Run("select", func(t *testing.T) {
+		table := newSalesTable()
+		db := newMockDB(t, table)
+		queryAll(t, db, "SELECT name FROM tbl WHERE (amount > 1)")
+		assert.Equal(t, "CurrentValue.[amount] > 1", *table.listRequests()[0].Filter)
+		queryAll(t, db, "SELECT name FROM tbl WHERE (amount + 1) * 2 > 5")
+		assert.Equal(t, "(CurrentValue.[amount]%2B1) * 2 > 5", *table.listRequests()[1].Filter)
+
+		_, res := queryAll(t, db, "SELECT name FROM tbl WHERE owner = 'alice' AND name LIKE 'c%'")
+		assert.Equal(t, [][]interface{}{{"rec3", "cherry"}}, res)
+		assert.Equal(t, `CurrentValue.[owner] = "alice"`, *table.listRequests()[2].Filter, "LIKE is filtered in driver")
+		_, res = queryAll(t, db, "SELECT name FROM tbl WHERE NOT (owner = 'alice') OR amount BETWEEN 2 AND 2")
+		assert.Equal(t, [][]interface{}{{"rec2", "banana"}, {"rec3", "cherry"}}, res)
+		assert.Empty(t, *table.listRequests()[3].Filter)
+	})
+
+	for _, c := range []struct {
+		where   string
+		deleted []string
+	}{
+		{"(owner = 'alice')", []string{"rec1", "rec3"}},
+		{"name LIKE 'a%'", []string{"rec1"}},
+		{"NOT (owner = 'alice')", []string{"rec2"}},
+		{"amount BETWEEN 1 AND 1", []string{"rec2"}},
+		{"owner = 'alice' AND name NOT LIKE '%e'", []string{"rec3"}},
+	} {
+		t.Run("delete where "+c.where, func(t *testing.T) {
+			table := newSalesTable()
+			_, err := newMockDB(t, table).Exec("DELETE FROM tbl WHERE " + c.where)
+			require.NoError(t, err)
+			assert.ElementsMatch(t, c.deleted, table.deletedRecords())
+		})
+	}
+
+	t.Run("update", func(t *testing.T) {
+		table := newSalesTable()
+		res, err := newMockDB(t, table).Exec("UPDATE tbl SET owner = 'carol' WHERE name LIKE '%an%' OR amount > 2")
+		require.NoError(t, err)
+		n, _ := res.RowsAffected()
+		assert.Equal(t, int64(2), n)
+		assert.Len(t, table.writeFields(), 2)
+	})
+}
+
+func TestFormulaDateFunctions(t *testing.T) {
+	table := newSalesTable()
+	table.fields = append(table.fields, newMockField("created", FieldTypeDate))
+	table.records[0].Fields["created"] = float64(time.Date(2021, 3, 4, 0, 0, 0, 0, time.Local).UnixNano() / 1e6)
+	table.records[2].Fields["created"] = float64(time.Date(2021, 3, 6, 12, 0, 0, 0, time.Local).UnixNano() / 1e6)
+	db := newMockDB(t, table)
+
+	_, res := queryAll(t, db, "SELECT name FROM tbl WHERE DATE(created) = '2021-03-04' AND owner = 'alice'")
+	assert.Equal(t, [][]interface{}{{"rec1", "apple"}}, res)
+	assert.Equal(t, `CurrentValue.[owner] = "alice"`, *table.listRequests()[0].Filter, "DATE(x) is filtered in driver")
+	_, res = queryAll(t, db, "SELECT name FROM tbl WHERE WEEKDAY(created) = 5")
+	assert.Equal(t, [][]interface{}{{"rec3", "cherry"}}, res)
+	assert.Empty(t, *table.listRequests()[1].Filter, "WEEKDAY(x) is filtered in driver")
+
+	queryAll(t, db, "SELECT name FROM tbl WHERE created >= TODATE('2021-03-05') AND YEAR(created) = 2021")
+	assert.Equal(t, `AND(CurrentValue.[created] >= TODATE("2021-03-05"),YEAR(CurrentValue.[created]) = 2021)`,
+		*table.listRequests()[2].Filter)
+}
@@ -0,0 +1,759 @@
+package driver
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/pingcap/parser/ast"
+	"github.com/pingcap/parser/opcode"
+)
+
+// scalarFunc a MySQL scalar function evaluated in driver.
+type scalarFunc struct {
+	minArgs int
+	maxArgs int // -1 for variadic
+	// nullable functions receive NULL arguments, the others return NULL on any NULL argument
+	nullable bool
+	call     func(args []interface{}) (interface{}, error)
+}
+
+// maxStringLength the longest string REPEAT, LPAD and RPAD build, like the default max_allowed_packet of MySQL,
+// the longer results are NULL.
+const maxStringLength = 64 << 20
+
+var scalarFuncs map[string]scalarFunc
+
+func init() {
+	scalarFuncs = map[string]scalarFunc{
+		// control flow
+		"if":       {minArgs: 3, maxArgs: 3, nullable: true, call: funcIf},
+		"ifnull":   {minArgs: 2, maxArgs: 2, nullable: true, call: funcCoalesce},
+		"coalesce": {minArgs: 1, maxArgs: -1, nullable: true, call: funcCoalesce},
+		"nullif":   {minArgs: 2, maxArgs: 2, nullable: true, call: funcNullIf},
+		"isnull":   {minArgs: 1, maxArgs: 1, nullable: true, call: funcIsNull},
+
+		// string
+		"concat":           {minArgs: 1, maxArgs: -1, call: funcConcat},
+		"concat_ws":        {minArgs: 2, maxArgs: -1, nullable: true, call: funcConcatWS},
+		"length":           {minArgs: 1, maxArgs: 1, call: funcLength},
+		"octet_length":     {minArgs: 1, maxArgs: 1, call: funcLength},
+		"char_length":      {minArgs: 1, maxArgs: 1, call: funcCharLength},
+		"character_length": {minArgs: 1, maxArgs: 1, call: funcCharLength},
+		"upper":            {minArgs: 1, maxArgs: 1, call: funcUpper},
+		"ucase":            {minArgs: 1, maxArgs: 1, call: funcUpper},
+		"lower":            {minArgs: 1, maxArgs: 1, call: funcLower},
+		"lcase":            {minArgs: 1, maxArgs: 1, call: funcLower},
+		"substring":        {minArgs: 2, maxArgs: 3, call: funcSubstring},
+		"substr":           {minArgs: 2, maxArgs: 3, call: funcSubstring},
+		"mid":              {minArgs: 3, maxArgs: 3, call: funcSubstring},
+		"left":             {minArgs: 2, maxArgs: 2, call: funcLeft},
+		"right":            {minArgs: 2, maxArgs: 2, call: funcRight},
+		"trim":             {minArgs: 1, maxArgs: 3, nullable: true, call: funcTrim},
+		"ltrim":            {minArgs: 1, maxArgs: 1, call: funcLTrim},
+		"rtrim":            {minArgs: 1, maxArgs: 1, call: funcRTrim},
+		"replace":          {minArgs: 3, maxArgs: 3, call: funcReplace},
+		"reverse":          {minArgs: 1, maxArgs: 1, call: funcReverse},
+		"repeat":           {minArgs: 2, maxArgs: 2, call: funcRepeat},
+		"lpad":             {minArgs: 3, maxArgs: 3, call: funcLPad},
+		"rpad":             {minArgs: 3, maxArgs: 3, call: funcRPad},
+		"locate":           {minArgs: 2, maxArgs: 3, call: funcLocate},
+		"instr":            {minArgs: 2, maxArgs: 2, call: funcInstr},
+
+		// math
+		"abs":      {minArgs: 1, maxArgs: 1, call: funcAbs},
+		"ceil":     {minArgs: 1, maxArgs: 1, call: mathFunc(math.Ceil)},
+		"ceiling":  {minArgs: 1, maxArgs: 1, call: mathFunc(math.Ceil)},
+		"floor":    {minArgs: 1, maxArgs: 1, call: mathFunc(math.Floor)},
+		"round":    {minArgs: 1, maxArgs: 2, call: funcRound},
+		"truncate": {minArgs: 2, maxArgs: 2, call: funcTruncate},
+		"mod":      {minArgs: 2, maxArgs: 2, call: funcMod},
+		"pow":      {minArgs: 2, maxArgs: 2, call: funcPow},
+		"power":    {minArgs: 2, maxArgs: 2, call: funcPow},
+		"sqrt":     {minArgs: 1, maxArgs: 1, call: funcSqrt},
+		"exp":      {minArgs: 1, maxArgs: 1, call: floatFunc(math.Exp)},
+		"ln":       {minArgs: 1, maxArgs: 1, call: funcLog(math.Log)},
+		"log10":    {minArgs: 1, maxArgs: 1, call: funcLog(math.Log10)},
+		"log2":     {minArgs: 1, maxArgs: 1, call: funcLog(math.Log2)},
+		"log":      {minArgs: 1, maxArgs: 2, call: funcLogBase},
+		"sign":     {minArgs: 1, maxArgs: 1, call: funcSign},
+		"pi":       {minArgs: 0, maxArgs: 0, call: func([]interface{}) (interface{}, error) { return math.Pi, nil }},
+		"greatest": {minArgs: 2, maxArgs: -1, call: funcGreatest},
+		"least":    {minArgs: 2, maxArgs: -1, call: funcLeast},
+
+		// date
+		"now":               {minArgs: 0, maxArgs: 1, call: funcNow},
+		"current_timestamp": {minArgs: 0, maxArgs: 1, call: funcNow},
+		"localtime":         {minArgs: 0, maxArgs: 1, call: funcNow},
+		"localtimestamp":    {minArgs: 0, maxArgs: 1, call: funcNow},
+		"sysdate":           {minArgs: 0, maxArgs: 1, call: funcNow},
+		"curdate":           {minArgs: 0, maxArgs: 0, call: funcCurDate},
+		"current_date":      {minArgs: 0, maxArgs: 0, call: funcCurDate},
+		"today":             {minArgs: 0, maxArgs: 0, call: funcCurDate},
+		"date":              {minArgs: 1, maxArgs: 1, call: funcDate},
+		"todate":            {minArgs: 1, maxArgs: 1, call: funcDate},
+		"year":              {minArgs: 1, maxArgs: 1, call: datePart(func(t time.Time) int64 { return int64(t.Year()) })},
+		"quarter":           {minArgs: 1, maxArgs: 1, call: datePart(func(t time.Time) int64 { return int64(t.Month()+2) / 3 })},
+		"month":             {minArgs: 1, maxArgs: 1, call: datePart(func(t time.Time) int64 { return int64(t.Month()) })},
+		"day":               {minArgs: 1, maxArgs: 1, call: datePart(func(t time.Time) int64 { return int64(t.Day()) })},
+		"dayofmonth":        {minArgs: 1, maxArgs: 1, call: datePart(func(t time.Time) int64 { return int64(t.Day()) })},
+		"dayofyear":         {minArgs: 1, maxArgs: 1, call: datePart(func(t time.Time) int64 { return int64(t.YearDay()) })},
+		"dayofweek":         {minArgs: 1, maxArgs: 1, call: datePart(func(t time.Time) int64 { return int64(t.Weekday()) + 1 })},
+		"weekday":           {minArgs: 1, maxArgs: 1, call: datePart(func(t time.Time) int64 { return (int64(t.Weekday()) + 6) % 7 })},
+		"hour":              {minArgs: 1, maxArgs: 1, call: datePart(func(t time.Time) int64 { return int64(t.Hour()) })},
+		"minute":            {minArgs: 1, maxArgs: 1, call: datePart(func(t time.Time) int64 { return int64(t.Minute()) })},
+		"second":            {minArgs: 1, maxArgs: 1, call: datePart(func(t time.Time) int64 { return int64(t.Second()) })},
+		"last_day":          {minArgs: 1, maxArgs: 1, call: funcLastDay},
+		"date_format":       {minArgs: 2, maxArgs: 2, call: funcDateFormat},
+		"datediff":          {minArgs: 2, maxArgs: 2, call: funcDateDiff},
+		"timestampdiff":     {minArgs: 3, maxArgs: 3, call: funcTimestampDiff},
+		"date_add":          {minArgs: 3, maxArgs: 3, call: funcDateAdd(1)},
+		"adddate":           {minArgs: 2, maxArgs: 3, call: funcDateAdd(1)},
+		"date_sub":          {minArgs: 3, maxArgs: 3, call: funcDateAdd(-1)},
+		"subdate":           {minArgs: 2, maxArgs: 3, call: funcDateAdd(-1)},
+		"unix_timestamp":    {minArgs: 0, maxArgs: 1, call: funcUnixTimestamp},
+		"from_unixtime":     {minArgs: 1, maxArgs: 2, call: funcFromUnixTime},
+
+		"version": {minArgs: 0, maxArgs: 0, call: func([]interface{}) (interface{}, error) { return biTableVersion, nil }},
+	}
+}
+
+// callFunction call the scalar function name with evaluated args.
+func callFunction(name string, args []interface{}) (interface{}, error) {
+	f, ok := scalarFuncs[name]
+	if !ok {
+		return nil, fmt.Errorf("not supported function %s", strings.ToUpper(name))
+	}
+	if len(args) < f.minArgs || (f.maxArgs >= 0 && len(args) > f.maxArgs) {
+		return nil, fmt.Errorf("incorrect parameter count in the call to function %s", strings.ToUpper(name))
+	}
+	if !f.nullable {
+		for _, arg := range args {
+			if arg == nil {
+				return nil, nil
+			}
+		}
+	}
+	return f.call(args)
+}
+
+func funcIf(args []interface{}) (interface{}, error) {
+	if isTrue(args[0]) {
+		return args[1], nil
+	}
+	return args[2], nil
+}
+
+func funcCoalesce(args []interface{}) (interface{}, error) {
+	for _, arg := range args {
+		if arg != nil {
+			return arg, nil
+		}
+	}
+	return nil, nil
+}
+
+func funcNullIf(args []interface{}) (interface{}, error) {
+	if args[0] != nil && args[1] != nil && compareValues(args[0], args[1]) == 0 {
+		return nil, nil
+	}
+	return args[0], nil
+}
+
+func funcIsNull(args []interface{}) (interface{}, error) {
+	return boolValue(args[0] == nil), nil
+}
+
+func funcConcat(args []interface{}) (interface{}, error) {
+	buff := strings.Builder{}
+	for _, arg := range args {
+		buff.WriteString(toString(arg))
+	}
+	return buff.String(), nil
+}
+
+func funcConcatWS(args []interface{}) (interface{}, error) {
+	if args[0] == nil {
+		return nil, nil
+	}
+	items := make([]string, 0, len(args)-1)
+	for _, arg := range args[1:] {
+		if arg != nil {
+			items = append(items, toString(arg))
+		}
+	}
+	return strings.Join(items, toString(args[0])), nil
+}
+
+func funcLength(args []interface{}) (interface{}, error) {
+	return int64(len(toString(args[0]))), nil
+}
+
+func funcCharLength(args []interface{}) (interface{}, error) {
+	return int64(utf8.RuneCountInString(toString(args[0]))), nil
+}
+
+func funcUpper(args []interface{}) (interface{}, error) {
+	return strings.ToUpper(toString(args[0])), nil
+}
+
+func funcLower(args []interface{}) (interface{}, error) {
+	return strings.ToLower(toString(args[0])), nil
+}
+
+// funcSubstring SUBSTRING(str, pos[, len]), pos is 1-based and counts from the end when negative.
+func funcSubstring(args []interface{}) (interface{}, error) {
+	runes := []rune(toString(args[0]))
+	pos := toInt(args[1])
+	switch {
+	case pos > 0:
+		pos--
+	case pos < 0:
+		pos += int64(len(runes))
+	default:
+		return "", nil
+	}
+	if pos < 0 || pos >= int64(len(runes)) {
+		return "", nil
+	}
+	end := int64(len(runes))
+	if len(args) == 3 {
+		n := toInt(args[2])
+		if n <= 0 {
+			return "", nil
+		}
+		if pos+n < end {
+			end = pos + n
+		}
+	}
+	return string(runes[pos:end]), nil
+}
+
+func funcLeft(args []interface{}) (interface{}, error) {
+	runes := []rune(toString(args[0]))
+	n := toInt(args[1])
+	if n <= 0 {
+		return "", nil
+	}
+	if n > int64(len(runes)) {
+		n = int64(len(runes))
+	}
+	return string(runes[:n]), nil
+}
+
+func funcRight(args []interface{}) (interface{}, error) {
+	runes := []rune(toString(args[0]))
+	n := toInt(args[1])
+	if n <= 0 {
+		return "", nil
+	}
+	if n > int64(len(runes)) {
+		n = int64(len(runes))
+	}
+	return string(runes[int64(len(runes))-n:]), nil
+}
+
+// funcTrim TRIM([{BOTH | LEADING | TRAILING} [remstr] FROM] str), parsed as (str, remstr, direction).
+func funcTrim(args []interface{}) (interface{}, error) {
+	if args[0] == nil {
+		return nil, nil
+	}
+	s := toString(args[0])
+	remstr := " "
+	if len(args) > 1 && args[1] != nil {
+		remstr = toString(args[1])
+	}
+	direction := ast.TrimBothDefault
+	if len(args) > 2 {
+		if d, ok := args[2].(ast.TrimDirectionType); ok {
+			direction = d
+		}
+	}
+	if remstr == "" {
+		return s, nil
+	}
+	if direction != ast.TrimTrailing {
+		for strings.HasPrefix(s, remstr) {
+			s = s[len(remstr):]
+		}
+	}
+	if direction != ast.TrimLeading {
+		for strings.HasSuffix(s, remstr) {
+			s = s[:len(s)-len(remstr)]
+		}
+	}
+	return s, nil
+}
+
+func funcLTrim(args []interface{}) (interface{}, error) {
+	return strings.TrimLeft(toString(args[0]), " "), nil
+}
+
+func funcRTrim(args []interface{}) (interface{}, error) {
+	return strings.TrimRight(toString(args[0]), " "), nil
+}
+
+func funcReplace(args []interface{}) (interface{}, error) {
+	from := toString(args[1])
+	if from == "" {
+		return toString(args[0]), nil
+	}
+	return strings.ReplaceAll(toString(args[0]), from, toString(args[2])), nil
+}
+
+func funcReverse(args []interface{}) (interface{}, error) {
+	runes := []rune(toString(args[0]))
+	for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+		runes[i], runes[j] = runes[j], runes[i]
+	}
+	return string(runes), nil
+}
+
+func funcRepeat(args []interface{}) (interface{}, error) {
+	str := toString(args[0])
+	n := toInt(args[1])
+	if n <= 0 || str == "" {
+		return "", nil
+	}
+	if n > maxStringLength/int64(len(str)) {
+		return nil, nil
+	}
+	return strings.Repeat(str, int(n)), nil
+}
+
+func funcLPad(args []interface{}) (interface{}, error) {
+	return pad(args, true)
+}
+
+func funcRPad(args []interface{}) (interface{}, error) {
+	return pad(args, false)
+}
+
+func pad(args []interface{}, left bool) (interface{}, error) {
+	runes := []rune(toString(args[0]))
+	n := toInt(args[1])
+	padding := []rune(toString(args[2]))
+	if n < 0 || n > maxStringLength {
+		return nil, nil
+	}
+	if n <= int64(len(runes)) {
+		return string(runes[:n]), nil
+	}
+	if len(padding) == 0 {
+		return nil, nil
+	}
+	fill := make([]rune, 0, n-int64(len(runes)))
+	for int64(len(fill)) < n-int64(len(runes)) {
+		fill = append(fill, padding[len(fill)%len(padding)])
+	}
+	if left {
+		return string(fill) + string(runes), nil
+	}
+	return string(runes) + string(fill), nil
+}
+
+// funcLocate LOCATE(substr, str[, pos]) return the 1-based position, 0 when not found.
+func funcLocate(args []interface{}) (interface{}, error) {
+	sub := []rune(strings.ToLower(toString(args[0])))
+	runes := []rune(strings.ToLower(toString(args[1])))
+	start := int64(1)
+	if len(args) == 3 {
+		start = toInt(args[2])
+	}
+	if start < 1 || start > int64(len(runes))+1 {
+		return int64(0), nil
+	}
+	i := strings.Index(string(runes[start-1:]), string(sub))
+	if i < 0 {
+		return int64(0), nil
+	}
+	return start + int64(utf8.RuneCountInString(string(runes[start-1:])[:i])), nil
+}
+
+func funcInstr(args []interface{}) (interface{}, error) {
+	return funcLocate([]interface{}{args[1], args[0]})
+}
+
+func funcAbs(args []interface{}) (interface{}, error) {
+	if i, ok := args[0].(int64); ok {
+		if i < 0 {
+			return -i, nil
+		}
+		return i, nil
+	}
+	return math.Abs(toFloat(args[0])), nil
+}
+
+// mathFunc keep integers as is, and return an integer for a float argument.
+func mathFunc(f func(float64) float64) func([]interface{}) (interface{}, error) {
+	return func(args []interface{}) (interface{}, error) {
+		if i, ok := args[0].(int64); ok {
+			return i, nil
+		}
+		return integralValue(f(toFloat(args[0]))), nil
+	}
+}
+
+// integralValue return the integral f as a BIGINT, f out of the range of BIGINT is kept as a DOUBLE like MySQL.
+func integralValue(f float64) interface{} {
+	if f >= math.MinInt64 && f < math.MaxInt64 {
+		return int64(f)
+	}
+	return f
+}
+
+func floatFunc(f func(float64) float64) func([]interface{}) (interface{}, error) {
+	return func(args []interface{}) (interface{}, error) {
+		return f(toFloat(args[0])), nil
+	}
+}
+
+func funcRound(args []interface{}) (interface{}, error) {
+	d := int64(0)
+	if len(args) == 2 {
+		d = toInt(args[1])
+	}
+	if i, ok := args[0].(int64); ok && d >= 0 {
+		return i, nil
+	}
+	scale := math.Pow(10, float64(d))
+	v := math.Round(toFloat(args[0])*scale) / scale
+	if d <= 0 {
+		return integralValue(v), nil
+	}
+	return v, nil
+}
+
+func funcTruncate(args []interface{}) (interface{}, error) {
+	d := toInt(args[1])
+	if i, ok := args[0].(int64); ok && d >= 0 {
+		return i, nil
+	}
+	scale := math.Pow(10, float64(d))
+	v := math.Trunc(toFloat(args[0])*scale) / scale
+	if d <= 0 {
+		return integralValue(v), nil
+	}
+	return v, nil
+}
+
+func funcMod(args []interface{}) (interface{}, error) {
+	return arithmetic(opcode.Mod, args[0], args[1])
+}
+
+func funcPow(args []interface{}) (interface{}, error) {
+	return math.Pow(toFloat(args[0]), toFloat(args[1])), nil
+}
+
+func funcSqrt(args []interface{}) (interface{}, error) {
+	v := toFloat(args[0])
+	if v < 0 {
+		return nil, nil
+	}
+	return math.Sqrt(v), nil
+}
+
+func funcLog(f func(float64) float64) func([]interface{}) (interface{}, error) {
+	return func(args []interface{}) (interface{}, error) {
+		v := toFloat(args[0])
+		if v <= 0 {
+			return nil, nil
+		}
+		return f(v), nil
+	}
+}
+
+func funcLogBase(args []interface{}) (interface{}, error) {
+	if len(args) == 1 {
+		return funcLog(math.Log)(args)
+	}
+	base, v := toFloat(args[0]), toFloat(args[1])
+	if base <= 0 || base == 1 || v <= 0 {
+		return nil, nil
+	}
+	return math.Log(v) / math.Log(base), nil
+}
+
+func funcSign(args []interface{}) (interface{}, error) {
+	v := toFloat(args[0])
+	return int64(compareOrdered(v < 0, v > 0)), nil
+}
+
+func funcGreatest(args []interface{}) (interface{}, error) {
+	res := args[0]
+	for _, arg := range args[1:] {
+		if compareValues(arg, res) > 0 {
+			res = arg
+		}
+	}
+	return res, nil
+}
+
+func funcLeast(args []interface{}) (interface{}, error) {
+	res := args[0]
+	for _, arg := range args[1:] {
+		if compareValues(arg, res) < 0 {
+			res = arg
+		}
+	}
+	return res, nil
+}
+
+func funcNow([]interface{}) (interface{}, error) {
+	return time.Now().Truncate(time.Second), nil
+}
+
+func funcCurDate([]interface{}) (interface{}, error) {
+	return truncateDay(time.Now()), nil
+}
+
+func funcDate(args []interface{}) (interface{}, error) {
+	t, ok := toTime(args[0])
+	if !ok {
+		return nil, nil
+	}
+	return truncateDay(t), nil
+}
+
+func datePart(f func(time.Time) int64) func([]interface{}) (interface{}, error) {
+	return func(args []interface{}) (interface{}, error) {
+		t, ok := toTime(args[0])
+		if !ok {
+			return nil, nil
+		}
+		return f(t), nil
+	}
+}
+
+func funcLastDay(args []interface{}) (interface{}, error) {
+	t, ok := toTime(args[0])
+	if !ok {
+		return nil, nil
+	}
+	first := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+	return first.AddDate(0, 1, -1), nil
+}
+
+func funcDateDiff(args []interface{}) (interface{}, error) {
+	a, ok1 := toTime(args[0])
+	b, ok2 := toTime(args[1])
+	if !ok1 || !ok2 {
+		return nil, nil
+	}
+	return daysBetween(b, a), nil
+}
+
+// daysBetween the calendar days from a to b.
+func daysBetween(a, b time.Time) int64 {
+	da := time.Date(a.Year(), a.Month(), a.Day(), 0, 0, 0, 0, time.UTC)
+	db := time.Date(b.Year(), b.Month(), b.Day(), 0, 0, 0, 0, time.UTC)
+	return int64(db.Sub(da).Hours() / 24)
+}
+
+func funcTimestampDiff(args []interface{}) (interface{}, error) {
+	unit, ok := args[0].(ast.TimeUnitType)
+	if !ok {
+		return nil, fmt.Errorf("TIMESTAMPDIFF needs a time unit")
+	}
+	a, ok1 := toTime(args[1])
+	b, ok2 := toTime(args[2])
+	if !ok1 || !ok2 {
+		return nil, nil
+	}
+	months := func() int64 {
+		m := int64(b.Year()-a.Year())*12 + int64(b.Month()-a.Month())
+		// an incomplete month doesn't count
+		if m > 0 && b.AddDate(0, -int(m), 0).Before(a) {
+			m--
+		} else if m < 0 && b.AddDate(0, -int(m), 0).After(a) {
+			m++
+		}
+		return m
+	}
+	d := b.Sub(a)
+	switch unit {
+	case ast.TimeUnitMicrosecond:
+		return d.Microseconds(), nil
+	case ast.TimeUnitSecond:
+		return int64(d / time.Second), nil
+	case ast.TimeUnitMinute:
+		return int64(d / time.Minute), nil
+	case ast.TimeUnitHour:
+		return int64(d / time.Hour), nil
+	case ast.TimeUnitDay:
+		return int64(d / (24 * time.Hour)), nil
+	case ast.TimeUnitWeek:
+		return int64(d / (7 * 24 * time.Hour)), nil
+	case ast.TimeUnitMonth:
+		return months(), nil
+	case ast.TimeUnitQuarter:
+		return months() / 3, nil
+	case ast.TimeUnitYear:
+		return months() / 12, nil
+	}
+	return nil, fmt.Errorf("not supported time unit %s", unit.String())
+}
+
+// funcDateAdd DATE_ADD(date, INTERVAL expr unit), parsed as (date, expr, unit). ADDDATE(date, days) is also supported.
+func funcDateAdd(sign int) func([]interface{}) (interface{}, error) {
+	return func(args []interface{}) (interface{}, error) {
+		t, ok := toTime(args[0])
+		if !ok {
+			return nil, nil
+		}
+		unit := ast.TimeUnitDay
+		if len(args) == 3 {
+			if u, ok := args[2].(ast.TimeUnitType); ok {
+				unit = u
+			}
+		}
+		n := toFloat(args[1]) * float64(sign)
+		switch unit {
+		case ast.TimeUnitMicrosecond:
+			return t.Add(time.Duration(n) * time.Microsecond), nil
+		case ast.TimeUnitSecond:
+			return t.Add(time.Duration(n * float64(time.Second))), nil
+		case ast.TimeUnitMinute:
+			return t.Add(time.Duration(n * float64(time.Minute))), nil
+		case ast.TimeUnitHour:
+			return t.Add(time.Duration(n * float64(time.Hour))), nil
+		case ast.TimeUnitDay:
+			return t.AddDate(0, 0, int(n)), nil
+		case ast.TimeUnitWeek:
+			return t.AddDate(0, 0, int(n)*7), nil
+		case ast.TimeUnitMonth:
+			return addMonths(t, int(n)), nil
+		case ast.TimeUnitQuarter:
+			return addMonths(t, int(n)*3), nil
+		case ast.TimeUnitYear:
+			return addMonths(t, int(n)*12), nil
+		}
+		return nil, fmt.Errorf("not supported time unit %s", unit.String())
+	}
+}
+
+// addMonths add months and clip the day to the end of month like MySQL.
+func addMonths(t time.Time, months int) time.Time {
+	first := time.Date(t.Year(), t.Month(), 1, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+	first = first.AddDate(0, months, 0)
+	lastDay := first.AddDate(0, 1, -1).Day()
+	day := t.Day()
+	if day > lastDay {
+		day = lastDay
+	}
+	return first.AddDate(0, 0, day-1)
+}
+
+func funcUnixTimestamp(args []interface{}) (interface{}, error) {
+	if len(args) == 0 {
+		return time.Now().Unix(), nil
+	}
+	t, ok := toTime(args[0])
+	if !ok {
+		return nil, nil
+	}
+	return t.Unix(), nil
+}
+
+func funcFromUnixTime(args []interface{}) (interface{}, error) {
+	t := time.Unix(toInt(args[0]), 0)
+	if len(args) == 2 {
+		return funcDateFormat([]interface{}{t, args[1]})
+	}
+	return t, nil
+}
+
+// funcDateFormat DATE_FORMAT(date, format) with MySQL format specifiers.
+func funcDateFormat(args []interface{}) (interface{}, error) {
+	t, ok := toTime(args[0])
+	if !ok {
+		return nil, nil
+	}
+	format := toString(args[1])
+	buff := strings.Builder{}
+	for i := 0; i < len(format); i++ {
+		c := format[i]
+		if c != '%' || i+1 == len(format) {
+			buff.WriteByte(c)
+			continue
+		}
+		i++
+		hour12 := t.Hour() % 12
+		if hour12 == 0 {
+			hour12 = 12
+		}
+		switch format[i] {
+		case 'Y':
+			buff.WriteString(fmt.Sprintf("%04d", t.Year()))
+		case 'y':
+			buff.WriteString(fmt.Sprintf("%02d", t.Year()%100))
+		case 'm':
+			buff.WriteString(fmt.Sprintf("%02d", int(t.Month())))
+		case 'c':
+			buff.WriteString(strconv.Itoa(int(t.Month())))
+		case 'M':
+			buff.WriteString(t.Month().String())
+		case 'b':
+			buff.WriteString(t.Month().String()[:3])
+		case 'd':
+			buff.WriteString(fmt.Sprintf("%02d", t.Day()))
+		case 'e':
+			buff.WriteString(strconv.Itoa(t.Day()))
+		case 'D':
+			buff.WriteString(strconv.Itoa(t.Day()) + ordinalSuffix(t.Day()))
+		case 'j':
+			buff.WriteString(fmt.Sprintf("%03d", t.YearDay()))
+		case 'H':
+			buff.WriteString(fmt.Sprintf("%02d", t.Hour()))
+		case 'k':
+			buff.WriteString(strconv.Itoa(t.Hour()))
+		case 'h', 'I':
+			buff.WriteString(fmt.Sprintf("%02d", hour12))
+		case 'l':
+			buff.WriteString(strconv.Itoa(hour12))
+		case 'i':
+			buff.WriteString(fmt.Sprintf("%02d", t.Minute()))
+		case 's', 'S':
+			buff.WriteString(fmt.Sprintf("%02d", t.Second()))
+		case 'f':
+			buff.WriteString(fmt.Sprintf("%06d", t.Nanosecond()/1000))
+		case 'p':
+			if t.Hour() < 12 {
+				buff.WriteString("AM")
+			} else {
+				buff.WriteString("PM")
+			}
+		case 'r':
+			buff.WriteString(t.Format("03:04:05 PM"))
+		case 'T':
+			buff.WriteString(t.Format("15:04:05"))
+		case 'W':
+			buff.WriteString(t.Weekday().String())
+		case 'a':
+			buff.WriteString(t.Weekday().String()[:3])
+		case 'w':
+			buff.WriteString(strconv.Itoa(int(t.Weekday())))
+		default:
+			buff.WriteByte(format[i])
+		}
+	}
+	return buff.String(), nil
+}
+
+func ordinalSuffix(day int) string {
+	if day/10 == 1 {
+		return "th"
+	}
+	switch day % 10 {
+	case 1:
+		return "st"
+	case 2:
+		return "nd"
+	case 3:
+		return "rd"
+	}
+	return "th"
+}
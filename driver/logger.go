@@ -0,0 +1,48 @@
+package driver
+
+import (
+	"context"
+
+	"github.com/pingcap/parser"
+	"github.com/sirupsen/logrus"
+
+	"github.com/luw2007/bitable-mysql-driver/internal/lark"
+)
+
+// Logger write the logs of a connector, fields are pairs of key and value.
+// The logs below the log level of the DSN are dropped before calling it.
+type Logger = lark.Logger
+
+// LogLevel is the level of a log.
+type LogLevel = lark.Level
+
+// the levels of the logs, from the most verbose.
+const (
+	LevelTrace = lark.LevelTrace
+	LevelDebug = lark.LevelDebug
+	LevelInfo  = lark.LevelInfo
+	LevelWarn  = lark.LevelWarn
+	LevelError = lark.LevelError
+)
+
+// ParseLogLevel parse trace, debug, info, warn or error, other levels are info.
+func ParseLogLevel(level string) LogLevel {
+	return lark.ParseLevel(level)
+}
+
+// NewLogrusLogger return a Logger writing to a logrus logger, it's the default with the standard logger.
+func NewLogrusLogger(logger *logrus.Logger) Logger {
+	return lark.NewLogrusLogger(logger)
+}
+
+// log write a log of the Conn.
+func (c *Conn) log(ctx context.Context, level LogLevel, msg string, fields ...interface{}) {
+	if c.BiTable != nil {
+		c.Logger().Log(ctx, level, msg, fields...)
+	}
+}
+
+// redact replace the literals of query by `?`, the statements are logged without the values.
+func redact(query string) string {
+	return parser.Normalize(query)
+}
@@ -0,0 +1,31 @@
+//go:build go1.21
+// +build go1.21
+
+package driver
+
+import (
+	"context"
+	"log/slog"
+)
+
+// slogLevels are the levels of slog, trace is below debug.
+var slogLevels = map[LogLevel]slog.Level{
+	LevelTrace: slog.LevelDebug - 4,
+	LevelDebug: slog.LevelDebug,
+	LevelInfo:  slog.LevelInfo,
+	LevelWarn:  slog.LevelWarn,
+	LevelError: slog.LevelError,
+}
+
+type slogLogger struct {
+	logger *slog.Logger
+}
+
+// NewSlogLogger return a Logger writing to a slog logger.
+func NewSlogLogger(logger *slog.Logger) Logger {
+	return slogLogger{logger: logger}
+}
+
+func (l slogLogger) Log(ctx context.Context, level LogLevel, msg string, fields ...interface{}) {
+	l.logger.Log(ctx, slogLevels[level], msg, fields...)
+}
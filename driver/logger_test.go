@@ -0,0 +1,63 @@
+package driver
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/luw2007/bitable-mysql-driver/internal/lark"
+)
+
+// recordLogger keep the logs.
+type recordLogger struct {
+	mu   sync.Mutex
+	logs []map[string]interface{}
+}
+
+func (l *recordLogger) Log(_ context.Context, level LogLevel, msg string, fields ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	log := lark.FieldMap(fields)
+	log["level"], log["msg"] = level, msg
+	l.logs = append(l.logs, log)
+}
+
+func (l *recordLogger) messages(msg string) []map[string]interface{} {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var res []map[string]interface{}
+	for _, log := range l.logs {
+		if log["msg"] == msg {
+			res = append(res, log)
+		}
+	}
+	return res
+}
+
+// withLogLevel set the log level of a mock client.
+func withLogLevel(level string) lark.ClientOption {
+	return func(cfg *lark.ClientConfig) {
+		cfg.LogLevel = level
+	}
+}
+
+func TestLogger(t *testing.T) {
+	debug, info := &recordLogger{}, &recordLogger{}
+	debugDB := sql.OpenDB(mockConnector{conn: newMockConn(t, newSalesTable(), lark.WithLogger(debug), withLogLevel("debug"))})
+	infoDB := sql.OpenDB(mockConnector{conn: newMockConn(t, newSalesTable(), lark.WithLogger(info), withLogLevel("info"))})
+
+	queryAll(t, debugDB, "SELECT name FROM tbl WHERE owner = 'alice' AND amount > 2")
+	queryAll(t, infoDB, "SELECT name FROM tbl WHERE owner = 'alice'")
+
+	prepares := debug.messages("[bitable driver] prepare")
+	if assert.Len(t, prepares, 1) {
+		assert.Equal(t, LevelDebug, prepares[0]["level"])
+		assert.NotContains(t, prepares[0]["sql"], "alice", "the values are redacted")
+		assert.Contains(t, prepares[0]["sql"], "owner = ?")
+	}
+	assert.Empty(t, info.logs, "the level of a DSN only applies to its connections")
+
+}
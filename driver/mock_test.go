@@ -0,0 +1,472 @@
+package driver
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	larksdk "github.com/chyroc/lark"
+
+	"github.com/luw2007/bitable-mysql-driver/internal/lark"
+)
+
+// mockTable a fake bitable table served by the lark sdk mock.
+type mockTable struct {
+	fields  []*larksdk.GetBitableFieldListRespItem
+	records []*larksdk.GetBitableRecordListRespItem
+
+	// others serve the requests of other table ids
+	others map[string]*mockTable
+
+	mu       sync.Mutex
+	requests []*larksdk.GetBitableRecordListReq
+	// writes are the fields of the created or updated records
+	writes []map[string]interface{}
+	// fieldLists count the requests of the fields
+	fieldLists int
+	// userTokens are the user_access_token of the list requests
+	userTokens []string
+	// batchGets are the record ids of the batch_get requests
+	batchGets [][]string
+	// deletes are the deleted record ids
+	deletes []string
+	// views are the views of the table, the list requests without filter and sort apply their filter
+	views []*lark.View
+	// tables are the tables of the app, served by the table apis of the root table
+	tables []*lark.Table
+	// createdFields limit the fields of the created tables, all the fields are created when 0
+	createdFields int
+}
+
+func newMockTable() *mockTable {
+	return &mockTable{
+		fields: []*larksdk.GetBitableFieldListRespItem{
+			{FieldID: "fld1", FieldName: "name", Type: int64(FieldTypeText)},
+			{FieldID: "fld2", FieldName: "amount", Type: int64(FieldTypeNumber)},
+			{FieldID: "fld3", FieldName: "owner", Type: int64(FieldTypeText)},
+		},
+	}
+}
+
+func newMockField(name string, fieldType FieldType) *larksdk.GetBitableFieldListRespItem {
+	return &larksdk.GetBitableFieldListRespItem{FieldID: "fld_" + name, FieldName: name, Type: int64(fieldType)}
+}
+
+func (m *mockTable) addRecord(recordID string, fields map[string]interface{}) {
+	m.records = append(m.records, &larksdk.GetBitableRecordListRespItem{RecordID: recordID, Fields: fields})
+}
+
+func (m *mockTable) addTable(tableID string, other *mockTable) {
+	if m.others == nil {
+		m.others = make(map[string]*mockTable)
+	}
+	m.others[tableID] = other
+}
+
+func (m *mockTable) table(tableID string) *mockTable {
+	if other, ok := m.others[tableID]; ok {
+		return other
+	}
+	return m
+}
+
+func (m *mockTable) writeFields() []map[string]interface{} {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]map[string]interface{}(nil), m.writes...)
+}
+
+func (m *mockTable) batchGetRequests() [][]string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([][]string(nil), m.batchGets...)
+}
+
+func (m *mockTable) deletedRecords() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]string(nil), m.deletes...)
+}
+
+func (m *mockTable) view(viewID string) *lark.View {
+	for _, view := range m.views {
+		if view.ViewID == viewID {
+			return view
+		}
+	}
+	return nil
+}
+
+func (m *mockTable) tableNames() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	names := make([]string, 0, len(m.tables))
+	for _, table := range m.tables {
+		names = append(names, table.TableID+":"+table.Name)
+	}
+	return names
+}
+
+func (m *mockTable) fieldNames() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	names := make([]string, 0, len(m.fields))
+	for _, field := range m.fields {
+		names = append(names, field.FieldName)
+	}
+	return names
+}
+
+func (m *mockTable) listRequests() []*larksdk.GetBitableRecordListReq {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]*larksdk.GetBitableRecordListReq(nil), m.requests...)
+}
+
+// newMockConn create a Conn whose Open API calls are served by table.
+func newMockConn(t *testing.T, root *mockTable, options ...lark.ClientOption) *Conn {
+	t.Helper()
+	cfg := NewConfig()
+	cfg.AppID, cfg.AppSecret, cfg.AppToken = "cli_mock", "secret", "app_mock"
+	conn := newConn(lark.NewLarkClient(cfg.AppID, cfg.AppSecret, cfg.baseURL(), "error", time.Second, options...), cfg)
+	mock := conn.Mock()
+	mock.MockBitableGetBitableFieldList(func(ctx context.Context, req *larksdk.GetBitableFieldListReq,
+		options ...larksdk.MethodOptionFunc) (*larksdk.GetBitableFieldListResp, *larksdk.Response, error) {
+		table := root.table(req.TableID)
+		table.mu.Lock()
+		table.fieldLists++
+		table.mu.Unlock()
+		return &larksdk.GetBitableFieldListResp{Items: table.fields, Total: int64(len(table.fields))}, nil, nil
+	})
+	mock.MockBitableGetBitableRecordList(func(ctx context.Context, req *larksdk.GetBitableRecordListReq,
+		options ...larksdk.MethodOptionFunc) (*larksdk.GetBitableRecordListResp, *larksdk.Response, error) {
+		table := root.table(req.TableID)
+		table.mu.Lock()
+		table.requests = append(table.requests, req)
+		table.userTokens = append(table.userTokens, lark.UserTokenOf(ctx))
+		table.mu.Unlock()
+		records := table.records
+		if req.Filter != nil {
+			records = filterMockRecords(records, *req.Filter)
+		}
+		// the view is ignored with a filter or sort like the open api
+		if req.ViewID != nil && *req.ViewID != "" && (req.Filter == nil || *req.Filter == "") && (req.Sort == nil || *req.Sort == "") {
+			table.mu.Lock()
+			view := table.view(*req.ViewID)
+			table.mu.Unlock()
+			if view == nil {
+				return nil, nil, larksdk.NewError("Bitable", "GetBitableRecordList", 1254005, "view not found")
+			}
+			records = viewMockRecords(table, records, view)
+		}
+		start := 0
+		if req.PageToken != nil && *req.PageToken != "" {
+			start, _ = strconv.Atoi(*req.PageToken)
+		}
+		end := len(records)
+		if req.PageSize != nil && start+int(*req.PageSize) < end {
+			end = start + int(*req.PageSize)
+		}
+		resp := &larksdk.GetBitableRecordListResp{Total: int64(len(records))}
+		if start < end {
+			resp.Items = records[start:end]
+		}
+		if end < len(records) {
+			resp.HasMore = true
+			resp.PageToken = strconv.Itoa(end)
+		}
+		return resp, nil, nil
+	})
+	// the apis missing in the sdk are raw requests, the table id and the params are in the body
+	mock.MockRawRequest(func(ctx context.Context, req *larksdk.RawRequestReq, resp interface{}) (*larksdk.Response, error) {
+		body := reflect.ValueOf(req.Body).Elem()
+		if strings.HasSuffix(req.URL, "/tables") && req.Method == "POST" {
+			return nil, root.createTable(req.Body, resp)
+		}
+		if strings.HasSuffix(req.URL, "/tables/:table_id") {
+			root.mu.Lock()
+			defer root.mu.Unlock()
+			for _, table := range root.tables {
+				if table.TableID == body.FieldByName("TableID").String() {
+					table.Name = body.FieldByName("Name").String()
+					return nil, nil
+				}
+			}
+			return nil, larksdk.NewError("Bitable", req.API, 1254004, "table not found")
+		}
+		table := root.table(body.FieldByName("TableID").String())
+		table.mu.Lock()
+		defer table.mu.Unlock()
+		var data interface{}
+		switch {
+		case strings.HasSuffix(req.URL, "/records/batch_get"):
+			recordIDs := body.FieldByName("RecordIDs").Interface().([]string)
+			table.batchGets = append(table.batchGets, recordIDs)
+			records := make([]*larksdk.GetBitableRecordListRespItem, 0, len(recordIDs))
+			for _, recordID := range recordIDs {
+				for _, record := range table.records {
+					if record.RecordID == recordID {
+						records = append(records, record)
+					}
+				}
+			}
+			data = map[string]interface{}{"records": records}
+		case strings.HasSuffix(req.URL, "/views/:view_id"):
+			view := table.view(body.FieldByName("ViewID").String())
+			if view == nil {
+				return nil, larksdk.NewError("Bitable", req.API, 1254005, "view not found")
+			}
+			if req.Method == "PATCH" {
+				if name := body.FieldByName("ViewName").String(); name != "" {
+					view.ViewName = name
+				}
+				if property := body.FieldByName("Property").Interface().(*lark.ViewProperty); property != nil {
+					view.Property = property
+				}
+			}
+			data = map[string]interface{}{"view": view}
+		default:
+			return nil, fmt.Errorf("unexpected request %s %s", req.Method, req.URL)
+		}
+		b, err := json.Marshal(map[string]interface{}{"data": data})
+		if err != nil {
+			return nil, err
+		}
+		return nil, json.Unmarshal(b, resp)
+	})
+	mock.MockBitableGetBitableViewList(func(ctx context.Context, req *larksdk.GetBitableViewListReq,
+		options ...larksdk.MethodOptionFunc) (*larksdk.GetBitableViewListResp, *larksdk.Response, error) {
+		table := root.table(req.TableID)
+		table.mu.Lock()
+		defer table.mu.Unlock()
+		resp := &larksdk.GetBitableViewListResp{Total: int64(len(table.views))}
+		for _, view := range table.views {
+			resp.Items = append(resp.Items, &larksdk.GetBitableViewListRespItem{
+				ViewID: view.ViewID, ViewName: view.ViewName, ViewType: view.ViewType})
+		}
+		return resp, nil, nil
+	})
+	mock.MockBitableCreateBitableView(func(ctx context.Context, req *larksdk.CreateBitableViewReq,
+		options ...larksdk.MethodOptionFunc) (*larksdk.CreateBitableViewResp, *larksdk.Response, error) {
+		table := root.table(req.TableID)
+		table.mu.Lock()
+		defer table.mu.Unlock()
+		view := &lark.View{ViewID: fmt.Sprintf("vewnew%d", len(table.views)+1), ViewName: req.ViewName, ViewType: *req.ViewType}
+		table.views = append(table.views, view)
+		return &larksdk.CreateBitableViewResp{View: &larksdk.CreateBitableViewRespView{
+			ViewID: view.ViewID, ViewName: view.ViewName, ViewType: view.ViewType}}, nil, nil
+	})
+	mock.MockBitableDeleteBitableView(func(ctx context.Context, req *larksdk.DeleteBitableViewReq,
+		options ...larksdk.MethodOptionFunc) (*larksdk.DeleteBitableViewResp, *larksdk.Response, error) {
+		table := root.table(req.TableID)
+		table.mu.Lock()
+		defer table.mu.Unlock()
+		for i, view := range table.views {
+			if view.ViewID == req.ViewID {
+				table.views = append(table.views[:i], table.views[i+1:]...)
+				return &larksdk.DeleteBitableViewResp{}, nil, nil
+			}
+		}
+		return nil, nil, larksdk.NewError("Bitable", "DeleteBitableView", 1254005, "view not found")
+	})
+	mock.MockBitableGetBitableTableList(func(ctx context.Context, req *larksdk.GetBitableTableListReq,
+		options ...larksdk.MethodOptionFunc) (*larksdk.GetBitableTableListResp, *larksdk.Response, error) {
+		root.mu.Lock()
+		defer root.mu.Unlock()
+		resp := &larksdk.GetBitableTableListResp{Total: int64(len(root.tables))}
+		for _, table := range root.tables {
+			resp.Items = append(resp.Items, &larksdk.GetBitableTableListRespItem{TableID: table.TableID, Name: table.Name})
+		}
+		return resp, nil, nil
+	})
+	mock.MockBitableDeleteBitableTable(func(ctx context.Context, req *larksdk.DeleteBitableTableReq,
+		options ...larksdk.MethodOptionFunc) (*larksdk.DeleteBitableTableResp, *larksdk.Response, error) {
+		root.mu.Lock()
+		defer root.mu.Unlock()
+		for i, table := range root.tables {
+			if table.TableID == req.TableID {
+				root.tables = append(root.tables[:i], root.tables[i+1:]...)
+				return &larksdk.DeleteBitableTableResp{}, nil, nil
+			}
+		}
+		return nil, nil, larksdk.NewError("Bitable", "DeleteBitableTable", 1254004, "table not found")
+	})
+	mock.MockBitableCreateBitableField(func(ctx context.Context, req *larksdk.CreateBitableFieldReq,
+		options ...larksdk.MethodOptionFunc) (*larksdk.CreateBitableFieldResp, *larksdk.Response, error) {
+		table := root.table(req.TableID)
+		table.mu.Lock()
+		defer table.mu.Unlock()
+		field := &larksdk.GetBitableFieldListRespItem{FieldID: "fld_" + req.FieldName, FieldName: req.FieldName, Type: req.Type}
+		table.fields = append(table.fields, field)
+		return &larksdk.CreateBitableFieldResp{Field: &larksdk.CreateBitableFieldRespField{
+			FieldID: field.FieldID, FieldName: field.FieldName, Type: field.Type}}, nil, nil
+	})
+	mock.MockBitableDeleteBitableField(func(ctx context.Context, req *larksdk.DeleteBitableFieldReq,
+		options ...larksdk.MethodOptionFunc) (*larksdk.DeleteBitableFieldResp, *larksdk.Response, error) {
+		table := root.table(req.TableID)
+		table.mu.Lock()
+		defer table.mu.Unlock()
+		for i, field := range table.fields {
+			if field.FieldID == req.FieldID {
+				table.fields = append(table.fields[:i], table.fields[i+1:]...)
+				return &larksdk.DeleteBitableFieldResp{FieldID: req.FieldID, Deleted: true}, nil, nil
+			}
+		}
+		return nil, nil, larksdk.NewError("Bitable", "DeleteBitableField", 1254009, "field not found")
+	})
+	mock.MockBitableDeleteBitableRecord(func(ctx context.Context, req *larksdk.DeleteBitableRecordReq,
+		options ...larksdk.MethodOptionFunc) (*larksdk.DeleteBitableRecordResp, *larksdk.Response, error) {
+		table := root.table(req.TableID)
+		table.mu.Lock()
+		defer table.mu.Unlock()
+		table.deletes = append(table.deletes, req.RecordID)
+		return &larksdk.DeleteBitableRecordResp{Deleted: true, RecordID: req.RecordID}, nil, nil
+	})
+	mock.MockBitableBatchCreateBitableRecord(func(ctx context.Context, req *larksdk.BatchCreateBitableRecordReq,
+		options ...larksdk.MethodOptionFunc) (*larksdk.BatchCreateBitableRecordResp, *larksdk.Response, error) {
+		table := root.table(req.TableID)
+		resp := &larksdk.BatchCreateBitableRecordResp{}
+		table.mu.Lock()
+		defer table.mu.Unlock()
+		for _, record := range req.Records {
+			table.writes = append(table.writes, record.Fields)
+			resp.Records = append(resp.Records, &larksdk.BatchCreateBitableRecordRespRecord{
+				RecordID: fmt.Sprintf("new%d", len(table.writes)), Fields: record.Fields})
+		}
+		return resp, nil, nil
+	})
+	mock.MockBitableBatchUpdateBitableRecord(func(ctx context.Context, req *larksdk.BatchUpdateBitableRecordReq,
+		options ...larksdk.MethodOptionFunc) (*larksdk.BatchUpdateBitableRecordResp, *larksdk.Response, error) {
+		table := root.table(req.TableID)
+		resp := &larksdk.BatchUpdateBitableRecordResp{}
+		table.mu.Lock()
+		defer table.mu.Unlock()
+		for _, record := range req.Records {
+			table.writes = append(table.writes, record.Fields)
+			resp.Records = append(resp.Records, &larksdk.BatchUpdateBitableRecordRespRecord{
+				RecordID: *record.RecordID, Fields: record.Fields})
+		}
+		return resp, nil, nil
+	})
+	return conn
+}
+
+var mockEqualFilter = regexp.MustCompile(`^CurrentValue\.\[(.+?)\] = "?(.*?)"?$`)
+
+// createTable serve the create table request with its default view and fields.
+func (m *mockTable) createTable(body, resp interface{}) error {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	var req struct {
+		Table struct {
+			Name            string             `json:"name"`
+			DefaultViewName string             `json:"default_view_name"`
+			Fields          []*lark.TableField `json:"fields"`
+		} `json:"table"`
+	}
+	if err := json.Unmarshal(b, &req); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	created := &lark.CreatedTable{TableID: fmt.Sprintf("tblnew%d", len(m.tables)+1), DefaultViewID: "vewdefault"}
+	m.tables = append(m.tables, &lark.Table{TableID: created.TableID, Name: req.Table.Name})
+	table := &mockTable{views: []*lark.View{{ViewID: created.DefaultViewID, ViewName: req.Table.DefaultViewName, ViewType: "grid"}}}
+	for i, field := range req.Table.Fields {
+		if m.createdFields > 0 && i >= m.createdFields {
+			break
+		}
+		table.fields = append(table.fields, &larksdk.GetBitableFieldListRespItem{
+			FieldID: "fld_" + field.FieldName, FieldName: field.FieldName, Type: field.Type})
+		created.FieldIDList = append(created.FieldIDList, "fld_"+field.FieldName)
+	}
+	m.addTable(created.TableID, table)
+	if b, err = json.Marshal(map[string]interface{}{"data": created}); err != nil {
+		return err
+	}
+	return json.Unmarshal(b, resp)
+}
+
+// filterMockRecords support the filter formula `CurrentValue.[field] = value`, other formulas are ignored.
+func filterMockRecords(records []*larksdk.GetBitableRecordListRespItem, filter string) []*larksdk.GetBitableRecordListRespItem {
+	m := mockEqualFilter.FindStringSubmatch(filter)
+	if m == nil {
+		return records
+	}
+	res := make([]*larksdk.GetBitableRecordListRespItem, 0, len(records))
+	for _, record := range records {
+		if v, ok := record.Fields[m[1]]; ok && fmt.Sprint(v) == m[2] {
+			res = append(res, record)
+		}
+	}
+	return res
+}
+
+// viewMockRecords support the view filter of `is` conditions joined by AND.
+func viewMockRecords(table *mockTable, records []*larksdk.GetBitableRecordListRespItem, view *lark.View) []*larksdk.GetBitableRecordListRespItem {
+	if view.Property == nil || view.Property.FilterInfo == nil {
+		return records
+	}
+	names := make(map[string]string, len(table.fields))
+	for _, field := range table.fields {
+		names[field.FieldID] = field.FieldName
+	}
+	res := make([]*larksdk.GetBitableRecordListRespItem, 0, len(records))
+	for _, record := range records {
+		match := true
+		for _, condition := range view.Property.FilterInfo.Conditions {
+			var values []string
+			_ = json.Unmarshal([]byte(condition.Value), &values)
+			if condition.Operator != "is" || len(values) != 1 || fmt.Sprint(record.Fields[names[condition.FieldID]]) != values[0] {
+				match = false
+			}
+		}
+		if match {
+			res = append(res, record)
+		}
+	}
+	return res
+}
+
+// mockConnector open Conns sharing the mock client of conn.
+type mockConnector struct {
+	conn *Conn
+}
+
+func (m mockConnector) Connect(context.Context) (driver.Conn, error) {
+	conn := newConn(m.conn.BiTable, m.conn.cfg)
+	conn.userTokens = m.conn.userTokens
+	return conn, nil
+}
+
+func (m mockConnector) Driver() driver.Driver {
+	return &Driver{}
+}
+
+func newMockDB(t *testing.T, table *mockTable) *sql.DB {
+	t.Helper()
+	db := sql.OpenDB(mockConnector{conn: newMockConn(t, table)})
+	db.SetMaxOpenConns(1)
+	return db
+}
+
+// newWindowDB is newMockDB with the window functions enabled.
+func newWindowDB(t *testing.T, table *mockTable) *sql.DB {
+	t.Helper()
+	conn := newMockConn(t, table)
+	conn.cfg.WindowFunc = true
+	db := sql.OpenDB(mockConnector{conn: conn})
+	db.SetMaxOpenConns(1)
+	return db
+}
@@ -0,0 +1,64 @@
+package driver
+
+import (
+	"context"
+)
+
+type contextKey string
+
+const (
+	pageTokenContextKey  contextKey = "page_token"
+	pageCursorContextKey contextKey = "page_cursor"
+)
+
+// PageCursor receive the `page_token` of the last page read by a SELECT.
+// Pass PageToken to WithPageToken in the next request to continue after that page.
+type PageCursor struct {
+	PageToken string
+	HasMore   bool
+}
+
+// PageTokenRows is implemented by the rows of a SELECT on records,
+// use it with `sql.Conn.Raw` when calling the driver directly.
+type PageTokenRows interface {
+	// PageToken return the `page_token` of the last page read.
+	PageToken() string
+	// HasMore report whether more pages after PageToken.
+	HasMore() bool
+}
+
+// WithPageToken add `page_token` to context.Value, a SELECT on records resume from it.
+func WithPageToken(ctx context.Context, pageToken string) context.Context {
+	return context.WithValue(ctx, pageTokenContextKey, pageToken)
+}
+
+// WithPageCursor add cursor to context.Value, a SELECT on records fill it after every page read.
+func WithPageCursor(ctx context.Context, cursor *PageCursor) context.Context {
+	return context.WithValue(ctx, pageCursorContextKey, cursor)
+}
+
+func pageTokenFromContext(ctx context.Context) string {
+	pageToken, _ := ctx.Value(pageTokenContextKey).(string)
+	return pageToken
+}
+
+func pageCursorFromContext(ctx context.Context) *PageCursor {
+	cursor, _ := ctx.Value(pageCursorContextKey).(*PageCursor)
+	return cursor
+}
+
+// pagedContext report whether ctx has a page token or a cursor.
+func pagedContext(ctx context.Context) bool {
+	return pageTokenFromContext(ctx) != "" || pageCursorFromContext(ctx) != nil
+}
+
+// unpaged return r reading the records from the first page without filling the cursor, for the records
+// read ahead by an operator or matched by UPDATE and DELETE, their pages aren't the rows returned.
+func unpaged(r *rows) *rows {
+	if !pagedContext(r.ctx) {
+		return r
+	}
+	base := *r
+	base.ctx = WithPageCursor(WithPageToken(r.ctx, ""), nil)
+	return &base
+}
@@ -0,0 +1,86 @@
+package driver
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPageToken(t *testing.T) {
+	table := newMockTable()
+	for i := 0; i < 7; i++ {
+		table.addRecord(fmt.Sprintf("rec%d", i), map[string]interface{}{"name": fmt.Sprintf("n%d", i)})
+	}
+	db := newWindowDB(t, table)
+
+	readQuery := func(query, pageToken string) ([]string, *PageCursor) {
+		cursor := &PageCursor{}
+		ctx := WithPageCursor(WithPageToken(context.Background(), pageToken), cursor)
+		rows, err := db.QueryContext(ctx, query)
+		assert.NoError(t, err)
+		defer rows.Close()
+		ids := []string{}
+		for rows.Next() {
+			var id, name string
+			assert.NoError(t, rows.Scan(&id, &name))
+			ids = append(ids, id)
+		}
+		assert.NoError(t, rows.Err())
+		return ids, cursor
+	}
+	readPage := func(pageToken string) ([]string, *PageCursor) {
+		return readQuery("SELECT name FROM tbl LIMIT 3", pageToken)
+	}
+
+	ids, cursor := readPage("")
+	assert.Equal(t, []string{"rec0", "rec1", "rec2"}, ids)
+	assert.True(t, cursor.HasMore)
+
+	ids, cursor = readPage(cursor.PageToken)
+	assert.Equal(t, []string{"rec3", "rec4", "rec5"}, ids)
+
+	ids, cursor = readPage(cursor.PageToken)
+	assert.Equal(t, []string{"rec6"}, ids)
+	assert.False(t, cursor.HasMore)
+	assert.Empty(t, cursor.PageToken)
+
+	t.Run("subqueries read from the first page", func(t *testing.T) {
+		_, cursor := readPage("")
+		query := "SELECT name FROM tbl WHERE name IN (SELECT name FROM tbl WHERE name <> 'n0') LIMIT 3"
+		ids, _ := readQuery(query, cursor.PageToken)
+		assert.Equal(t, []string{"rec3", "rec4", "rec5"}, ids)
+	})
+
+	t.Run("operators reading ahead are rejected", func(t *testing.T) {
+		_, cursor := readPage("")
+		for _, query := range []string{
+			"SELECT name FROM tbl ORDER BY CONCAT(name, 'x') LIMIT 3",
+			"SELECT name FROM tbl WHERE name LIKE 'n%' LIMIT 3",
+			"SELECT DISTINCT name FROM tbl LIMIT 3",
+			"SELECT name, ROW_NUMBER() OVER () FROM tbl LIMIT 3",
+			"SELECT name FROM tbl UNION ALL SELECT name FROM tbl LIMIT 3",
+			"(SELECT name FROM tbl LIMIT 3) UNION (SELECT name FROM tbl LIMIT 3)",
+			"SELECT name FROM (SELECT name FROM tbl) t LIMIT 3",
+			"SELECT name FROM (SELECT name FROM tbl UNION SELECT name FROM tbl) t LIMIT 3",
+		} {
+			ctx := WithPageToken(context.Background(), cursor.PageToken)
+			_, err := db.QueryContext(ctx, query)
+			assert.ErrorIs(t, err, errPageTokenResume, query)
+			_, err = db.QueryContext(WithPageCursor(context.Background(), &PageCursor{}), query)
+			assert.Error(t, err, query)
+		}
+	})
+
+	t.Run("changes match all the records", func(t *testing.T) {
+		_, cursor := readPage("")
+		ctx := WithPageCursor(WithPageToken(context.Background(), cursor.PageToken), &PageCursor{})
+		res, err := db.ExecContext(ctx, "UPDATE tbl SET name = 'x' WHERE name LIKE 'n%'")
+		require.NoError(t, err)
+		affected, err := res.RowsAffected()
+		require.NoError(t, err)
+		assert.Equal(t, int64(7), affected)
+	})
+}
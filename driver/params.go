@@ -0,0 +1,129 @@
+package driver
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"sort"
+
+	"github.com/pingcap/parser/ast"
+	"github.com/pingcap/parser/test_driver"
+)
+
+// rewriteNamedParams replace the named params `:name` and `@name` outside literals and comments by `?`,
+// padded with spaces so the offsets don't change. names are the param names by offset, `@@var` is kept.
+func rewriteNamedParams(query string) (string, map[int]string) {
+	var b []byte
+	var names map[int]string
+	l := &withLexer{query: query}
+	for l.pos < len(query) {
+		c := query[l.pos]
+		switch c {
+		case '\'', '"', '`':
+			l.skipQuoted(c)
+			continue
+		case '#', '-', '/':
+			before := l.pos
+			l.skipSpace()
+			if l.pos != before {
+				continue
+			}
+		case ':', '@':
+			start := l.pos
+			if start > 0 && (query[start-1] == '@' || isWordByte(query[start-1])) {
+				break
+			}
+			l.pos++
+			name := l.word()
+			if name == "" {
+				continue
+			}
+			if b == nil {
+				b = []byte(query)
+				names = make(map[int]string)
+			}
+			b[start] = '?'
+			for i := start + 1; i < l.pos; i++ {
+				b[i] = ' '
+			}
+			names[start] = name
+			continue
+		}
+		l.pos++
+	}
+	if b == nil {
+		return query, nil
+	}
+	return string(b), names
+}
+
+// paramCollector collect the offsets of the param markers.
+type paramCollector struct {
+	offsets map[int]struct{}
+}
+
+func (c *paramCollector) Enter(n ast.Node) (ast.Node, bool) {
+	if v, ok := n.(*test_driver.ParamMarkerExpr); ok {
+		c.offsets[v.Offset] = struct{}{}
+	}
+	return n, false
+}
+
+func (c *paramCollector) Leave(n ast.Node) (ast.Node, bool) {
+	return n, true
+}
+
+// paramOffsets return the offsets of the param markers in nodes in the order of the query,
+// a CTE referenced twice has its markers once.
+func paramOffsets(nodes ...ast.Node) []int {
+	c := &paramCollector{offsets: make(map[int]struct{})}
+	for _, node := range nodes {
+		if node != nil {
+			node.Accept(c)
+		}
+	}
+	offsets := make([]int, 0, len(c.offsets))
+	for offset := range c.offsets {
+		offsets = append(offsets, offset)
+	}
+	sort.Ints(offsets)
+	return offsets
+}
+
+// bindArgs map args to the param markers at offsets, named args bind the named params and
+// the others bind `?` in order.
+func bindArgs(offsets []int, names map[int]string, args []driver.NamedValue) (map[int]driver.NamedValue, error) {
+	var positional []driver.NamedValue
+	named := make(map[string]driver.NamedValue)
+	for _, arg := range args {
+		if arg.Name == "" {
+			positional = append(positional, arg)
+		} else {
+			named[arg.Name] = arg
+		}
+	}
+	if want := len(offsets) - len(names); want != len(positional) {
+		return nil, fmt.Errorf("expected %d arguments, got %d", want, len(positional))
+	}
+	bound := make(map[int]driver.NamedValue, len(offsets))
+	used := make(map[string]bool, len(named))
+	i := 0
+	for _, offset := range offsets {
+		if name, ok := names[offset]; ok {
+			arg, ok := named[name]
+			if !ok {
+				return nil, fmt.Errorf("missing named argument '%s'", name)
+			}
+			used[name] = true
+			bound[offset] = arg
+			continue
+		}
+		bound[offset] = positional[i]
+		i++
+	}
+	for name := range named {
+		if !used[name] {
+			return nil, fmt.Errorf("named argument '%s' is not used", name)
+		}
+	}
+	return bound, nil
+}
@@ -0,0 +1,68 @@
+package driver
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitNamedParams(t *testing.T) {
+	query, names := rewriteNamedParams("SELECT '?:x', `@y` FROM t WHERE a = :a AND b = @b_1 AND @@c = 1 -- :d\n")
+	assert.Equal(t, "SELECT '?:x', `@y` FROM t WHERE a = ?  AND b = ?    AND @@c = 1 -- :d\n", query)
+	assert.Equal(t, map[int]string{36: "a", 47: "b_1"}, names)
+
+	query, names = rewriteNamedParams("SELECT * FROM t WHERE a = ?")
+	assert.Equal(t, "SELECT * FROM t WHERE a = ?", query)
+	assert.Nil(t, names)
+}
+
+func TestSelectPlaceholders(t *testing.T) {
+	t.Run("positional", func(t *testing.T) {
+		table := newSalesTable()
+		db := newMockDB(t, table)
+		_, res := queryAll(t, db, "SELECT name, '?' AS `a?` FROM tbl WHERE owner IN (?, ?) LIMIT ?", "alice", "bob", 2)
+		assert.Equal(t, [][]interface{}{{"rec1", "apple", "?"}, {"rec2", "banana", "?"}}, res)
+		assert.Equal(t, `OR(CurrentValue.[owner] = "alice",CurrentValue.[owner] = "bob")`, *table.listRequests()[0].Filter)
+
+		_, err := db.Query("SELECT name FROM tbl WHERE owner = ?", "alice", "bob")
+		assert.EqualError(t, err, "[bitable driver] expected 1 arguments, got 2")
+		stmt, err := db.Prepare("SELECT name FROM tbl WHERE owner = ? AND name <> '?'")
+		require.NoError(t, err)
+		defer stmt.Close()
+		_, err = stmt.Query()
+		assert.EqualError(t, err, "sql: expected 1 arguments, got 0")
+		_, err = db.Query("SELECT name FROM tbl LIMIT ?", "x")
+		assert.Error(t, err)
+	})
+
+	t.Run("named", func(t *testing.T) {
+		table := newSalesTable()
+		db := newMockDB(t, table)
+		_, res := queryAll(t, db, "SELECT name FROM tbl WHERE owner = :owner AND amount > @min OR name = :owner",
+			sql.Named("owner", "bob"), sql.Named("min", 0))
+		assert.Len(t, res, 3)
+		assert.Equal(t, `OR(AND(CurrentValue.[owner] = "bob",CurrentValue.[amount] > 0),CurrentValue.[name] = "bob")`,
+			*table.listRequests()[0].Filter)
+
+		_, err := db.Query("SELECT name FROM tbl WHERE owner = :owner", sql.Named("name", "bob"))
+		assert.EqualError(t, err, "[bitable driver] missing named argument 'owner'")
+		_, err = db.Query("SELECT name FROM tbl WHERE owner = ?", "bob", sql.Named("name", "bob"))
+		assert.EqualError(t, err, "[bitable driver] named argument 'name' is not used")
+	})
+
+	t.Run("set and values", func(t *testing.T) {
+		table := newSalesTable()
+		db := newMockDB(t, table)
+		_, err := db.Exec("INSERT INTO tbl (name, amount) VALUES (?, ?), (:name, 4)", "fig", 5, sql.Named("name", "kiwi"))
+		require.NoError(t, err)
+		_, err = db.Exec("UPDATE tbl SET amount = ? WHERE record_id = ?", 6, "rec1")
+		require.NoError(t, err)
+		assert.Equal(t, []map[string]interface{}{
+			{"name": "fig", "amount": int64(5)},
+			{"name": "kiwi", "amount": int64(4)},
+			{"amount": int64(6)},
+		}, table.writeFields())
+	})
+}
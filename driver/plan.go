@@ -0,0 +1,249 @@
+package driver
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pingcap/parser/ast"
+
+	"github.com/luw2007/bitable-mysql-driver/internal/lark"
+)
+
+// planCacheSize is the number of plans cached by a Conn.
+var planCacheSize = 256
+
+// fieldCacheTTL is how long the fields cached by a plan are used, the fields changed by other clients
+// are reloaded after it.
+var fieldCacheTTL = time.Minute
+
+// plan is the parsed query of a prepared statement, shared by the statements of the same query on a Conn.
+type plan struct {
+	query          string // the query with named params rewritten to `?`
+	node           ast.StmtNode
+	offsets        []int
+	names          map[int]string
+	derivedColumns map[ast.ResultSetNode][]string
+	// reusable is false when executing changes the AST, such as binding subqueries and window functions
+	reusable bool
+
+	mu     sync.Mutex
+	fields map[string]planFields
+}
+
+// planFields the fields of a table loaded with the schema version of the Conn.
+type planFields struct {
+	version  uint64
+	loadedAt time.Time
+	fields   map[string]lark.Field
+	order    []string
+}
+
+func (p *plan) tableFields(appToken, table string, version uint64) (planFields, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	f, ok := p.fields[appToken+"."+table]
+	return f, ok && f.version == version && time.Since(f.loadedAt) < fieldCacheTTL
+}
+
+func (p *plan) setTableFields(appToken, table string, f planFields) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.fields == nil {
+		p.fields = make(map[string]planFields)
+	}
+	p.fields[appToken+"."+table] = f
+}
+
+// planCache is a LRU of plans keyed by the query.
+type planCache struct {
+	size  int
+	mu    sync.Mutex
+	ll    *list.List
+	items map[string]*list.Element
+}
+
+type planEntry struct {
+	query string
+	plan  *plan
+}
+
+func newPlanCache(size int) *planCache {
+	return &planCache{size: size, ll: list.New(), items: make(map[string]*list.Element)}
+}
+
+func (c *planCache) get(query string) *plan {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.items[query]; ok {
+		c.ll.MoveToFront(e)
+		return e.Value.(*planEntry).plan
+	}
+	return nil
+}
+
+func (c *planCache) add(query string, p *plan) {
+	if c.size <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.items[query]; ok {
+		c.ll.MoveToFront(e)
+		e.Value.(*planEntry).plan = p
+		return
+	}
+	c.items[query] = c.ll.PushFront(&planEntry{query: query, plan: p})
+	if c.ll.Len() > c.size {
+		e := c.ll.Back()
+		c.ll.Remove(e)
+		delete(c.items, e.Value.(*planEntry).query)
+	}
+}
+
+func (c *planCache) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ll.Len()
+}
+
+// reusableCollector find the nodes changed by executing.
+type reusableCollector struct {
+	reusable bool
+}
+
+func (c *reusableCollector) Enter(n ast.Node) (ast.Node, bool) {
+	switch n.(type) {
+	case *ast.SubqueryExpr, *ast.WindowFuncExpr:
+		c.reusable = false
+	}
+	return n, !c.reusable
+}
+
+func (c *reusableCollector) Leave(n ast.Node) (ast.Node, bool) {
+	return n, c.reusable
+}
+
+func isReusable(node ast.Node) bool {
+	c := &reusableCollector{reusable: true}
+	node.Accept(c)
+	return c.reusable
+}
+
+// prepare parse the query once, check the table and column names, and cache the plan in the Conn.
+func (stmt *bitableStatement) prepare(ctx context.Context) error {
+	plans := stmt.conn.planCache()
+	if p := plans.get(stmt.query); p != nil {
+		stmt.plan = p
+		return nil
+	}
+	query, names := rewriteNamedParams(stmt.query)
+	node, offsets, err := stmt.parse(query)
+	if err != nil {
+		return err
+	}
+	stmt.plan = &plan{
+		query:          query,
+		node:           node,
+		offsets:        offsets,
+		names:          names,
+		derivedColumns: stmt.derivedColumns,
+		reusable:       isReusable(node),
+	}
+	if err := stmt.validate(ctx, node); err != nil {
+		return err
+	}
+	plans.add(stmt.query, stmt.plan)
+	return nil
+}
+
+// validate check the column names of a statement on a table, the fields are cached by the plan
+// and used by executing it, the statements which don't load the fields aren't checked.
+func (stmt *bitableStatement) validate(ctx context.Context, node ast.StmtNode) error {
+	var table interface{}
+	clauses := make(map[string][]string)
+	switch s := node.(type) {
+	case *ast.ExplainStmt:
+		return stmt.validate(ctx, s.Stmt)
+	case *ast.SelectStmt:
+		if s.From == nil || tableNameOf(s.From) == nil {
+			// a derived table is checked by executing
+			return nil
+		}
+		table = s.From
+		clauses["field list"] = collectColumnNames(s.Fields)
+		clauses["where clause"] = collectColumnNames(s.Where)
+	case *ast.UpdateStmt:
+		if tableNameOf(s.TableRefs) == nil {
+			return nil
+		}
+		table = s.TableRefs
+		for _, assignment := range s.List {
+			clauses["field list"] = append(clauses["field list"], assignment.Column.Name.O)
+		}
+		clauses["where clause"] = collectColumnNames(s.Where)
+	case *ast.DeleteStmt:
+		if tableNameOf(s.TableRefs) == nil {
+			return nil
+		}
+		table = s.TableRefs
+		clauses["where clause"] = collectColumnNames(s.Where)
+	default:
+		// INSERT doesn't load the fields, the api reports the unknown ones
+
+		return nil
+	}
+	appToken, tableID, _, err := stmt.getAppTableView(ctx, table)
+	if err != nil {
+		return fmt.Errorf("[bitable driver] %w", err)
+	}
+	r := &rows{ctx: ctx, conn: stmt.conn, appToken: stmt.conn.appToken()}
+	if appToken != "" {
+		r.appToken = appToken
+	}
+	fields, _, err := stmt.loadFields(r, tableID)
+	if err != nil {
+		return fmt.Errorf("[bitable driver] %w", err)
+	}
+	for _, clause := range []string{"field list", "where clause"} {
+		for _, name := range clauses[clause] {
+			if _, ok := fields[name]; !ok && name != FieldKeyRecordID {
+				return unknownColumn(name, clause)
+			}
+		}
+	}
+	return nil
+}
+
+// tableNameOf return the table of a statement on one table, nil for a derived table.
+func tableNameOf(node interface{}) *ast.TableName {
+	switch t := node.(type) {
+	case *ast.TableRefsClause:
+		if t != nil && t.TableRefs != nil && t.TableRefs.Right == nil {
+			return tableNameOf(t.TableRefs.Left)
+		}
+	case *ast.TableSource:
+		return tableNameOf(t.Source)
+	case *ast.TableName:
+		return t
+	}
+	return nil
+}
+
+// planCache return the plans of the Conn.
+func (c *Conn) planCache() *planCache {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.plans == nil {
+		c.plans = newPlanCache(c.config().PlanCacheSize)
+	}
+	return c.plans
+}
+
+// schemaChanged invalidate the fields cached by the plans, after a DDL on the Conn.
+func (c *Conn) schemaChanged() {
+	atomic.AddUint64(&c.schemaVersion, 1)
+}
@@ -0,0 +1,103 @@
+package driver
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPreparedPlan(t *testing.T) {
+	t.Run("parse and load fields once", func(t *testing.T) {
+		table := newSalesTable()
+		db := newMockDB(t, table)
+
+		stmt, err := db.Prepare("SELECT name FROM tbl WHERE owner = ?")
+		require.NoError(t, err)
+		defer stmt.Close()
+		for _, owner := range []string{"alice", "bob", "carol"} {
+			rows, err := stmt.Query(owner)
+			require.NoError(t, err)
+			for rows.Next() {
+			}
+			require.NoError(t, rows.Close())
+		}
+		assert.Equal(t, 1, table.fieldLists)
+		assert.Len(t, table.listRequests(), 3)
+		assert.Equal(t, `CurrentValue.[owner] = "carol"`, *table.listRequests()[2].Filter)
+
+		_, res := queryAll(t, db, "SELECT name FROM tbl WHERE owner = ?", "bob")
+		assert.Equal(t, [][]interface{}{{"rec2", "banana"}}, res)
+		assert.Equal(t, 1, table.fieldLists)
+	})
+
+	t.Run("check columns at prepare", func(t *testing.T) {
+		db := newMockDB(t, newSalesTable())
+		_, err := db.Prepare("SELECT name FROM tbl WHERE missing = 1")
+		assert.EqualError(t, err, "[bitable driver] unknown column 'missing' in 'where clause'")
+		_, err = db.Prepare("UPDATE tbl SET missing = 1")
+		assert.EqualError(t, err, "[bitable driver] unknown column 'missing' in 'field list'")
+		_, err = db.Prepare("SELECT name FROM (SELECT name FROM tbl) t")
+		assert.NoError(t, err)
+	})
+
+	t.Run("insert doesn't load the fields", func(t *testing.T) {
+		table := newSalesTable()
+		db := newMockDB(t, table)
+		_, err := db.Exec("INSERT INTO tbl (name, amount) VALUES ('durian', 4)")
+		require.NoError(t, err)
+		assert.Equal(t, 0, table.fieldLists)
+	})
+
+	t.Run("fields expire", func(t *testing.T) {
+		defer func(ttl time.Duration) { fieldCacheTTL = ttl }(fieldCacheTTL)
+
+		table := newSalesTable()
+		db := newMockDB(t, table)
+		db.SetMaxOpenConns(1)
+		query := "SELECT * FROM tbl WHERE owner = ?"
+		table.fields = append(table.fields, newMockField("note", FieldTypeText))
+		columns, _ := queryAll(t, db, query, "alice")
+		assert.Contains(t, columns, "note")
+		assert.Equal(t, 1, table.fieldLists)
+
+		// the field dropped by another client is seen after the ttl
+		table.fields = table.fields[:len(table.fields)-1]
+		columns, _ = queryAll(t, db, query, "alice")
+		assert.Contains(t, columns, "note", "cached")
+		fieldCacheTTL = 0
+		columns, _ = queryAll(t, db, query, "alice")
+		assert.NotContains(t, columns, "note")
+		assert.Equal(t, 2, table.fieldLists)
+	})
+
+	t.Run("bounded and invalidated by ddl", func(t *testing.T) {
+		defer func(size int) { planCacheSize = size }(planCacheSize)
+		planCacheSize = 2
+
+		table := newSalesTable()
+		conn := newMockConn(t, table)
+		for i := 0; i < 3; i++ {
+			_, err := conn.Prepare(fmt.Sprintf("SELECT name FROM tbl LIMIT %d", i+1))
+			require.NoError(t, err)
+		}
+		assert.Equal(t, 2, conn.planCache().len())
+		assert.Nil(t, conn.planCache().get("SELECT name FROM tbl LIMIT 1"))
+		assert.NotNil(t, conn.planCache().get("SELECT name FROM tbl LIMIT 3"))
+
+		fieldLists := table.fieldLists
+		_, err := conn.Prepare("SELECT name FROM tbl LIMIT 3")
+		require.NoError(t, err)
+		assert.Equal(t, fieldLists, table.fieldLists)
+		conn.schemaChanged()
+		stmt, err := conn.Prepare("SELECT name FROM tbl LIMIT 3")
+		require.NoError(t, err)
+		rows, err := stmt.(*bitableStatement).QueryContext(context.Background(), nil)
+		require.NoError(t, err)
+		require.NoError(t, rows.Close())
+		assert.Equal(t, fieldLists+1, table.fieldLists)
+	})
+}
@@ -0,0 +1,149 @@
+package driver
+
+import (
+	"database/sql/driver"
+
+	"github.com/pingcap/parser/ast"
+	"github.com/pingcap/parser/opcode"
+	"github.com/pingcap/parser/test_driver"
+)
+
+// recordIDsOf find the conjuncts of where matching record ids, `record_id = ?`, `record_id IN (?, ?)`
+// or an OR of them, the records are read by id instead of a filter.
+// rest are the other conjuncts, ok is false when no conjunct matches, and ids is empty when no record matches.
+func (stmt *bitableStatement) recordIDsOf(where ast.ExprNode) (ids []string, rest ast.ExprNode, ok bool) {
+	var others []ast.ExprNode
+	for _, cond := range splitConjuncts(where) {
+		condIDs, match := stmt.matchRecordIDs(cond)
+		switch {
+		case !match:
+			others = append(others, cond)
+		case ok:
+			ids = intersectIDs(ids, condIDs)
+		default:
+			ids, ok = condIDs, true
+		}
+	}
+	return ids, joinConjuncts(others), ok
+}
+
+// matchRecordIDs return the ids matched by a record_id equality, IN list or an OR of them.
+func (stmt *bitableStatement) matchRecordIDs(expr ast.ExprNode) ([]string, bool) {
+	switch e := expr.(type) {
+	case *ast.ParenthesesExpr:
+		return stmt.matchRecordIDs(e.Expr)
+	case *ast.BinaryOperationExpr:
+		switch e.Op {
+		case opcode.EQ:
+			if isRecordIDColumn(e.L) {
+				return stmt.recordIDValues(e.R)
+			}
+			if isRecordIDColumn(e.R) {
+				return stmt.recordIDValues(e.L)
+			}
+		case opcode.LogicOr:
+			l, ok := stmt.matchRecordIDs(e.L)
+			if !ok {
+				return nil, false
+			}
+			r, ok := stmt.matchRecordIDs(e.R)
+			if !ok {
+				return nil, false
+			}
+			return uniqueIDs(append(l, r...)), true
+		}
+	case *ast.PatternInExpr:
+		if !e.Not && e.Sel == nil && isRecordIDColumn(e.Expr) {
+			return stmt.recordIDValues(e.List...)
+		}
+	}
+	return nil, false
+}
+
+func isRecordIDColumn(expr ast.ExprNode) bool {
+	column, ok := expr.(*ast.ColumnNameExpr)
+	return ok && column.Name.Name.L == FieldKeyRecordID
+}
+
+// recordIDValues return the string literals or params of exprs, NULL matches no record.
+// Other values are compared as numbers by MySQL, so they are left to the filter.
+func (stmt *bitableStatement) recordIDValues(exprs ...ast.ExprNode) ([]string, bool) {
+	ids := make([]string, 0, len(exprs))
+	for _, expr := range exprs {
+		switch expr.(type) {
+		case *test_driver.ValueExpr, *test_driver.ParamMarkerExpr:
+		default:
+			return nil, false
+		}
+		v, err := stmt.eval(expr, nil)
+		if err != nil {
+			return nil, false
+		}
+		switch id := v.(type) {
+		case nil:
+		case string:
+			if id != "" {
+				ids = append(ids, id)
+			}
+		default:
+			return nil, false
+		}
+	}
+	return uniqueIDs(ids), true
+}
+
+// uniqueIDs remove the duplicated ids, keeping the first.
+func uniqueIDs(ids []string) []string {
+	seen := make(map[string]bool, len(ids))
+	res := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			res = append(res, id)
+		}
+	}
+	return res
+}
+
+// intersectIDs return the ids of a also in b, in the order of a.
+func intersectIDs(a, b []string) []string {
+	in := make(map[string]bool, len(b))
+	for _, id := range b {
+		in[id] = true
+	}
+	res := make([]string, 0, len(a))
+	for _, id := range a {
+		if in[id] {
+			res = append(res, id)
+		}
+	}
+	return res
+}
+
+// matchRecords return the ids of the records of plan matching its rest, at most limit.
+func (stmt *bitableStatement) matchRecords(r *rows, table string, plan *changePlan, limit int64) ([]string, error) {
+	var fetch []string
+	for _, name := range collectColumnNames(plan.rest) {
+		if _, ok := plan.fields[name]; ok {
+			fetch = append(fetch, name)
+		} else if name != FieldKeyRecordID {
+			return nil, unknownColumn(name, "where clause")
+		}
+	}
+	recordLimit := limit
+	if plan.rest != nil || plan.byID {
+		recordLimit = 0
+	}
+	source := newRecordRows(unpaged(r), table, plan.view, "", fetch, plan.fields, plan.filter, plan.recordIDs, recordLimit)
+	if plan.rest != nil {
+		env := newEvalEnv(append([]string{FieldKeyRecordID}, fetch...))
+		source = newFilterRows(r, source, stmt.exprProjection(plan.rest, env), 0)
+	}
+	defer source.Close()
+	matched := make([]string, 0, len(plan.recordIDs))
+	_, err := readSource(source, limit, func(src []driver.Value) error {
+		matched = append(matched, src[0].(string))
+		return nil
+	})
+	return matched, err
+}
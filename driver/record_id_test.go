@@ -0,0 +1,89 @@
+package driver
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSelectRecordIDs(t *testing.T) {
+	tests := []struct {
+		query     string
+		args      []interface{}
+		want      [][]interface{}
+		batchGets [][]string
+	}{
+		{
+			"SELECT name FROM tbl WHERE record_id IN ('rec3', ?, 'rec1', 'missing') ORDER BY amount",
+			[]interface{}{"rec1"},
+			[][]interface{}{{"rec3", "cherry"}, {"rec1", "apple"}},
+			[][]string{{"rec3", "rec1", "missing"}},
+		},
+		{
+			"SELECT name FROM tbl WHERE (record_id = 'rec1' OR record_id IN ('rec2', 'rec3')) AND owner = 'alice'",
+			nil,
+			[][]interface{}{{"rec1", "apple"}, {"rec3", "cherry"}},
+			[][]string{{"rec1", "rec2", "rec3"}},
+		},
+		{
+			"SELECT name FROM tbl WHERE record_id IN ('rec1', 'rec2') AND record_id = 'rec2' AND amount < 5",
+			nil,
+			[][]interface{}{{"rec2", "banana"}},
+			[][]string{{"rec2"}},
+		},
+		{
+			"SELECT name FROM tbl WHERE record_id = 'rec1' AND record_id = 'rec2'",
+			nil,
+			[][]interface{}{},
+			nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			table := newSalesTable()
+			_, res := queryAll(t, newMockDB(t, table), tt.query, tt.args...)
+			assert.Equal(t, tt.want, res)
+			assert.Equal(t, tt.batchGets, table.batchGetRequests())
+			assert.Empty(t, table.listRequests(), "the records are read by id")
+		})
+	}
+
+	t.Run("an OR with other predicates is a filter", func(t *testing.T) {
+		table := newSalesTable()
+		queryAll(t, newMockDB(t, table), "SELECT name FROM tbl WHERE record_id = 'rec1' OR owner = 'bob'")
+		assert.Empty(t, table.batchGetRequests())
+		assert.Len(t, table.listRequests(), 1)
+	})
+
+	t.Run("update and delete", func(t *testing.T) {
+		table := newSalesTable()
+		db := newMockDB(t, table)
+		res, err := db.Exec("UPDATE tbl SET amount = 9 WHERE record_id IN ('rec1', 'rec2', 'missing') AND owner = ?", "alice")
+		require.NoError(t, err)
+		n, _ := res.RowsAffected()
+		assert.Equal(t, int64(1), n)
+		assert.Equal(t, []map[string]interface{}{{"amount": int64(9)}}, table.writeFields())
+
+		res, err = db.Exec("DELETE FROM tbl WHERE record_id = 'rec3' OR record_id = 'rec2' LIMIT 1")
+		require.NoError(t, err)
+		n, _ = res.RowsAffected()
+		assert.Equal(t, int64(1), n)
+		assert.Equal(t, []string{"rec3"}, table.deletedRecords())
+		assert.Empty(t, table.listRequests())
+	})
+
+	t.Run("limit is the same by id and by filter", func(t *testing.T) {
+		for _, where := range []string{"owner = 'alice'", "record_id IN ('rec1', 'rec3')", "owner LIKE 'ali%'"} {
+			for limit, want := range map[int]int{1: 1, 2: 2, 5: 2} {
+				table := newSalesTable()
+				res, err := newMockDB(t, table).Exec(fmt.Sprintf("DELETE FROM tbl WHERE %s LIMIT %d", where, limit))
+				require.NoError(t, err)
+				n, _ := res.RowsAffected()
+				assert.Equal(t, int64(want), n, "%s LIMIT %d", where, limit)
+				assert.Len(t, table.deletedRecords(), want, "%s LIMIT %d", where, limit)
+			}
+		}
+	})
+}
@@ -26,6 +26,7 @@ type rows struct {
 	seek     int
 
 	pageList *lark.PageList
+	loaded   bool
 
 	limit int64
 	count int64
@@ -69,17 +70,17 @@ func (r *rows) Pick(dest []driver.Value, v interface{}) {
 
 func (r *rows) Next(in Rows, dest []driver.Value) error {
 	for i := 0; i < maxLoopTimes; i++ {
+		if r.limit > 0 && r.count >= r.limit {
+			return io.EOF
+		}
 		if r.seek < len(r.pageList.Items) {
-			if r.limit > 0 && r.count >= r.limit {
-				return io.EOF
-			}
 			in.Pick(dest, r.pageList.Items[r.seek])
 			r.seek++
 			r.count++
 			return nil
 		}
-		// pageToken 判断是否加载过
-		if r.pageList.PageToken != "" && !r.pageList.HasMore {
+		// 加载过且没有更多数据
+		if r.loaded && !r.pageList.HasMore {
 			return io.EOF
 		}
 		if err := r.loadMore(in); err != nil {
@@ -102,13 +103,18 @@ func (r *rows) loadMore(in Rows) error {
 		return io.EOF
 	}
 	r.seek = 0
-	// 如果需要后续查询，pageToken 不应该为空
+	r.loaded = true
 	r.pageList = res
 	return nil
 }
 
 type rowsFactory struct {
 	rows Rows
+	// conn and query are set for the rows returned to database/sql, the errors of Next are *Error of query
+	conn  *Conn
+	query string
+	// span trace the statement of the rows, it ends when the rows are read or closed
+	span *statementSpan
 }
 
 func newRowsFactory(rows Rows) *rowsFactory {
@@ -120,9 +126,36 @@ func (l rowsFactory) Columns() []string {
 }
 
 func (l rowsFactory) Close() error {
+	l.span.end(nil)
 	return l.rows.Close()
 }
 
 func (l rowsFactory) Next(dest []driver.Value) error {
-	return l.rows.Next(l.rows, dest)
+	err := l.rows.Next(l.rows, dest)
+	switch {
+	case err == nil:
+		l.span.next()
+	case err == io.EOF:
+		l.span.end(nil)
+	case l.conn != nil:
+		err = l.conn.checkError(err, l.query, false)
+		l.span.end(err)
+	}
+	return err
+}
+
+// PageToken implement PageTokenRows, return empty when rows not paged.
+func (l rowsFactory) PageToken() string {
+	if p, ok := l.rows.(PageTokenRows); ok {
+		return p.PageToken()
+	}
+	return ""
+}
+
+// HasMore implement PageTokenRows.
+func (l rowsFactory) HasMore() bool {
+	if p, ok := l.rows.(PageTokenRows); ok {
+		return p.HasMore()
+	}
+	return false
 }
@@ -0,0 +1,51 @@
+package driver
+
+import (
+	"database/sql/driver"
+
+	"github.com/luw2007/bitable-mysql-driver/internal/lark"
+)
+
+// filterRows keep the rows of source where the predicate is true, for conditions the filter formula can't express.
+type filterRows struct {
+	*rows
+	source    driver.Rows
+	predicate projection
+	drained   bool
+}
+
+func newFilterRows(base *rows, source driver.Rows, predicate projection, limit int64) driver.Rows {
+	newRows := base.Clone(source.Columns(), nil)
+	newRows.limit = limit
+	return newRowsFactory(&filterRows{rows: newRows, source: source, predicate: predicate})
+}
+
+func (p *filterRows) Load() (*lark.PageList, error) {
+	items := make([]interface{}, 0, DefaultPageSize)
+	for !p.drained && int64(len(items)) < DefaultPageSize {
+		more, err := readSource(p.source, DefaultPageSize-int64(len(items)), func(src []driver.Value) error {
+			v, err := p.predicate(src)
+			if err != nil {
+				return err
+			}
+			if !isTrue(v) {
+				return nil
+			}
+			item := make([]interface{}, len(src))
+			for i, v := range src {
+				item[i] = v
+			}
+			items = append(items, item)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		p.drained = !more
+	}
+	return &lark.PageList{Items: items, HasMore: !p.drained}, nil
+}
+
+func (p *filterRows) Close() error {
+	return p.source.Close()
+}
@@ -0,0 +1,87 @@
+package driver
+
+import (
+	"database/sql/driver"
+	"io"
+
+	"github.com/luw2007/bitable-mysql-driver/internal/lark"
+)
+
+// projection compute an output value from a source row.
+type projection func(src []driver.Value) (driver.Value, error)
+
+// columnProjection pick the source column at index.
+func columnProjection(index int) projection {
+	return func(src []driver.Value) (driver.Value, error) {
+		return src[index], nil
+	}
+}
+
+// projectRows map the rows of source to columns.
+type projectRows struct {
+	*rows
+	source      driver.Rows
+	projections []projection
+}
+
+func newProjectRows(base *rows, source driver.Rows, columns []string, projections []projection) driver.Rows {
+	newRows := base.Clone(columns, nil)
+	return newRowsFactory(&projectRows{rows: newRows, source: source, projections: projections})
+}
+
+func (p *projectRows) Load() (*lark.PageList, error) {
+	items := make([]interface{}, 0, DefaultPageSize)
+	more, err := readSource(p.source, DefaultPageSize, func(src []driver.Value) error {
+		item := make([]interface{}, len(p.projections))
+		for i, project := range p.projections {
+			v, err := project(src)
+			if err != nil {
+				return err
+			}
+			item[i] = v
+		}
+		items = append(items, item)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &lark.PageList{Items: items, HasMore: more}, nil
+}
+
+func (p *projectRows) Close() error {
+	return p.source.Close()
+}
+
+// PageToken implement PageTokenRows.
+func (p *projectRows) PageToken() string {
+	if s, ok := p.source.(PageTokenRows); ok {
+		return s.PageToken()
+	}
+	return ""
+}
+
+// HasMore implement PageTokenRows.
+func (p *projectRows) HasMore() bool {
+	if s, ok := p.source.(PageTokenRows); ok {
+		return s.HasMore()
+	}
+	return false
+}
+
+// readSource read at most n rows from source, return false when source is drained.
+func readSource(source driver.Rows, n int64, fn func(src []driver.Value) error) (bool, error) {
+	for i := int64(0); n <= 0 || i < n; i++ {
+		src := make([]driver.Value, len(source.Columns()))
+		if err := source.Next(src); err != nil {
+			if err == io.EOF {
+				return false, nil
+			}
+			return false, err
+		}
+		if err := fn(src); err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
@@ -21,79 +21,148 @@ type recordRows struct {
 	fieldNames string
 	filter     string
 	fields     map[string]lark.Field
-	recordID   string
+	// recordIDs are read by id instead of listing the records, nil to list them
+	recordIDs []string
 }
 
 func newRecordRows(base *rows, table string, view string, sort string, fieldNames []string,
-	fields map[string]lark.Field, filter string, recordID string, limit int64) driver.Rows {
+	fields map[string]lark.Field, filter string, recordIDs []string, limit int64) driver.Rows {
 	newRows := base.Clone(nil, nil)
 	newRows.columns = append([]string{FieldKeyRecordID}, fieldNames...)
 	newRows.limit = limit
 	newRows.pageList = &lark.PageList{}
+	// resume from the page token of a previous query
+	if pageToken := pageTokenFromContext(base.ctx); pageToken != "" && recordIDs == nil {
+		newRows.pageList = &lark.PageList{PageToken: pageToken, HasMore: true}
+	}
 	p := &recordRows{rows: newRows, table: table, view: view, sort: sort,
-		fieldNames: oneLine(fieldNames), fields: fields, filter: filter, recordID: recordID}
+		fields: fields, filter: filter, recordIDs: recordIDs}
+	// only request the needed fields, empty field_names return all fields
+	if !isAllFields(fieldNames, fields) {
+		p.fieldNames = oneLine(fieldNames)
+	}
 	return newRowsFactory(p)
 }
 
+// isAllFields report whether the unique fieldNames are all fields of the table.
+func isAllFields(fieldNames []string, fields map[string]lark.Field) bool {
+	if len(fieldNames) != len(fields) {
+		return false
+	}
+	for _, name := range fieldNames {
+		if _, ok := fields[name]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// Pick scan the fields of a record, absent fields and empty values are NULL.
 func (p *recordRows) Pick(dst []driver.Value, data interface{}) {
 	item, ok := data.(*lark.Record)
 	if !ok {
 		return
 	}
 	dst[0] = item.RecordID
-	for i, col := range p.columns {
-		if v, ok := item.Fields[col]; ok {
-			if f, ok := p.fields[col]; ok {
-				switch FieldType(f.Type) {
-				case FieldTypeText, FieldTypeSelect:
-					dst[i] = v
-				case FieldTypeNumber:
-					if v == nil {
-						dst[i] = 0
-					} else {
-						dst[i], _ = strconv.ParseFloat(v.(string), 64)
-					}
-				case FieldTypeCheckbox:
-					dst[i], _ = v.(bool)
-				case FieldTypeLink, FieldTypePerson, FieldTypeAttachment, FieldTypeMultipleSelect:
-					dst[i] = oneLine(v)
-				case FieldTypeDate, FieldTypeCreateTime, FieldTypeUpdateTime:
-					if v != nil {
-						dst[i] = time.Unix(int64(v.(float64)/1e3), 0)
-					}
+	for i := 1; i < len(p.columns); i++ {
+		col := p.columns[i]
+		// dst is reused between rows, so every column must be set
+		dst[i] = nil
+		v, ok := item.Fields[col]
+		if !ok || v == nil {
+			continue
+		}
+		f, ok := p.fields[col]
+		if !ok {
+			dst[i] = oneLine(v)
+			continue
+		}
+		switch FieldType(f.Type) {
+		case FieldTypeText, FieldTypeSelect:
+			dst[i] = v
+		case FieldTypeNumber:
+			switch n := v.(type) {
+			case string:
+				if n != "" {
+					dst[i], _ = strconv.ParseFloat(n, 64)
 				}
-				continue
+			default:
+				dst[i] = n
+			}
+		case FieldTypeCheckbox:
+			dst[i], _ = v.(bool)
+		case FieldTypeDate, FieldTypeCreateTime, FieldTypeUpdateTime:
+			if ms, ok := v.(float64); ok {
+				dst[i] = time.Unix(int64(ms/1e3), 0).In(p.conn.config().Loc)
+			}
+		default:
+			if !isEmptyValue(v) {
+				dst[i] = oneLine(v)
 			}
-			dst[i] = oneLine(v)
 		}
 	}
 }
 
+// isEmptyValue report whether v is an empty array or object, like the persons or attachments of an empty field.
+func isEmptyValue(v interface{}) bool {
+	switch v := v.(type) {
+	case []interface{}:
+		return len(v) == 0
+	case map[string]interface{}:
+		return len(v) == 0
+	case string:
+		return v == ""
+	}
+	return false
+}
+
 func (p *recordRows) Load() (*lark.PageList, error) {
-	pageSize := DefaultPageSize
-	if p.limit > 0 && p.limit < pageSize {
-		pageSize = p.limit
+	pageSize := p.conn.config().PageSize
+	// the last page stop at limit, so the page token point to the next unread record
+	if rest := p.limit - p.count; p.limit > 0 && rest < pageSize {
+		pageSize = rest
 	}
-	if p.recordID != "" {
-		return p.loadRecord()
+	if p.recordIDs != nil {
+		return p.loadRecords()
 	}
 	res, err := p.conn.ListRecords(p.ctx, p.appToken, p.table, p.view, p.fieldNames, p.filter, p.sort, p.pageList.PageToken, pageSize)
 	if err != nil {
 		return nil, fmt.Errorf("load records %w", err)
 	}
+	if cursor := pageCursorFromContext(p.ctx); cursor != nil {
+		cursor.PageToken = res.PageToken
+		cursor.HasMore = res.HasMore
+	}
 	return res, nil
 }
 
-func (p recordRows) loadRecord() (*lark.PageList, error) {
-	res, err := p.conn.GetRecord(p.ctx, p.appToken, p.table, p.recordID)
+// PageToken implement PageTokenRows.
+func (p *recordRows) PageToken() string {
+	if p.recordIDs != nil {
+		return ""
+	}
+	return p.pageList.PageToken
+}
+
+// HasMore implement PageTokenRows.
+func (p *recordRows) HasMore() bool {
+	return p.recordIDs == nil && p.pageList.HasMore
+}
+
+// loadRecords get the records of recordIDs at once, the absent records are skipped.
+func (p recordRows) loadRecords() (*lark.PageList, error) {
+	records, err := p.conn.BatchGetRecords(p.ctx, p.appToken, p.table, p.recordIDs)
 	if err != nil {
-		return nil, fmt.Errorf("get record: %w", err)
+		return nil, fmt.Errorf("get records: %w", err)
+	}
+	items := make([]interface{}, 0, len(records))
+	for _, record := range records {
+		items = append(items, record)
 	}
-	items := []interface{}{res}
 	return &lark.PageList{
 		// 如果需要后续查询，pageToken 不应该为空
 		PageToken: loadOneTime,
-		Total:     1,
+		Total:     int64(len(items)),
 		HasMore:   false,
 		Items:     items,
 	}, nil
@@ -0,0 +1,299 @@
+package driver
+
+import (
+	"bufio"
+	"container/heap"
+	"database/sql/driver"
+	"encoding/gob"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/luw2007/bitable-mysql-driver/internal/lark"
+)
+
+// sortMemoryLimit the estimated bytes of rows sorted in memory, more rows spill to temporary files.
+var sortMemoryLimit int64 = 64 << 20
+
+// the values of the spilled rows, a rich text or an unknown field is the decoded json
+func init() {
+	gob.Register(time.Time{})
+	gob.Register([]interface{}{})
+	gob.Register(map[string]interface{}{})
+}
+
+// sortKey evaluate an ORDER BY item against the source row.
+type sortKey struct {
+	value projection
+	desc  bool
+}
+
+// sortedRow a row with its evaluated sort keys, exported fields for gob.
+type sortedRow struct {
+	Keys   []interface{}
+	Values []interface{}
+}
+
+// sortRows sort all rows of source in driver, it's an external merge sort when rows exceed sortMemoryLimit.
+type sortRows struct {
+	*rows
+	source driver.Rows
+	keys   []sortKey
+	merger *sortMerger
+}
+
+func newSortRows(base *rows, source driver.Rows, keys []sortKey, limit int64) driver.Rows {
+	newRows := base.Clone(source.Columns(), nil)
+	newRows.limit = limit
+	return newRowsFactory(&sortRows{rows: newRows, source: source, keys: keys})
+}
+
+func (p *sortRows) Load() (*lark.PageList, error) {
+	if p.merger == nil {
+		merger, err := p.sort()
+		if err != nil {
+			return nil, fmt.Errorf("sort rows: %w", err)
+		}
+		p.merger = merger
+	}
+	items := make([]interface{}, 0, DefaultPageSize)
+	for int64(len(items)) < DefaultPageSize {
+		row, err := p.merger.next()
+		if err == io.EOF {
+			return &lark.PageList{Items: items}, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("merge sorted rows: %w", err)
+		}
+		items = append(items, row.Values)
+	}
+	return &lark.PageList{Items: items, HasMore: true}, nil
+}
+
+// sort read all rows of source, spill every sorted run over sortMemoryLimit to a temporary file.
+func (p *sortRows) sort() (*sortMerger, error) {
+	merger := &sortMerger{less: p.less}
+	var run []*sortedRow
+	var size int64
+	_, err := readSource(p.source, 0, func(src []driver.Value) error {
+		row := &sortedRow{Keys: make([]interface{}, len(p.keys)), Values: make([]interface{}, len(src))}
+		for i, key := range p.keys {
+			v, err := key.value(src)
+			if err != nil {
+				return err
+			}
+			row.Keys[i] = normalizeValue(v)
+		}
+		for i, v := range src {
+			row.Values[i] = v
+		}
+		run = append(run, row)
+		size += estimateSize(row.Keys) + estimateSize(row.Values)
+		if size < sortMemoryLimit {
+			return nil
+		}
+		if err := merger.spill(p.sortRun(run)); err != nil {
+			return err
+		}
+		run, size = nil, 0
+		return nil
+	})
+	if err != nil {
+		merger.close()
+		return nil, err
+	}
+	merger.addMemory(p.sortRun(run))
+	heap.Init(merger)
+	return merger, nil
+}
+
+func (p *sortRows) sortRun(run []*sortedRow) []*sortedRow {
+	sort.SliceStable(run, func(i, j int) bool {
+		return p.less(run[i], run[j])
+	})
+	return run
+}
+
+// less order NULL first for ASC and last for DESC like MySQL.
+func (p *sortRows) less(a, b *sortedRow) bool {
+	for i, key := range p.keys {
+		c := compareNullable(a.Keys[i], b.Keys[i])
+		if c == 0 {
+			continue
+		}
+		if key.desc {
+			return c > 0
+		}
+		return c < 0
+	}
+	return false
+}
+
+func (p *sortRows) Close() error {
+	if p.merger != nil {
+		p.merger.close()
+	}
+	return p.source.Close()
+}
+
+// compareNullable compare values where NULL is smaller than any value.
+func compareNullable(a, b interface{}) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1
+	case b == nil:
+		return 1
+	}
+	return compareValues(a, b)
+}
+
+func estimateSize(values []interface{}) int64 {
+	size := int64(0)
+	for _, v := range values {
+		size += estimateValueSize(v)
+	}
+	return size
+}
+
+func estimateValueSize(v interface{}) int64 {
+	switch s := v.(type) {
+	case string:
+		return 16 + int64(len(s))
+	case []byte:
+		return 16 + int64(len(s))
+	}
+	return 16
+}
+
+// sortRun a sorted run in memory or in a temporary file.
+type sortRun struct {
+	head *sortedRow
+	seq  int
+
+	rows []*sortedRow
+
+	file    *os.File
+	decoder *gob.Decoder
+}
+
+func (r *sortRun) advance() error {
+	r.head = nil
+	if r.decoder == nil {
+		if len(r.rows) > 0 {
+			r.head, r.rows = r.rows[0], r.rows[1:]
+		}
+		return nil
+	}
+	row := &sortedRow{}
+	if err := r.decoder.Decode(row); err != nil {
+		if err == io.EOF {
+			return nil
+		}
+		return err
+	}
+	r.head = row
+	return nil
+}
+
+// sortMerger merge the sorted runs by a heap of run heads.
+type sortMerger struct {
+	runs  []*sortRun
+	files []*os.File
+	less  func(a, b *sortedRow) bool
+}
+
+func (m *sortMerger) spill(rows []*sortedRow) error {
+	f, err := ioutil.TempFile("", "bitable-sort-*")
+	if err != nil {
+		return err
+	}
+	m.files = append(m.files, f)
+	w := bufio.NewWriter(f)
+	encoder := gob.NewEncoder(w)
+	for _, row := range rows {
+		if err := encoder.Encode(row); err != nil {
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	run := &sortRun{seq: len(m.runs), file: f, decoder: gob.NewDecoder(bufio.NewReader(f))}
+	if err := run.advance(); err != nil {
+		return err
+	}
+	m.runs = append(m.runs, run)
+	return nil
+}
+
+func (m *sortMerger) addMemory(rows []*sortedRow) {
+	run := &sortRun{seq: len(m.runs), rows: rows}
+	_ = run.advance()
+	if run.head != nil {
+		m.runs = append(m.runs, run)
+	}
+}
+
+func (m *sortMerger) next() (*sortedRow, error) {
+	if len(m.runs) == 0 {
+		return nil, io.EOF
+	}
+	run := m.runs[0]
+	row := run.head
+	if err := run.advance(); err != nil {
+		return nil, err
+	}
+	if run.head == nil {
+		heap.Pop(m)
+	} else {
+		heap.Fix(m, 0)
+	}
+	return row, nil
+}
+
+func (m *sortMerger) close() {
+	for _, f := range m.files {
+		_ = f.Close()
+		_ = os.Remove(f.Name())
+	}
+	m.files = nil
+	m.runs = nil
+}
+
+func (m *sortMerger) Len() int {
+	return len(m.runs)
+}
+
+// Less keep the order of runs for equal rows, so the sort is stable.
+func (m *sortMerger) Less(i, j int) bool {
+	a, b := m.runs[i], m.runs[j]
+	if m.less(a.head, b.head) {
+		return true
+	}
+	if m.less(b.head, a.head) {
+		return false
+	}
+	return a.seq < b.seq
+}
+
+func (m *sortMerger) Swap(i, j int) {
+	m.runs[i], m.runs[j] = m.runs[j], m.runs[i]
+}
+
+func (m *sortMerger) Push(x interface{}) {
+	m.runs = append(m.runs, x.(*sortRun))
+}
+
+func (m *sortMerger) Pop() interface{} {
+	run := m.runs[len(m.runs)-1]
+	m.runs = m.runs[:len(m.runs)-1]
+	return run
+}
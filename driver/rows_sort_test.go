@@ -0,0 +1,74 @@
+package driver
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSelectOrderBy(t *testing.T) {
+	t.Run("push down plain fields", func(t *testing.T) {
+		table := newSalesTable()
+		_, res := queryAll(t, newMockDB(t, table), "SELECT name FROM tbl ORDER BY amount DESC, name LIMIT 2")
+		assert.Len(t, res, 2)
+		assert.Equal(t, `["amount DESC","name ASC"]`, *table.listRequests()[0].Sort)
+	})
+
+	t.Run("ordinal and alias of expressions", func(t *testing.T) {
+		table := newSalesTable()
+		db := newMockDB(t, table)
+		_, res := queryAll(t, db, "SELECT name, amount * 2 AS twice FROM tbl ORDER BY 2 DESC")
+		assert.Equal(t, []interface{}{"rec1", "apple", 6.0}, res[0])
+		assert.Equal(t, []interface{}{"rec3", "cherry", 4.0}, res[1])
+		assert.Equal(t, []interface{}{"rec2", "banana", 2.0}, res[2])
+		assert.Equal(t, "", *table.listRequests()[0].Sort)
+
+		_, res = queryAll(t, db, "SELECT name, amount * 2 AS twice FROM tbl ORDER BY owner DESC, twice LIMIT 2")
+		assert.Equal(t, [][]interface{}{{"rec2", "banana", 2.0}, {"rec3", "cherry", 4.0}}, res)
+
+		_, err := db.Query("SELECT name FROM tbl ORDER BY 3")
+		assert.Error(t, err)
+		_, err = db.Query("SELECT name FROM tbl ORDER BY missing")
+		assert.Error(t, err)
+	})
+
+	t.Run("table with formula field", func(t *testing.T) {
+		table := newSalesTable()
+		table.fields = append(table.fields, newMockField("total", FieldTypeFormula))
+		_, res := queryAll(t, newMockDB(t, table), "SELECT name FROM tbl ORDER BY amount")
+		assert.Equal(t, [][]interface{}{{"rec2", "banana"}, {"rec3", "cherry"}, {"rec1", "apple"}}, res)
+		assert.Equal(t, "", *table.listRequests()[0].Sort)
+	})
+
+	t.Run("spill to temporary files", func(t *testing.T) {
+		defer func(limit int64) { sortMemoryLimit = limit }(sortMemoryLimit)
+		sortMemoryLimit = 1024
+
+		table := newMockTable()
+		for i := 0; i < 250; i++ {
+			table.addRecord(fmt.Sprintf("rec%d", i), map[string]interface{}{"name": fmt.Sprintf("n%d", i), "amount": float64(i % 50)})
+		}
+		_, res := queryAll(t, newMockDB(t, table), "SELECT amount FROM tbl ORDER BY amount DESC, CONCAT('x', name) LIMIT 120")
+		assert.Len(t, res, 120)
+		assert.Equal(t, []interface{}{"rec149", 49.0}, res[0])
+		assert.Equal(t, []interface{}{"rec199", 49.0}, res[1])
+		assert.Equal(t, []interface{}{"rec99", 49.0}, res[4])
+		assert.Equal(t, []interface{}{"rec76", 26.0}, res[119])
+	})
+	t.Run("spill arrays and objects", func(t *testing.T) {
+		defer func(limit int64) { sortMemoryLimit = limit }(sortMemoryLimit)
+		sortMemoryLimit = 1024
+
+		// a text with a mention or a link is an array of segments
+		table := newMockTable()
+		for i := 0; i < 100; i++ {
+			table.addRecord(fmt.Sprintf("rec%d", i), map[string]interface{}{"amount": float64(i),
+				"name": []interface{}{map[string]interface{}{"type": "text", "text": fmt.Sprintf("n%d", i)}}})
+		}
+		_, res := queryAll(t, newMockDB(t, table), "SELECT name FROM tbl ORDER BY amount + 0 DESC")
+		assert.Len(t, res, 100)
+		assert.Equal(t, []interface{}{"rec99", []interface{}{map[string]interface{}{"type": "text", "text": "n99"}}}, res[0])
+		assert.Equal(t, []interface{}{"rec0", []interface{}{map[string]interface{}{"type": "text", "text": "n0"}}}, res[99])
+	})
+}
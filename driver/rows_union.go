@@ -0,0 +1,118 @@
+package driver
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/luw2007/bitable-mysql-driver/internal/lark"
+)
+
+// unionRows stream the rows of sources one by one, rows of the first distinct sources are deduplicated.
+type unionRows struct {
+	*rows
+	sources  []driver.Rows
+	current  int
+	distinct int
+	seen     map[string]struct{}
+}
+
+func newUnionRows(base *rows, columns []string, sources []driver.Rows, distinct int, limit int64) driver.Rows {
+	newRows := base.Clone(columns, nil)
+	newRows.limit = limit
+	return newRowsFactory(&unionRows{rows: newRows, sources: sources, distinct: distinct, seen: make(map[string]struct{})})
+}
+
+// newDistinctRows remove the duplicate rows of source.
+func newDistinctRows(base *rows, source driver.Rows, limit int64) driver.Rows {
+	return newUnionRows(base, source.Columns(), []driver.Rows{source}, 1, limit)
+}
+
+// newLimitRows return at most limit rows of source.
+func newLimitRows(base *rows, source driver.Rows, limit int64) driver.Rows {
+	return newUnionRows(base, source.Columns(), []driver.Rows{source}, 0, limit)
+}
+
+func (p *unionRows) Load() (*lark.PageList, error) {
+	items := make([]interface{}, 0, DefaultPageSize)
+	for p.current < len(p.sources) {
+		dedup := p.current < p.distinct
+		more, err := readSource(p.sources[p.current], DefaultPageSize-int64(len(items)), func(src []driver.Value) error {
+			item := make([]interface{}, len(src))
+			for i, v := range src {
+				item[i] = v
+			}
+			if dedup {
+				key := distinctKey(item)
+				if _, ok := p.seen[key]; ok {
+					return nil
+				}
+				p.seen[key] = struct{}{}
+			}
+			items = append(items, item)
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("union rows: %w", err)
+		}
+		if !more {
+			p.current++
+		}
+		if int64(len(items)) >= DefaultPageSize {
+			return &lark.PageList{Items: items, HasMore: true}, nil
+		}
+	}
+	return &lark.PageList{Items: items}, nil
+}
+
+func (p *unionRows) Close() error {
+	var err error
+	for _, source := range p.sources {
+		if closeErr := source.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+// distinctKey encode values with the type, numbers are equal like MySQL when 1 = 1.0,
+// the integers are exact and strings are case-insensitive like compareValues.
+func distinctKey(values []interface{}) string {
+	var b strings.Builder
+	for _, v := range values {
+		switch v := normalizeValue(v).(type) {
+		case nil:
+			b.WriteString("z")
+		case int64:
+			b.WriteString("n" + strconv.FormatInt(v, 10))
+		case uint64:
+			b.WriteString("n" + strconv.FormatUint(v, 10))
+		case float64:
+			b.WriteString("n" + distinctFloat(v))
+		case time.Time:
+			b.WriteString("t" + strconv.FormatInt(v.UnixNano(), 10))
+		case string:
+			v = strings.ToLower(v)
+			b.WriteString("s" + strconv.Itoa(len(v)) + ":" + v)
+		default:
+			s := strings.ToLower(fmt.Sprint(v))
+			b.WriteString("v" + strconv.Itoa(len(s)) + ":" + s)
+		}
+		b.WriteByte(';')
+	}
+	return b.String()
+}
+
+// distinctFloat format an integral float as the integer, so 2.0 has the key of 2.
+func distinctFloat(f float64) string {
+	if f == 0 {
+		return "0"
+	}
+	if f == math.Trunc(f) && !math.IsInf(f, 0) {
+		return strconv.FormatFloat(f, 'f', 0, 64)
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
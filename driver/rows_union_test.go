@@ -0,0 +1,59 @@
+package driver
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSelectDistinct(t *testing.T) {
+	db := newMockDB(t, newSalesTable())
+	columns, res := queryAll(t, db, "SELECT DISTINCT owner FROM tbl ORDER BY owner LIMIT 5")
+	assert.Equal(t, []string{"owner"}, columns)
+	assert.Equal(t, [][]interface{}{{"alice"}, {"bob"}}, res)
+
+	_, res = queryAll(t, db, "SELECT DISTINCT owner, amount > 1 FROM tbl LIMIT 1")
+	assert.Equal(t, [][]interface{}{{"alice", int64(1)}}, res)
+}
+
+func TestDistinctKey(t *testing.T) {
+	table := newSalesTable()
+	table.addRecord("rec4", map[string]interface{}{"name": "date", "amount": 4.0, "owner": "Alice"})
+	db := newMockDB(t, table)
+	_, res := queryAll(t, db, "SELECT DISTINCT owner FROM tbl")
+	assert.Equal(t, [][]interface{}{{"alice"}, {"bob"}}, res, "strings are compared case-insensitively")
+
+	_, res = queryAll(t, db, "SELECT 'Apple' UNION SELECT 'apple' UNION SELECT 2 UNION SELECT 2.0")
+	assert.Len(t, res, 2)
+	_, res = queryAll(t, db, "SELECT 9007199254740992 UNION SELECT 9007199254740993")
+	assert.Equal(t, [][]interface{}{{int64(9007199254740992)}, {int64(9007199254740993)}}, res)
+
+	assert.Equal(t, distinctKey([]interface{}{int64(3), "A"}), distinctKey([]interface{}{3.0, []byte("a")}))
+	assert.NotEqual(t, distinctKey([]interface{}{int64(3)}), distinctKey([]interface{}{3.5}))
+	assert.Equal(t, distinctKey([]interface{}{0.0}), distinctKey([]interface{}{math.Copysign(0, -1)}))
+	assert.NotEqual(t, distinctKey([]interface{}{uint64(math.MaxUint64)}), distinctKey([]interface{}{float64(1 << 64)}),
+		"the float 2^64 is 18446744073709551616, not MaxUint64")
+}
+
+func TestSelectUnion(t *testing.T) {
+	table := newSalesTable()
+	db := newMockDB(t, table)
+
+	columns, res := queryAll(t, db, "SELECT owner AS who FROM tbl UNION ALL SELECT name FROM bascnOther.tblOther")
+	assert.Equal(t, []string{"who"}, columns)
+	assert.Len(t, res, 6)
+	requests := table.listRequests()
+	assert.Equal(t, "app_mock", requests[0].AppToken)
+	assert.Equal(t, "bascnOther", requests[1].AppToken)
+	assert.Equal(t, "tblOther", requests[1].TableID)
+
+	_, res = queryAll(t, db, "SELECT owner FROM tbl UNION SELECT 'carol' UNION ALL SELECT 'alice' ORDER BY 1 DESC")
+	assert.Equal(t, [][]interface{}{{"carol"}, {"bob"}, {"alice"}, {"alice"}}, res)
+
+	_, res = queryAll(t, db, "SELECT owner FROM tbl UNION SELECT name FROM tbl LIMIT 3")
+	assert.Equal(t, [][]interface{}{{"alice"}, {"bob"}, {"apple"}}, res)
+
+	_, err := db.Query("SELECT owner FROM tbl UNION SELECT name, amount FROM tbl")
+	assert.Error(t, err)
+}
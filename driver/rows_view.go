@@ -3,19 +3,36 @@ package driver
 import (
 	"database/sql/driver"
 	"fmt"
+	"strings"
 
 	"github.com/luw2007/bitable-mysql-driver/internal/lark"
 )
 
+var (
+	viewColumns     = []string{"id", "name", "type"}
+	fullViewColumns = []string{"id", "name", "type", "filter", "hidden_fields"}
+)
+
 type viewRows struct {
 	*rows
 	table string
+	// names are the field names by id, nil unless the filter and hidden fields of the views are listed
+	names map[string]string
 }
 
-func newViewRows(base *rows, table string) driver.Rows {
+// newViewRows list the views of table, with fields every view is got for its filter and hidden fields.
+func newViewRows(base *rows, table string, fields map[string]lark.Field) driver.Rows {
 	newRows := base.Clone(nil, nil)
-	newRows.columns = []string{"id", "name", "type"}
-	return newRowsFactory(&viewRows{rows: newRows, table: table})
+	newRows.columns = viewColumns
+	p := &viewRows{rows: newRows, table: table}
+	if fields != nil {
+		newRows.columns = fullViewColumns
+		p.names = make(map[string]string, len(fields))
+		for name, field := range fields {
+			p.names[field.FieldID] = name
+		}
+	}
+	return newRowsFactory(p)
 }
 
 func (p *viewRows) Load() (*lark.PageList, error) {
@@ -23,6 +40,16 @@ func (p *viewRows) Load() (*lark.PageList, error) {
 	if err != nil {
 		return nil, fmt.Errorf("load view rows: %w", err)
 	}
+	if p.names == nil {
+		return res, nil
+	}
+	for i, item := range res.Items {
+		view, err := p.conn.GetView(p.ctx, p.appToken, p.table, item.(*lark.View).ViewID)
+		if err != nil {
+			return nil, fmt.Errorf("load view: %w", err)
+		}
+		res.Items[i] = view
+	}
 	return res, nil
 }
 
@@ -34,4 +61,24 @@ func (p *viewRows) Pick(dst []driver.Value, i interface{}) {
 	dst[0] = item.ViewID
 	dst[1] = item.ViewName
 	dst[2] = item.ViewType
+	if p.names == nil {
+		return
+	}
+	dst[3], dst[4] = nil, nil
+	if item.Property == nil {
+		return
+	}
+	if filter := viewSQL(item.Property.FilterInfo, p.names); filter != "" {
+		dst[3] = filter
+	}
+	if len(item.Property.HiddenFields) > 0 {
+		hidden := make([]string, 0, len(item.Property.HiddenFields))
+		for _, id := range item.Property.HiddenFields {
+			if name, ok := p.names[id]; ok {
+				id = name
+			}
+			hidden = append(hidden, id)
+		}
+		dst[4] = strings.Join(hidden, ", ")
+	}
 }
@@ -0,0 +1,490 @@
+package driver
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pingcap/parser/ast"
+	"github.com/pingcap/parser/model"
+
+	"github.com/luw2007/bitable-mysql-driver/internal/lark"
+)
+
+// windowMemoryLimit the estimated bytes of the rows read by window functions, the partitions are computed
+// in memory and more rows are an error.
+var windowMemoryLimit int64 = 256 << 20
+
+// windowFunc a window function computed over the partitions of all the source rows.
+type windowFunc struct {
+	name      string
+	args      []projection
+	partition []projection
+	order     []sortKey
+	frame     *ast.FrameClause
+	// offsets of the frame bounds like `2 PRECEDING`
+	offsets [2]int
+}
+
+// windowRows append the values of window functions to the rows of source,
+// the rows keep the order of source.
+type windowRows struct {
+	*rows
+	source driver.Rows
+	funcs  []*windowFunc
+	done   bool
+}
+
+func newWindowRows(base *rows, source driver.Rows, columns []string, funcs []*windowFunc, limit int64) driver.Rows {
+	newRows := base.Clone(columns, nil)
+	newRows.limit = limit
+	return newRowsFactory(&windowRows{rows: newRows, source: source, funcs: funcs})
+}
+
+func (p *windowRows) Load() (*lark.PageList, error) {
+	if p.done {
+		return &lark.PageList{}, nil
+	}
+	p.done = true
+	var all [][]driver.Value
+	var size int64
+	if _, err := readSource(p.source, 0, func(src []driver.Value) error {
+		for _, v := range src {
+			size += estimateValueSize(v)
+		}
+		if size > windowMemoryLimit {
+			return fmt.Errorf("the rows of window functions exceed %d bytes in memory, filter them by WHERE first",
+				windowMemoryLimit)
+		}
+		all = append(all, src)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	values := make([][]interface{}, len(all))
+	for i := range values {
+		values[i] = make([]interface{}, len(p.funcs))
+	}
+	for i, fn := range p.funcs {
+		if err := fn.compute(all, values, i); err != nil {
+			return nil, fmt.Errorf("window function %s: %w", fn.name, err)
+		}
+	}
+	items := make([]interface{}, 0, len(all))
+	for i, src := range all {
+		item := make([]interface{}, 0, len(src)+len(p.funcs))
+		for _, v := range src {
+			item = append(item, v)
+		}
+		items = append(items, append(item, values[i]...))
+	}
+	return &lark.PageList{Items: items}, nil
+}
+
+func (p *windowRows) Close() error {
+	return p.source.Close()
+}
+
+// windowRow a row of a partition with its order keys.
+type windowRow struct {
+	index int
+	keys  []interface{}
+}
+
+// compute set values[row][col] of the function for every row.
+func (fn *windowFunc) compute(all [][]driver.Value, values [][]interface{}, col int) error {
+	var partitions [][]*windowRow
+	index := make(map[string]int)
+	for i, src := range all {
+		key := ""
+		if len(fn.partition) > 0 {
+			keyValues := make([]interface{}, 0, len(fn.partition))
+			for _, project := range fn.partition {
+				v, err := project(src)
+				if err != nil {
+					return err
+				}
+				keyValues = append(keyValues, v)
+			}
+			key = distinctKey(keyValues)
+		}
+		row := &windowRow{index: i, keys: make([]interface{}, len(fn.order))}
+		for k, order := range fn.order {
+			v, err := order.value(src)
+			if err != nil {
+				return err
+			}
+			row.keys[k] = normalizeValue(v)
+		}
+		n, ok := index[key]
+		if !ok {
+			n = len(partitions)
+			index[key] = n
+			partitions = append(partitions, nil)
+		}
+		partitions[n] = append(partitions[n], row)
+	}
+	for _, partition := range partitions {
+		sort.SliceStable(partition, func(i, j int) bool {
+			return fn.compareKeys(partition[i], partition[j]) < 0
+		})
+		res, err := fn.computePartition(all, partition)
+		if err != nil {
+			return err
+		}
+		for i, row := range partition {
+			values[row.index][col] = res[i]
+		}
+	}
+	return nil
+}
+
+func (fn *windowFunc) compareKeys(a, b *windowRow) int {
+	for i, order := range fn.order {
+		c := compareNullable(a.keys[i], b.keys[i])
+		if c == 0 {
+			continue
+		}
+		if order.desc {
+			return -c
+		}
+		return c
+	}
+	return 0
+}
+
+func (fn *windowFunc) arg(all [][]driver.Value, row *windowRow, i int) (interface{}, error) {
+	if i >= len(fn.args) {
+		return nil, nil
+	}
+	v, err := fn.args[i](all[row.index])
+	return normalizeValue(v), err
+}
+
+// computePartition compute the values of the rows of a sorted partition.
+func (fn *windowFunc) computePartition(all [][]driver.Value, partition []*windowRow) ([]interface{}, error) {
+	n := len(partition)
+	res := make([]interface{}, n)
+	// peers are the rows with equal order keys
+	peerStart := make([]int, n)
+	peerEnd := make([]int, n)
+	for i := 0; i < n; i++ {
+		if i > 0 && fn.compareKeys(partition[i-1], partition[i]) == 0 {
+			peerStart[i] = peerStart[i-1]
+		} else {
+			peerStart[i] = i
+		}
+	}
+	for i := n - 1; i >= 0; i-- {
+		if i < n-1 && fn.compareKeys(partition[i], partition[i+1]) == 0 {
+			peerEnd[i] = peerEnd[i+1]
+		} else {
+			peerEnd[i] = i
+		}
+	}
+
+	switch fn.name {
+	case "row_number":
+		for i := range partition {
+			res[i] = int64(i + 1)
+		}
+		return res, nil
+	case "rank":
+		for i := range partition {
+			res[i] = int64(peerStart[i] + 1)
+		}
+		return res, nil
+	case "dense_rank":
+		rank := int64(0)
+		for i := range partition {
+			if peerStart[i] == i {
+				rank++
+			}
+			res[i] = rank
+		}
+		return res, nil
+	case "lag", "lead":
+		offset := int64(1)
+		if len(fn.args) > 1 {
+			v, err := fn.arg(all, partition[0], 1)
+			if err != nil {
+				return nil, err
+			}
+			offset = toInt(v)
+		}
+		if fn.name == "lag" {
+			offset = -offset
+		}
+		for i, row := range partition {
+			j := i + int(offset)
+			if j < 0 || j >= n {
+				v, err := fn.arg(all, row, 2)
+				if err != nil {
+					return nil, err
+				}
+				res[i] = v
+				continue
+			}
+			v, err := fn.arg(all, partition[j], 0)
+			if err != nil {
+				return nil, err
+			}
+			res[i] = v
+		}
+		return res, nil
+	}
+
+	args := make([]interface{}, n)
+	for i, row := range partition {
+		v, err := fn.arg(all, row, 0)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = v
+	}
+	for i := range partition {
+		start, end, err := fn.frameBounds(i, n, peerStart, peerEnd)
+		if err != nil {
+			return nil, err
+		}
+		if start > end {
+			res[i] = emptyFrameValue(fn.name)
+			continue
+		}
+		v, err := aggregateFrame(fn.name, args[start:end+1])
+		if err != nil {
+			return nil, err
+		}
+		res[i] = v
+	}
+	return res, nil
+}
+
+// frameBounds the rows [start, end] of the frame of row i, the default frame is RANGE
+// BETWEEN UNBOUNDED PRECEDING AND CURRENT ROW with ORDER BY, the whole partition without.
+func (fn *windowFunc) frameBounds(i, n int, peerStart, peerEnd []int) (int, int, error) {
+	if fn.frame == nil {
+		if len(fn.order) == 0 {
+			return 0, n - 1, nil
+		}
+		return 0, peerEnd[i], nil
+	}
+	bound := func(b ast.FrameBound, start bool) (int, error) {
+		switch {
+		case b.Type == ast.CurrentRow && fn.frame.Type == ast.Rows:
+			return i, nil
+		case b.Type == ast.CurrentRow && start:
+			return peerStart[i], nil
+		case b.Type == ast.CurrentRow:
+			return peerEnd[i], nil
+		case b.UnBounded && b.Type == ast.Preceding:
+			return 0, nil
+		case b.UnBounded:
+			return n - 1, nil
+		case fn.frame.Type != ast.Rows:
+			return 0, errors.New("only ROWS frame supports offset")
+		}
+		offset := fn.offsets[1]
+		if start {
+			offset = fn.offsets[0]
+		}
+		if b.Type == ast.Preceding {
+			return i - offset, nil
+		}
+		return i + offset, nil
+	}
+	start, err := bound(fn.frame.Extent.Start, true)
+	if err != nil {
+		return 0, 0, err
+	}
+	end, err := bound(fn.frame.Extent.End, false)
+	if err != nil {
+		return 0, 0, err
+	}
+	if start < 0 {
+		start = 0
+	}
+	if end > n-1 {
+		end = n - 1
+	}
+	return start, end, nil
+}
+
+func emptyFrameValue(name string) interface{} {
+	if name == "count" {
+		return int64(0)
+	}
+	return nil
+}
+
+// aggregateFrame aggregate the values of a frame, NULL values are ignored.
+func aggregateFrame(name string, values []interface{}) (interface{}, error) {
+	var count int64
+	var sum float64
+	var res interface{}
+	for _, v := range values {
+		if v == nil {
+			continue
+		}
+		count++
+		switch name {
+		case "sum", "avg":
+			sum += toFloat(v)
+		case "min":
+			if res == nil || compareValues(v, res) < 0 {
+				res = v
+			}
+		case "max":
+			if res == nil || compareValues(v, res) > 0 {
+				res = v
+			}
+		case "first_value":
+			if count == 1 {
+				res = v
+			}
+		case "last_value":
+			res = v
+		}
+	}
+	switch name {
+	case "count":
+		return count, nil
+	case "sum":
+		if count == 0 {
+			return nil, nil
+		}
+		return sum, nil
+	case "avg":
+		if count == 0 {
+			return nil, nil
+		}
+		return sum / float64(count), nil
+	case "min", "max", "first_value", "last_value":
+		return res, nil
+	}
+	return nil, fmt.Errorf("not supported window function %s", name)
+}
+
+// windowBinder replace the window functions by columns of the window rows.
+type windowBinder struct {
+	specs   map[string]ast.WindowSpec
+	funcs   []*ast.WindowFuncExpr
+	columns map[*ast.WindowFuncExpr]string
+}
+
+func (b *windowBinder) Enter(n ast.Node) (ast.Node, bool) {
+	_, ok := n.(*ast.SubqueryExpr)
+	return n, ok
+}
+
+func (b *windowBinder) Leave(n ast.Node) (ast.Node, bool) {
+	fn, ok := n.(*ast.WindowFuncExpr)
+	if !ok {
+		return n, true
+	}
+	return b.column(fn), true
+}
+
+func (b *windowBinder) column(fn *ast.WindowFuncExpr) ast.ExprNode {
+	name, ok := b.columns[fn]
+	if !ok {
+		name = fmt.Sprintf("#window%d", len(b.funcs))
+		b.columns[fn] = name
+		b.funcs = append(b.funcs, fn)
+	}
+	return &ast.ColumnNameExpr{Name: &ast.ColumnName{Name: model.NewCIStr(name)}}
+}
+
+// bind replace the window functions of expr, expr itself may be a window function.
+func (b *windowBinder) bind(expr ast.ExprNode) ast.ExprNode {
+	if expr == nil {
+		return nil
+	}
+	if fn, ok := expr.(*ast.WindowFuncExpr); ok {
+		return b.column(fn)
+	}
+	node, _ := expr.Accept(b)
+	return node.(ast.ExprNode)
+}
+
+// resolve merge the named window the spec refers to.
+func (b *windowBinder) resolve(spec ast.WindowSpec) (ast.WindowSpec, error) {
+	ref := spec.Ref
+	if spec.OnlyAlias {
+		ref = spec.Name
+	}
+	if ref.L == "" {
+		return spec, nil
+	}
+	base, ok := b.specs[ref.L]
+	if !ok {
+		return spec, fmt.Errorf("window name '%s' is not defined", ref.O)
+	}
+	base, err := b.resolve(base)
+	if err != nil {
+		return spec, err
+	}
+	if spec.OnlyAlias {
+		return base, nil
+	}
+	if spec.PartitionBy == nil {
+		spec.PartitionBy = base.PartitionBy
+	}
+	if spec.OrderBy == nil {
+		spec.OrderBy = base.OrderBy
+	}
+	if spec.Frame == nil {
+		spec.Frame = base.Frame
+	}
+	return spec, nil
+}
+
+// windowFuncs build the window functions evaluated against env.
+func (stmt *bitableStatement) windowFuncs(b *windowBinder, env *evalEnv) ([]*windowFunc, error) {
+	funcs := make([]*windowFunc, 0, len(b.funcs))
+	for _, fn := range b.funcs {
+		spec, err := b.resolve(fn.Spec)
+		if err != nil {
+			return nil, err
+		}
+		w := &windowFunc{name: strings.ToLower(fn.F), frame: spec.Frame}
+		switch w.name {
+		case "row_number", "rank", "dense_rank", "lag", "lead",
+			"sum", "avg", "count", "min", "max", "first_value", "last_value":
+		default:
+			return nil, fmt.Errorf("not supported window function %s", fn.F)
+		}
+		if fn.Distinct {
+			return nil, fmt.Errorf("DISTINCT is not supported by window function %s", fn.F)
+		}
+		for _, arg := range fn.Args {
+			w.args = append(w.args, stmt.exprProjection(arg, env))
+		}
+		if spec.PartitionBy != nil {
+			for _, item := range spec.PartitionBy.Items {
+				w.partition = append(w.partition, stmt.exprProjection(item.Expr, env))
+			}
+		}
+		if spec.OrderBy != nil {
+			for _, item := range spec.OrderBy.Items {
+				w.order = append(w.order, sortKey{value: stmt.exprProjection(item.Expr, env), desc: item.Desc})
+			}
+		}
+		if spec.Frame != nil {
+			for i, bound := range []ast.FrameBound{spec.Frame.Extent.Start, spec.Frame.Extent.End} {
+				if bound.Expr == nil {
+					continue
+				}
+				v, err := stmt.eval(bound.Expr, nil)
+				if err != nil {
+					return nil, err
+				}
+				w.offsets[i] = int(toInt(v))
+			}
+		}
+		funcs = append(funcs, w)
+	}
+	return funcs, nil
+}
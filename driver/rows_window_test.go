@@ -0,0 +1,70 @@
+package driver
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSelectWindow(t *testing.T) {
+	table := newSalesTable()
+	table.addRecord("rec4", map[string]interface{}{"name": "durian", "amount": 3.0, "owner": "bob"})
+	db := newWindowDB(t, table)
+
+	_, res := queryAll(t, db, `SELECT name,
+		ROW_NUMBER() OVER (ORDER BY amount DESC, name) AS rn,
+		RANK() OVER (ORDER BY amount DESC) AS rk,
+		DENSE_RANK() OVER (ORDER BY amount DESC) AS drk,
+		SUM(amount) OVER (PARTITION BY owner ORDER BY name) AS running,
+		LAG(name, 1, '-') OVER w AS prev
+		FROM tbl WINDOW w AS (PARTITION BY owner ORDER BY name) ORDER BY rn LIMIT 3`)
+	assert.Equal(t, [][]interface{}{
+		{"rec1", "apple", int64(1), int64(1), int64(1), 3.0, "-"},
+		{"rec4", "durian", int64(2), int64(1), int64(1), 4.0, "banana"},
+		{"rec3", "cherry", int64(3), int64(3), int64(2), 5.0, "apple"},
+	}, res)
+	// the limit applies after the window functions
+	assert.Equal(t, DefaultPageSize, *table.listRequests()[0].PageSize)
+
+	_, res = queryAll(t, db, `SELECT name, SUM(amount) OVER (ORDER BY name ROWS BETWEEN 1 PRECEDING AND CURRENT ROW),
+		COUNT(owner) OVER (), LEAD(amount) OVER (ORDER BY name) FROM tbl WHERE amount > 0`)
+	assert.Equal(t, []interface{}{"rec2", "banana", 4.0, int64(4), 2.0}, res[1])
+	assert.Equal(t, []interface{}{"rec4", "durian", 5.0, int64(4), nil}, res[3])
+}
+
+func TestSelectWindowNames(t *testing.T) {
+	table := newSalesTable()
+	table.fields = append(table.fields, newMockField("rank", FieldTypeNumber))
+	conn := newMockConn(t, table)
+
+	// the window functions are disabled by default, their names are columns
+	db := sql.OpenDB(mockConnector{conn: conn})
+	_, res := queryAll(t, db, "SELECT rank FROM tbl WHERE name = 'apple'")
+	assert.Equal(t, [][]interface{}{{"rec1", nil}}, res)
+	_, err := db.Query("SELECT ROW_NUMBER() OVER () FROM tbl")
+	assert.Error(t, err)
+
+	// the names of window functions are keywords like MySQL 8.0 when they are enabled
+	conn.cfg.WindowFunc = true
+	db = sql.OpenDB(mockConnector{conn: conn})
+	_, err = db.Query("SELECT rank FROM tbl")
+	assert.Error(t, err)
+	_, res = queryAll(t, db, "SELECT `rank` FROM tbl WHERE name = 'apple'")
+	assert.Equal(t, [][]interface{}{{"rec1", nil}}, res)
+}
+
+func TestSelectWindowMemory(t *testing.T) {
+	defer func(limit int64) { windowMemoryLimit = limit }(windowMemoryLimit)
+	windowMemoryLimit = 100
+
+	db := newWindowDB(t, newSalesTable())
+	rows, err := db.Query("SELECT name, ROW_NUMBER() OVER () FROM tbl")
+	require.NoError(t, err)
+	defer rows.Close()
+	assert.False(t, rows.Next())
+	assert.ErrorContains(t, rows.Err(), "the rows of window functions exceed 100 bytes in memory, filter them by WHERE first")
+	_, res := queryAll(t, db, "SELECT name, ROW_NUMBER() OVER () FROM tbl WHERE name = 'apple'")
+	assert.Equal(t, [][]interface{}{{"rec1", "apple", int64(1)}}, res)
+}
@@ -8,8 +8,10 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"sort"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/pingcap/parser/ast"
@@ -17,13 +19,23 @@ import (
 	"github.com/pingcap/parser/opcode"
 	"github.com/pingcap/parser/test_driver"
 	"github.com/pingcap/parser/types"
-	"github.com/sirupsen/logrus"
 
 	"github.com/luw2007/bitable-mysql-driver/internal/lark"
 )
 
 var (
 	ErrNullValue = errors.New("null value")
+	// errNotPushable means the condition has no filter formula, it is evaluated in driver
+	errNotPushable = errors.New("the condition can't be a filter formula")
+	// errPageTokenResume means the statement reads ahead rows it doesn't return, a page token would skip them
+	errPageTokenResume = errors.New("[bitable driver] page_token can't resume a UNION, a derived table or a SELECT " +
+		"filtered, sorted, distinct or windowed in driver, the rows read ahead would be skipped")
+)
+
+const (
+	// filterFalse and filterTrue are the formulas of the conditions always false or true
+	filterFalse = "FALSE()"
+	filterTrue  = "TRUE()"
 )
 
 // bitableStatement for sql statement
@@ -34,6 +46,14 @@ type bitableStatement struct {
 	args  map[int]driver.NamedValue
 	seek  int
 	query string
+
+	subqueries     map[*ast.SubqueryExpr]*subqueryPlan
+	derivedColumns map[ast.ResultSetNode][]string
+	plan           *plan
+	// affected are the records changed by the last INSERT, UPDATE or DELETE
+	affected int64
+	// explain collect the plan instead of changing the records, set by EXPLAIN
+	explain *explainPlan
 }
 
 // Close  implement for stmt
@@ -41,33 +61,80 @@ func (stmt *bitableStatement) Close() error {
 	return nil
 }
 
-// QueryContext executes a query that may return rows
+// QueryContext executes a query that may return rows, the errors are *Error with the statement.
 func (stmt *bitableStatement) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
-	stmtNodes, _, err := stmt.conn.parser.Parse(stmt.query, "", "")
+	ctx, span := stmt.startSpan(ctx)
+	ctx = lark.WithWrites(ctx)
+	rows, err := stmt.execute(ctx, args)
+	if err != nil {
+		err = stmt.conn.checkError(err, stmt.query, lark.Written(ctx))
+		span.end(err)
+		return nil, err
+	}
+	r, ok := rows.(*rowsFactory)
+	if !ok {
+		if span != nil {
+			span.rows = stmt.affected
+		}
+		span.end(nil)
+		return rows, nil
+	}
+	r.conn, r.query, r.span = stmt.conn, stmt.query, span
+	return r, nil
+}
+
+// execute bind args to the plan and run the statement.
+func (stmt *bitableStatement) execute(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	ctx, err := stmt.conn.sessionContext(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("[bitable driver] parser %w", err)
+		return nil, err
+	}
+	if stmt.plan == nil {
+		if err := stmt.prepare(ctx); err != nil {
+			return nil, err
+		}
 	}
-	stmt.stmt = stmtNodes
+	p := stmt.plan
+	node, offsets := p.node, p.offsets
+	stmt.derivedColumns = p.derivedColumns
+	if !p.reusable {
+		// executing changes the AST, parse a new one
+		if node, offsets, err = stmt.parse(p.query); err != nil {
+			return nil, err
+		}
+	}
+	stmt.stmt = []ast.StmtNode{node}
 	stmt.ctx = ctx
-	stmt.args = buildNamedArgs(stmt.query, args)
-	logrus.Debug("[bitable driver]  do query")
+	if stmt.args, err = bindArgs(offsets, p.names, args); err != nil {
+		return nil, fmt.Errorf("[bitable driver] %w", err)
+	}
+	stmt.subqueries = nil
+	stmt.affected = 0
+	stmt.conn.log(ctx, LevelDebug, "[bitable driver] query", "sql", redact(stmt.query))
 	baseRows := &rows{
 		ctx:      stmt.ctx,
 		conn:     stmt.conn,
-		appToken: stmt.conn.AppToken,
-	}
-	if len(stmt.stmt) != 1 {
-		return nil, fmt.Errorf("only one statement")
+		appToken: stmt.conn.appToken(),
 	}
 	switch s := stmt.stmt[0].(type) {
 	case *ast.UseStmt:
 		return stmt.UseStmt(baseRows, s)
+	case *ast.SetStmt:
+		return stmt.setStmt(baseRows, s)
 	case *ast.ShowStmt:
 		return stmt.showStmt(baseRows, s)
 	case *ast.SelectStmt:
 		return stmt.selectStmt(baseRows, s)
+	case *ast.UnionStmt:
+		return stmt.unionStmt(baseRows, s, true)
 	case *ast.CreateViewStmt:
 		return stmt.createViewStmt(baseRows, s)
+	case *alterViewStmt:
+		return stmt.alterView(baseRows, s)
+	case *renameViewStmt:
+		return stmt.renameView(baseRows, s)
+	case *showViewsStmt:
+		return stmt.showViews(baseRows, s)
 	case *ast.CreateTableStmt:
 		return stmt.createTableStmt(baseRows, s)
 	case *ast.DropTableStmt:
@@ -80,127 +147,55 @@ func (stmt *bitableStatement) QueryContext(ctx context.Context, args []driver.Na
 		return stmt.deleteStmt(baseRows, s)
 	case *ast.AlterTableStmt:
 		return stmt.alterTableStmt(baseRows, s)
+	case *ast.RenameTableStmt:
+		return stmt.renameTableStmt(baseRows, s)
+	case *ast.ExplainStmt:
+		return stmt.explainStmt(baseRows, s)
 	default:
 		return nil, fmt.Errorf("bitable driver is not supported SQL: %s", stmt.query)
 	}
 }
 
-func buildNamedArgs(query string, args []driver.NamedValue) map[int]driver.NamedValue {
-	mask := '?'
-	want := make(map[int]driver.NamedValue, len(args))
-	index := 0
-	for i, v := range query {
-		if v == mask {
-			want[i] = args[index]
-			index++
-		}
+// parse split the WITH clause and parse query, offsets are the param markers in the order of the query.
+func (stmt *bitableStatement) parse(query string) (ast.StmtNode, []int, error) {
+	query, ctes, err := splitWith(query)
+	if err != nil {
+		return nil, nil, fmt.Errorf("[bitable driver] parser %w", err)
 	}
-	return want
-}
-
-func convertNamedValue(args []driver.NamedValue) []driver.Value {
-	values := make([]driver.Value, 0, len(args))
-	for _, arg := range args {
-		values = append(values, arg.Value)
+	query, wrap, delta := rewriteView(query)
+	// the parser isn't safe for concurrent use
+	stmt.conn.mu.Lock()
+	defer stmt.conn.mu.Unlock()
+	stmtNodes, _, err := stmt.conn.parser.Parse(query, "", "")
+	if err != nil {
+		return nil, nil, fmt.Errorf("[bitable driver] parser %w", err)
 	}
-	return values
-}
-
-// Query  implement for Query
-func (stmt *bitableStatement) Query(args []driver.Value) (driver.Rows, error) {
-	panic("not implemented, use QueryContext instead")
-}
-
-func (stmt *bitableStatement) dropTableStmt(r *rows, s *ast.DropTableStmt) (driver.Rows, error) {
-	for _, t := range s.Tables {
-		table, view, err := stmt.getTableView(r.ctx, t)
+	if len(stmtNodes) != 1 {
+		return nil, nil, fmt.Errorf("only one statement")
+	}
+	// the parser reuse the result slice, keep the node before parsing the CTEs
+	node := stmtNodes[0]
+	if wrap != nil {
+		node.Accept(paramShifter{delta: delta})
+		node = wrap(node)
+	}
+	stmt.derivedColumns = nil
+	nodes := []ast.Node{node}
+	if len(ctes) > 0 {
+		queries, err := stmt.bindCommonTables(ctes, node)
 		if err != nil {
-			return nil, fmt.Errorf("[bitable driver] get table view error: %w", err)
+			return nil, nil, fmt.Errorf("[bitable driver] %w", err)
 		}
-		if s.IsView {
-			if err := stmt.conn.DropView(r.ctx, r.appToken, table, view); err != nil {
-				return nil, fmt.Errorf("[bitable driver] %w", err)
-			}
-			return nil, nil
-		}
-		if err := stmt.conn.DropTable(r.ctx, r.appToken, table); err != nil {
-			return nil, fmt.Errorf("[bitable driver] %w", err)
+		for _, query := range queries {
+			nodes = append(nodes, query)
 		}
 	}
-	return nil, nil
+	return node, paramOffsets(nodes...), nil
 }
 
-func (stmt *bitableStatement) alterTableStmt(r *rows, s *ast.AlterTableStmt) (driver.Rows, error) {
-	table, _, err := stmt.getTableView(r.ctx, s.Table)
-	if err != nil {
-		return nil, fmt.Errorf("[bitable driver] %w", err)
-	}
-	for _, spec := range s.Specs {
-		switch spec.Tp {
-		case ast.AlterTableAddColumns:
-			items := make([]interface{}, 0, len(spec.NewColumns))
-			for _, column := range spec.NewColumns {
-				fieldName := column.Name.Name.O
-				fieldType := stmt.getFieldType(r.ctx, column.Tp)
-				comment := stmt.getComment(column.Options)
-				field, err := stmt.conn.AddField(r.ctx, r.appToken, table, fieldName, fieldType, comment)
-				if err != nil {
-					return nil, fmt.Errorf("[bitable driver] %w", err)
-				}
-				items = append(items, []interface{}{field.FieldID, field.FieldName, field.Type, oneLine(field.Property)})
-			}
-			columns := []string{"field_id", "name", "type", "property"}
-			newRows := r.Clone(columns, items)
-			return newRowsFactory(newRows), nil
-		case ast.AlterTableDropColumn:
-			fieldName := spec.OldColumnName.Name.O
-			fieldId, _, _, err := stmt.getFieldID(r.ctx, r.appToken, table, fieldName)
-			if err != nil {
-				return nil, fmt.Errorf("[bitable driver] %w", err)
-			}
-			if _, err = stmt.conn.DeleteField(r.ctx, r.appToken, table, fieldId); err != nil {
-				return nil, fmt.Errorf("[bitable driver] %w", err)
-			}
-			return nil, nil
-		case ast.AlterTableRenameColumn:
-			oldFieldName := spec.OldColumnName.Name.O
-			fieldName := spec.NewColumnName.Name.O
-			fieldID, oldFieldType, oldComment, err := stmt.getFieldID(r.ctx, r.appToken, table, oldFieldName)
-			if err != nil {
-				return nil, fmt.Errorf("[bitable driver] %w", err)
-			}
-			_, err = stmt.conn.UpdateField(r.ctx, r.appToken, table, fieldID, fieldName, oldFieldType, oldComment)
-			if err != nil {
-				return nil, fmt.Errorf("[bitable driver] %w", err)
-			}
-		case ast.AlterTableModifyColumn, ast.AlterTableChangeColumn:
-			for _, column := range spec.NewColumns {
-				fieldName := column.Name.Name.O
-				fieldType := stmt.getFieldType(r.ctx, column.Tp)
-				oldFieldName := fieldName
-				// modify column
-				if spec.OldColumnName != nil {
-					oldFieldName = spec.OldColumnName.Name.O
-				}
-				fieldID, oldFieldType, oldComment, err := stmt.getFieldID(r.ctx, r.appToken, table, oldFieldName)
-				if err != nil {
-					return nil, fmt.Errorf("[bitable driver] %w", err)
-				}
-				if fieldType == 0 {
-					fieldType = oldFieldType
-				}
-				comment := stmt.getComment(column.Options)
-				if comment == "" {
-					comment = oldComment
-				}
-				_, err = stmt.conn.UpdateField(r.ctx, r.appToken, table, fieldID, fieldName, fieldType, comment)
-				if err != nil {
-					return nil, fmt.Errorf("[bitable driver] %w", err)
-				}
-			}
-		}
-	}
-	return nil, nil
+// Query  implement for Query
+func (stmt *bitableStatement) Query(args []driver.Value) (driver.Rows, error) {
+	panic("not implemented, use QueryContext instead")
 }
 
 func (stmt *bitableStatement) deleteStmt(r *rows, s *ast.DeleteStmt) (driver.Rows, error) {
@@ -208,16 +203,14 @@ func (stmt *bitableStatement) deleteStmt(r *rows, s *ast.DeleteStmt) (driver.Row
 	if err != nil {
 		return nil, fmt.Errorf("[bitable driver] %w", err)
 	}
-	filter, err := stmt.buildFilter(r.ctx, s.Where)
+	plan, err := stmt.planChanges(r, table, view, s.Where)
 	if err != nil {
-		return nil, fmt.Errorf("[bitable driver] %w", err)
+		return nil, err
 	}
-
-	var recordID string
-	onlyRecordIDFilter := "CurrentValue.[record_id] = "
-	if strings.HasPrefix(filter, onlyRecordIDFilter) {
-		recordID = filter[len(onlyRecordIDFilter)+1 : len(filter)-1]
-		filter = ""
+	if plan.empty {
+		// nothing matches a NULL condition or no record id
+		stmt.explainf("Empty", table, "0", "the condition is always false")
+		return nil, nil
 	}
 
 	deleteCallback := func(ctx context.Context, m map[string]map[string]interface{}) (int, error) {
@@ -233,20 +226,15 @@ func (stmt *bitableStatement) deleteStmt(r *rows, s *ast.DeleteStmt) (driver.Row
 		}
 		return count, nil
 	}
-	var limit int64
-	if s.Limit != nil {
-		if v, ok := s.Limit.Count.(*test_driver.ValueExpr); ok {
-			limit = v.GetInt64()
-		}
+	limit, err := stmt.getLimit(s.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("[bitable driver] %w", err)
 	}
-	if len(recordID) > 0 {
-		_, err = deleteCallback(r.ctx, map[string]map[string]interface{}{recordID: nil})
-		if err != nil {
-			return nil, fmt.Errorf("[bitable driver] %w", err)
-		}
+	if stmt.explain != nil {
+		stmt.explainChanges("DeleteRecord", table, plan, limit, "1 per record")
 		return nil, nil
 	}
-	return stmt.searchRecords(r.ctx, r.appToken, table, view, filter, nil, limit, deleteCallback)
+	return stmt.changeRecords(r, table, plan, nil, limit, deleteCallback)
 }
 
 func (stmt *bitableStatement) updateStmt(r *rows, s *ast.UpdateStmt) (driver.Rows, error) {
@@ -254,16 +242,14 @@ func (stmt *bitableStatement) updateStmt(r *rows, s *ast.UpdateStmt) (driver.Row
 	if err != nil {
 		return nil, fmt.Errorf("[bitable driver] %w", err)
 	}
-	filter, err := stmt.buildFilter(r.ctx, s.Where)
+	plan, err := stmt.planChanges(r, table, view, s.Where)
 	if err != nil {
-		return nil, fmt.Errorf("[bitable driver] %w", err)
+		return nil, err
 	}
-
-	var recordID string
-	onlyRecordIDFilter := "CurrentValue.[record_id] = "
-	if strings.HasPrefix(filter, onlyRecordIDFilter) {
-		recordID = filter[len(onlyRecordIDFilter)+1 : len(filter)-1]
-		filter = ""
+	if plan.empty {
+		// nothing matches a NULL condition or no record id
+		stmt.explainf("Empty", table, "0", "the condition is always false")
+		return nil, nil
 	}
 	updateCallBack := func(ctx context.Context, m map[string]map[string]interface{}) (int, error) {
 		records, err := stmt.conn.UpdateRecords(ctx, r.appToken, table, m)
@@ -273,13 +259,12 @@ func (stmt *bitableStatement) updateStmt(r *rows, s *ast.UpdateStmt) (driver.Row
 		return len(records), nil
 	}
 
-	var limit int64
-	if s.Limit != nil {
-		if v, ok := s.Limit.Count.(*test_driver.ValueExpr); ok {
-			limit = v.GetInt64()
-		}
+	limit, err := stmt.getLimit(s.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("[bitable driver] %w", err)
 	}
 
+	// a nil value is sent as null, which clears the field
 	data := make(map[string]interface{})
 	for _, row := range s.List {
 		switch v := row.Expr.(type) {
@@ -290,6 +275,10 @@ func (stmt *bitableStatement) updateStmt(r *rows, s *ast.UpdateStmt) (driver.Row
 				switch s := v2.Value.(type) {
 				case string:
 					data[row.Column.Name.O] = s
+				case []byte:
+					data[row.Column.Name.O] = string(s)
+				case time.Time:
+					data[row.Column.Name.O] = s.UnixNano() / 1e6
 				default:
 					data[row.Column.Name.O] = v2.Value
 				}
@@ -299,14 +288,17 @@ func (stmt *bitableStatement) updateStmt(r *rows, s *ast.UpdateStmt) (driver.Row
 	if _, ok := data[FieldKeyRecordID]; ok {
 		delete(data, FieldKeyRecordID)
 	}
-	if len(recordID) > 0 {
-		_, err = updateCallBack(r.ctx, map[string]map[string]interface{}{recordID: data})
-		if err != nil {
-			return nil, fmt.Errorf("[bitable driver] %w", err)
+	if stmt.explain != nil {
+		names := make([]string, 0, len(data))
+		for name := range data {
+			names = append(names, name)
 		}
+		sort.Strings(names)
+		stmt.explainChanges("BatchUpdateRecords", table, plan, limit, "1 per page",
+			"fields: "+strings.Join(names, ", "))
 		return nil, nil
 	}
-	return stmt.searchRecords(r.ctx, r.appToken, table, view, filter, data, limit, updateCallBack)
+	return stmt.changeRecords(r, table, plan, data, limit, updateCallBack)
 }
 
 func (stmt *bitableStatement) insertStmt(r *rows, s *ast.InsertStmt) (driver.Rows, error) {
@@ -329,6 +321,9 @@ func (stmt *bitableStatement) insertStmt(r *rows, s *ast.InsertStmt) (driver.Row
 			markerExpr, ok := v.(*test_driver.ParamMarkerExpr)
 			if ok {
 				switch vv := stmt.args[markerExpr.Offset].Value.(type) {
+				case nil:
+					// NULL leaves the field empty
+					continue
 				case string:
 					b = []byte(vv)
 				case []byte:
@@ -336,6 +331,9 @@ func (stmt *bitableStatement) insertStmt(r *rows, s *ast.InsertStmt) (driver.Row
 				case int, int8, int16, int32, int64, float32, float64, uint, uint8, uint16, uint32, uint64:
 					record[fieldKey] = vv
 					continue
+				case bool:
+					record[fieldKey] = vv
+					continue
 				case time.Time:
 					record[fieldKey] = vv.UnixNano() / 1e6
 					continue
@@ -346,6 +344,9 @@ func (stmt *bitableStatement) insertStmt(r *rows, s *ast.InsertStmt) (driver.Row
 				}
 			} else {
 				if tv, ok := v.(*test_driver.ValueExpr); ok {
+					if tv.Kind() == test_driver.KindNull {
+						continue
+					}
 					b = tv.Datum.GetBytes()
 					if len(b) == 0 {
 						// maybe int or decimal
@@ -413,10 +414,11 @@ func (stmt *bitableStatement) insertStmt(r *rows, s *ast.InsertStmt) (driver.Row
 	if len(data) == 0 {
 		return nil, errors.New("not found any record")
 	}
-	_, err = stmt.conn.InsertRecords(r.ctx, r.appToken, table, data)
+	records, err := stmt.conn.InsertRecords(r.ctx, r.appToken, table, data)
 	if err != nil {
 		return nil, fmt.Errorf("[bitable driver] %w", err)
 	}
+	stmt.affected = int64(len(records))
 	columns := make([]string, 0, len(s.Columns))
 	for _, column := range s.Columns {
 		columns = append(columns, column.Name.O)
@@ -433,118 +435,465 @@ func (stmt *bitableStatement) insertStmt(r *rows, s *ast.InsertStmt) (driver.Row
 	return newRowsFactory(newRows), nil
 }
 
-func (stmt *bitableStatement) createTableStmt(r *rows, s *ast.CreateTableStmt) (driver.Rows, error) {
-	tableName, _, err := stmt.getTableView(r.ctx, s.Table)
+func (stmt *bitableStatement) selectStmt(r *rows, s *ast.SelectStmt) (driver.Rows, error) {
+	return stmt.selectRows(r, s, true, true)
+}
+
+// selectRows select records of a table, record_id is the first column when withRecordID.
+// DISTINCT never return record_id, it makes every row distinct.
+// top is the select of the statement, the nested selects of subqueries and unions don't resume from the page token.
+func (stmt *bitableStatement) selectRows(r *rows, s *ast.SelectStmt, withRecordID, top bool) (driver.Rows, error) {
+	if s.From == nil {
+		return stmt.selectWithoutTable(r, s)
+	}
+
+	var appToken, table, view string
+	var fields map[string]lark.Field
+	var fieldOrder []string
+	// derived is the rows of a derived table or CTE, it has no record_id
+	derived, err := stmt.derivedRows(r, s.From)
+	if err != nil {
+		return nil, err
+	}
+	if derived != nil {
+		if top && pagedContext(r.ctx) {
+			_ = derived.Close()
+			return nil, errPageTokenResume
+		}
+		withRecordID = false
+		names := tableNames(s.From, "")
+		table = names[len(names)-1]
+		fieldOrder = derived.Columns()
+		fields = make(map[string]lark.Field, len(fieldOrder))
+		for _, name := range fieldOrder {
+			fields[name] = lark.Field{FieldName: name}
+		}
+	} else {
+		appToken, table, view, err = stmt.getAppTableView(r.ctx, s.From)
+		if err != nil {
+			return nil, fmt.Errorf("[bitable driver] %w", err)
+		}
+		if appToken != "" {
+			appRows := *r
+			appRows.appToken = appToken
+			r = &appRows
+		}
+		if fields, fieldOrder, err = stmt.loadFields(r, table); err != nil {
+			return nil, err
+		}
+		if view != "" {
+			// a view shows its fields only
+			if view, fields, fieldOrder, err = stmt.viewFields(r, table, view, fields, fieldOrder); err != nil {
+				return nil, fmt.Errorf("[bitable driver] %w", err)
+			}
+		}
+	}
+	hasColumn := func(name string) bool {
+		_, ok := fields[name]
+		return ok || (derived == nil && name == FieldKeyRecordID)
+	}
+
+	limit, err := stmt.getLimit(s.Limit)
 	if err != nil {
 		return nil, fmt.Errorf("[bitable driver] %w", err)
 	}
-	table, err := stmt.conn.CreateTable(r.ctx, r.appToken, tableName)
+	distinctLimit := int64(0)
+	if s.Distinct {
+		// the limit applies after removing duplicates
+		withRecordID = false
+		limit, distinctLimit = 0, limit
+	}
+	columns := stmt.buildSelectColumns(r.ctx, s.Fields, fieldOrder)
+	orderItems, err := stmt.buildOrderBy(r.ctx, s.OrderBy, columns)
+	if err != nil {
+		return nil, err
+	}
+	sort, pushed := stmt.buildSort(r.ctx, orderItems, fields)
+
+	// env is the current source row, bound to the outer columns of correlated subqueries
+	env := &evalEnv{}
+	subqueryNodes := []ast.Node{s.Where}
+	for _, column := range columns {
+		subqueryNodes = append(subqueryNodes, column.expr)
+	}
+	for _, item := range orderItems {
+		subqueryNodes = append(subqueryNodes, item.expr)
+	}
+	outerColumns, err := stmt.bindSubqueries(r, tableNames(s.From, table), fields, env, subqueryNodes...)
+	if err != nil {
+		return nil, err
+	}
+	where, residual, empty, err := stmt.planWhere(s.Where)
 	if err != nil {
 		return nil, fmt.Errorf("[bitable driver] %w", err)
 	}
-	// create view
-	if viewName := stmt.getComment(s.Options); viewName != "" {
-		viewNames, err := stmt.conn.ListViews(r.ctx, r.appToken, table, "", 10)
-		oldViewID := viewNames.Items[0].(*lark.View).ViewID
-		_, err = stmt.conn.CreateView(r.ctx, r.appToken, table, viewName, string(ViewTypeGrid))
-		if err != nil {
-			return nil, fmt.Errorf("create default view error: %w", err)
+	if derived != nil {
+		// a derived table is filtered in driver
+		residual = joinConjuncts(append(splitConjuncts(where), splitConjuncts(residual)...))
+		where = nil
+	}
+	if view != "" {
+		// the list api ignores the view with a filter or sort, the records of the view are filtered and sorted in driver
+		residual = joinConjuncts(append(splitConjuncts(where), splitConjuncts(residual)...))
+		where = nil
+		if sort != "" {
+			sort, pushed = "", false
 		}
-		// 删除默认 view
-		if err = stmt.conn.DropView(r.ctx, r.appToken, table, oldViewID); err != nil {
-			return nil, fmt.Errorf("delete default view error: %w", err)
+	}
+	// the records of record_id equality or IN are read by id, the other conditions are evaluated in driver
+	var recordIDs []string
+	if derived == nil && view == "" {
+		var rest ast.ExprNode
+		var byID bool
+		if recordIDs, rest, byID = stmt.recordIDsOf(where); byID {
+			residual = joinConjuncts(append(splitConjuncts(rest), splitConjuncts(residual)...))
+			where = nil
+			empty = empty || len(recordIDs) == 0
+			if sort != "" {
+				// the records read by id aren't sorted
+				sort, pushed = "", false
+			}
 		}
 	}
-	// create columns
-	for i, column := range s.Cols {
-		fieldName := column.Name.Name.O
-		fieldType := stmt.getFieldType(r.ctx, column.Tp)
-		comment := stmt.getComment(column.Options)
 
-		if i == 0 {
-			fields, err := stmt.conn.ListFields(r.ctx, r.appToken, table, "", "", 1)
-			if err != nil {
-				return nil, fmt.Errorf("list default field error: %w", err)
+	filter, rest, err := stmt.buildWhere(r.ctx, where, fields)
+	if err != nil {
+		return nil, fmt.Errorf("bitable driver filter error: %w", err)
+	}
+	if filter == filterFalse {
+		empty = true
+	}
+	if rest != nil {
+		residual = joinConjuncts(append(splitConjuncts(rest), splitConjuncts(residual)...))
+	}
+
+	// fetch the selected fields, and the fields only referenced by WHERE, ORDER BY or subqueries
+	fetchFields := make([]string, 0, len(columns))
+	fetchIndex := map[string]int{FieldKeyRecordID: 0}
+	addFetch := func(name string) {
+		if _, ok := fetchIndex[name]; !ok {
+			fetchFields = append(fetchFields, name)
+			fetchIndex[name] = len(fetchFields)
+		}
+	}
+	if derived != nil {
+		fetchIndex = make(map[string]int, len(fieldOrder))
+		for i := len(fieldOrder) - 1; i >= 0; i-- {
+			fetchIndex[fieldOrder[i]] = i
+		}
+		addFetch = func(string) {}
+	}
+	for _, column := range columns {
+		if column.expr == nil {
+			if view != "" && !hasColumn(column.field) {
+				// a field hidden by the view
+				return nil, unknownColumn(column.field, "field list")
+			}
+			addFetch(column.field)
+			continue
+		}
+		for _, name := range collectColumnNames(column.expr) {
+			if !hasColumn(name) {
+				return nil, unknownColumn(name, "field list")
 			}
-			oldFileID := fields.Items[0].(*lark.Field).FieldID
-			if _, err := stmt.conn.UpdateField(r.ctx, r.appToken, table, oldFileID, fieldName, fieldType, comment); err != nil {
-				return nil, fmt.Errorf("update field error: %w", err)
+			addFetch(name)
+		}
+	}
+	for _, item := range orderItems {
+		if item.expr == nil {
+			if !hasColumn(item.field) {
+				return nil, unknownColumn(item.field, "order clause")
 			}
+			addFetch(item.field)
 			continue
 		}
-		if _, err := stmt.conn.AddField(r.ctx, r.appToken, table, fieldName, fieldType, comment); err != nil {
-			return nil, fmt.Errorf("[bitable driver] %w", err)
+		for _, name := range collectColumnNames(item.expr) {
+			if !hasColumn(name) {
+				return nil, unknownColumn(name, "order clause")
+			}
+			addFetch(name)
 		}
 	}
-	columns := []string{"table"}
-	items := []interface{}{[]interface{}{table}}
-	newRows := r.Clone(columns, items)
-	return newRowsFactory(newRows), nil
-}
+	windowNodes := []ast.Node{s.Where}
+	for i := range s.WindowSpecs {
+		windowNodes = append(windowNodes, &s.WindowSpecs[i])
+	}
+	for _, name := range collectColumnNames(windowNodes...) {
+		if _, ok := fields[name]; ok {
+			addFetch(name)
+		}
+	}
+	for _, name := range outerColumns {
+		if !hasColumn(name) {
+			return nil, unknownColumn(name, "where clause")
+		}
+		addFetch(name)
+	}
+	sourceColumns := append([]string{FieldKeyRecordID}, fetchFields...)
+	if derived != nil {
+		sourceColumns = fieldOrder
+	}
 
-func (stmt *bitableStatement) createViewStmt(r *rows, s *ast.CreateViewStmt) (driver.Rows, error) {
-	viewType, viewName, err := stmt.getTableView(r.ctx, s.ViewName)
-	if err != nil {
-		return nil, fmt.Errorf("[bitable driver] %w", err)
+	// the columns are explained before binding the window functions
+	var columnList string
+	if stmt.explain != nil {
+		columnList = explainColumnList(columns, withRecordID)
 	}
-	table, _, err := stmt.getTableView(r.ctx, s.Select.(*ast.SelectStmt).From)
-	if err != nil {
-		return nil, fmt.Errorf("[bitable driver] %w", err)
+	// window functions are columns appended to the source rows
+	windows := &windowBinder{specs: make(map[string]ast.WindowSpec), columns: make(map[*ast.WindowFuncExpr]string)}
+	for _, spec := range s.WindowSpecs {
+		windows.specs[spec.Name.L] = spec
 	}
-	newView, err := stmt.conn.CreateView(r.ctx, r.appToken, table, viewName, strings.ToLower(viewType))
-	if err != nil {
-		return nil, fmt.Errorf("[bitable driver] %w", err)
+	for i := range columns {
+		columns[i].expr = windows.bind(columns[i].expr)
 	}
-	columns := []string{"id", "name", "type"}
-	items := []interface{}{[]interface{}{newView.ViewID, newView.ViewName, newView.ViewType}}
-	newRows := r.Clone(columns, items)
-	return newRowsFactory(newRows), nil
-}
+	for i := range orderItems {
+		orderItems[i].expr = windows.bind(orderItems[i].expr)
+	}
+	hasWindow := len(windows.funcs) > 0
+	envColumns := sourceColumns
+	for _, fn := range windows.funcs {
+		envColumns = append(envColumns[:len(envColumns):len(envColumns)], windows.columns[fn])
+	}
+	*env = *newEvalEnv(envColumns)
 
-func (stmt *bitableStatement) selectStmt(r *rows, s *ast.SelectStmt) (driver.Rows, error) {
-	queryFields := stmt.buildFieldNames(r.ctx, s.Fields)
-	if len(queryFields) == 1 && strings.ToLower(queryFields[0]) == "version()" {
-		items := []interface{}{[]interface{}{biTableVersion}}
-		newRows := r.Clone(queryFields, items)
-		return newRowsFactory(newRows), nil
+	// the limit applies to the last step before projection
+	recordLimit, filterLimit, windowLimit := limit, limit, limit
+	if residual != nil || !pushed || hasWindow {
+		recordLimit = 0
+	}
+	if !pushed || hasWindow {
+		filterLimit = 0
+	}
+	if !pushed {
+		windowLimit = 0
+	}
+	// the page token of the last page loaded skip the rows read ahead by an operator but not returned
+	paged := top && derived == nil && residual == nil && pushed && !hasWindow && !s.Distinct
+	if top && !paged && pagedContext(r.ctx) {
+		return nil, errPageTokenResume
+	}
+	var source driver.Rows
+	switch {
+	case empty:
+		if derived != nil {
+			_ = derived.Close()
+		}
+		source = newRowsFactory(r.Clone(sourceColumns, nil))
+		stmt.explainf("Empty", table, "0", "the condition is always false")
+	case derived != nil && residual == nil && pushed && !hasWindow:
+		source = newLimitRows(r, derived, limit)
+		if limit > 0 {
+			stmt.explainf("Limit", table, "0", fmt.Sprintf("limit: %d", limit))
+		}
+	case derived != nil:
+		source = derived
+	default:
+		base := r
+		if !paged {
+			base = unpaged(r)
+		}
+		source = newRecordRows(base, table, view, sort, fetchFields, fields, filter, recordIDs, recordLimit)
+		rr, _ := source.(*rowsFactory).rows.(*recordRows)
+		stmt.explainRecords(table, view, filter, sort, rr.fieldNames, recordIDs, recordLimit)
+	}
+	if residual != nil {
+		source = newFilterRows(r, source, stmt.exprProjection(residual, env), filterLimit)
+		stmt.explainf("Filter", table, "0", "residual: "+restore(residual))
+	}
+	if hasWindow {
+		funcs, err := stmt.windowFuncs(windows, env)
+		if err != nil {
+			_ = source.Close()
+			return nil, fmt.Errorf("[bitable driver] %w", err)
+		}
+		source = newWindowRows(r, source, envColumns, funcs, windowLimit)
+		if stmt.explain != nil {
+			exprs := make([]string, 0, len(windows.funcs))
+			for _, fn := range windows.funcs {
+				exprs = append(exprs, restore(fn))
+			}
+			stmt.explainf("Window", table, "0", "functions: "+strings.Join(exprs, ", "))
+		}
+	}
+	if !pushed {
+		// the limit applies after sorting all the records in driver
+		keys := make([]sortKey, 0, len(orderItems))
+		for _, item := range orderItems {
+			key := sortKey{desc: item.desc}
+			if item.expr == nil {
+				key.value = columnProjection(fetchIndex[item.field])
+			} else {
+				key.value = stmt.exprProjection(item.expr, env)
+			}
+			keys = append(keys, key)
+		}
+		source = newSortRows(r, source, keys, limit)
+		if stmt.explain != nil {
+			details := []string{"order by: " + explainOrderBy(orderItems)}
+			if limit > 0 {
+				details = append(details, fmt.Sprintf("limit: %d", limit))
+			}
+			stmt.explainf("Sort", table, "0", details...)
+		}
+	}
+	if withRecordID && derived == nil && len(fetchFields) == len(columns) && isPlainColumns(columns, fetchFields) {
+		return source, nil
+	}
+
+	names := make([]string, 0, len(columns)+1)
+	projections := make([]projection, 0, len(columns)+1)
+	if withRecordID {
+		names = append(names, FieldKeyRecordID)
+		projections = append(projections, columnProjection(0))
+	}
+	for _, column := range columns {
+		names = append(names, column.name)
+		if column.expr == nil {
+			projections = append(projections, columnProjection(fetchIndex[column.field]))
+			continue
+		}
+		projections = append(projections, stmt.exprProjection(column.expr, env))
+	}
+	source = newProjectRows(r, source, names, projections)
+	stmt.explainf("Project", table, "0", "columns: "+columnList)
+	if s.Distinct {
+		if distinctLimit > 0 {
+			stmt.explainf("Distinct", table, "0", fmt.Sprintf("limit: %d", distinctLimit))
+		} else {
+			stmt.explainf("Distinct", table, "0")
+		}
+		return newDistinctRows(r, source, distinctLimit), nil
 	}
+	return source, nil
+}
 
-	table, view, err := stmt.getTableView(r.ctx, s.From)
+// unionStmt concatenate the selects, like MySQL a UNION DISTINCT removes duplicates of all the selects on its left.
+// top is the union of the statement, which can't resume from the page token.
+func (stmt *bitableStatement) unionStmt(r *rows, s *ast.UnionStmt, top bool) (driver.Rows, error) {
+	if s.SelectList == nil || len(s.SelectList.Selects) == 0 {
+		return nil, errors.New("[bitable driver] union without select")
+	}
+	if top && pagedContext(r.ctx) {
+		return nil, errPageTokenResume
+	}
+	sources := make([]driver.Rows, 0, len(s.SelectList.Selects))
+	closeSources := func() {
+		for _, source := range sources {
+			_ = source.Close()
+		}
+	}
+	distinct := 0
+	for i, sel := range s.SelectList.Selects {
+		source, err := stmt.selectRows(r, sel, false, false)
+		if err != nil {
+			closeSources()
+			return nil, err
+		}
+		sources = append(sources, source)
+		if len(source.Columns()) != len(sources[0].Columns()) {
+			closeSources()
+			return nil, errors.New("[bitable driver] the used SELECT statements have a different number of columns")
+		}
+		if sel.IsAfterUnionDistinct {
+			distinct = i + 1
+		}
+	}
+	columns := sources[0].Columns()
+	limit, err := stmt.getLimit(s.Limit)
 	if err != nil {
 		return nil, fmt.Errorf("[bitable driver] %w", err)
 	}
+	if stmt.explain != nil {
+		details := []string{fmt.Sprintf("selects: %d", len(sources)), fmt.Sprintf("distinct selects: %d", distinct)}
+		if limit > 0 && s.OrderBy == nil {
+			details = append(details, fmt.Sprintf("limit: %d", limit))
+		}
+		stmt.explainf("Union", "", "0", details...)
+	}
+	if s.OrderBy == nil {
+		return newUnionRows(r, columns, sources, distinct, limit), nil
+	}
 
-	var limit int64
-	if s.Limit != nil {
-		if v, ok := s.Limit.Count.(*test_driver.ValueExpr); ok {
-			limit = v.GetInt64()
+	// the order by of union refer to the columns of the first select
+	keys := make([]sortKey, 0, len(s.OrderBy.Items))
+	for _, by := range s.OrderBy.Items {
+		key := sortKey{desc: by.Desc}
+		switch n := by.Expr.(type) {
+		case *ast.PositionExpr:
+			if n.N < 1 || n.N > len(columns) {
+				closeSources()
+				return nil, unknownColumn(n.N, "order clause")
+			}
+			key.value = columnProjection(n.N - 1)
+		default:
+			key.value = stmt.exprProjection(by.Expr, newEvalEnv(columns))
+		}
+		keys = append(keys, key)
+	}
+	if stmt.explain != nil {
+		details := []string{"order by: " + strings.TrimPrefix(restore(s.OrderBy), "ORDER BY ")}
+		if limit > 0 {
+			details = append(details, fmt.Sprintf("limit: %d", limit))
 		}
+		stmt.explainf("Sort", "", "0", details...)
 	}
+	return newSortRows(r, newUnionRows(r, columns, sources, distinct, 0), keys, limit), nil
+}
 
-	filter, err := stmt.buildFilter(r.ctx, s.Where)
+// getLimit the row count of LIMIT, 0 means no limit, the count may be a placeholder.
+func (stmt *bitableStatement) getLimit(limit *ast.Limit) (int64, error) {
+	if limit == nil || limit.Count == nil {
+		return 0, nil
+	}
+	v, err := stmt.eval(limit.Count, nil)
 	if err != nil {
-		return nil, fmt.Errorf("bitable driver filter error: %w", err)
+		return 0, err
 	}
-	// hack recordID
-	var recordID string
-	onlyRecordIDFilter := "CurrentValue.[record_id] = "
-	if strings.HasPrefix(filter, onlyRecordIDFilter) {
-		recordID = filter[len(onlyRecordIDFilter)+1 : len(filter)-1]
-		filter = ""
+	switch n := v.(type) {
+	case int64:
+		if n >= 0 {
+			return n, nil
+		}
+	case uint64:
+		return int64(n), nil
 	}
-	sort := stmt.buildSort(r.ctx, s)
+	return 0, fmt.Errorf("invalid LIMIT %v", v)
+}
 
-	fields, err := stmt.loadFields(r, table)
-	if err != nil {
+// selectWithoutTable evaluate the field list once, like `SELECT version()` or `SELECT 1 + 1`.
+func (stmt *bitableStatement) selectWithoutTable(r *rows, s *ast.SelectStmt) (driver.Rows, error) {
+	columns := stmt.buildSelectColumns(r.ctx, s.Fields, nil)
+	nodes := make([]ast.Node, 0, len(columns))
+	for _, column := range columns {
+		nodes = append(nodes, column.expr)
+	}
+	if _, err := stmt.bindSubqueries(r, nil, nil, nil, nodes...); err != nil {
 		return nil, err
 	}
-	if len(queryFields) == 0 {
-		for k := range fields {
-			queryFields = append(queryFields, k)
+	names := make([]string, 0, len(columns))
+	item := make([]interface{}, 0, len(columns))
+	for _, column := range columns {
+		if column.expr == nil {
+			return nil, unknownColumn(column.field, "field list")
 		}
+		v, err := stmt.eval(column.expr, nil)
+		if err != nil {
+			return nil, fmt.Errorf("[bitable driver] %w", err)
+		}
+		names = append(names, column.name)
+		item = append(item, v)
 	}
+	newRows := r.Clone(names, []interface{}{item})
+	return newRowsFactory(newRows), nil
+}
 
-	return newRecordRows(r, table, view, sort, queryFields, fields, filter, recordID, limit), nil
+// exprProjection evaluate expr against the source row, env is shared by correlated subqueries.
+func (stmt *bitableStatement) exprProjection(expr ast.ExprNode, env *evalEnv) projection {
+	return func(src []driver.Value) (driver.Value, error) {
+		env.values = src
+		return stmt.eval(expr, env)
+	}
 }
 
 func (stmt *bitableStatement) showStmt(r *rows, s *ast.ShowStmt) (driver.Rows, error) {
@@ -558,7 +907,7 @@ func (stmt *bitableStatement) showStmt(r *rows, s *ast.ShowStmt) (driver.Rows, e
 		if err != nil {
 			return nil, fmt.Errorf("[bitable driver] %w", err)
 		}
-		return newViewRows(r, table), nil
+		return newViewRows(r, table, nil), nil
 	case ast.ShowColumns:
 		table, _, err := stmt.getTableView(r.ctx, s.Table)
 		if err != nil {
@@ -610,7 +959,7 @@ func (stmt *bitableStatement) getFieldID(ctx context.Context, appToken, table, f
 		}
 	}
 	if len(fieldId) == 0 {
-		return "", 0, "", fmt.Errorf("not found fieldName: %s", fieldName)
+		return "", 0, "", unknownColumn(fieldName, "field list")
 	}
 	return fieldId, fieldType, property, nil
 }
@@ -638,10 +987,61 @@ func (stmt *bitableStatement) getTableView(ctx context.Context, node interface{}
 	return "", "", errors.New("select not found table")
 }
 
+// derivedRows the rows of a derived table `FROM (SELECT ...) AS t` or a CTE, nil for a table.
+func (stmt *bitableStatement) derivedRows(r *rows, from *ast.TableRefsClause) (driver.Rows, error) {
+	if from == nil || from.TableRefs == nil {
+		return nil, nil
+	}
+	ts, ok := from.TableRefs.Left.(*ast.TableSource)
+	if !ok {
+		return nil, nil
+	}
+	switch ts.Source.(type) {
+	case *ast.SelectStmt, *ast.UnionStmt:
+	default:
+		return nil, nil
+	}
+	query := ts.Source.(ast.ResultSetNode)
+	source, err := stmt.resultSetRows(r, query)
+	if err != nil {
+		return nil, err
+	}
+	names, ok := stmt.derivedColumns[query]
+	if !ok {
+		return source, nil
+	}
+	if len(names) != len(source.Columns()) {
+		_ = source.Close()
+		return nil, fmt.Errorf("[bitable driver] in definition of '%s', the column list has %d columns, the query has %d",
+			ts.AsName.O, len(names), len(source.Columns()))
+	}
+	projections := make([]projection, 0, len(names))
+	for i := range names {
+		projections = append(projections, columnProjection(i))
+	}
+	return newProjectRows(r, source, names, projections), nil
+}
+
+// getAppTableView like getTableView, `app_token.table_id` select a table of another app.
+func (stmt *bitableStatement) getAppTableView(ctx context.Context, node interface{}) (
+	appToken string, table string, view string, err error) {
+	table, view, err = stmt.getTableView(ctx, node)
+	if err != nil {
+		return "", "", "", err
+	}
+	if strings.HasPrefix(view, tableIDPrefix) && !strings.HasPrefix(table, tableIDPrefix) {
+		return table, view, "", nil
+	}
+	return "", table, view, nil
+}
+
 // NumInput row numbers
 func (stmt *bitableStatement) NumInput() int {
-	// don't know how many row numbers
-	return -1
+	if stmt.plan == nil || len(stmt.plan.names) > 0 {
+		// a named arg may bind several params, let database/sql pass all args
+		return -1
+	}
+	return len(stmt.plan.offsets)
 }
 
 // Exec executes a query that doesn't return rows, such as an INSERT or UPDATE.
@@ -655,7 +1055,7 @@ func (stmt *bitableStatement) ExecContext(ctx context.Context, args []driver.Nam
 	if err != nil {
 		return nil, err
 	}
-	res := bitableResult{}
+	res := bitableResult{rowsAffected: stmt.affected}
 	if rows == nil {
 		return res, nil
 	}
@@ -665,61 +1065,95 @@ func (stmt *bitableStatement) ExecContext(ctx context.Context, args []driver.Nam
 			if io.EOF == err {
 				break
 			}
-			res.rowsAffected++
 			return nil, err
 		}
 	}
 	return res, nil
-	return stmt.Exec(convertNamedValue(args))
-}
-
-func (stmt *bitableStatement) searchRecords(ctx context.Context, appToken, table, view, filter string, fields map[string]interface{}, limit int64, callback func(context.Context, map[string]map[string]interface{}) (int, error)) (driver.Rows, error) {
-	log := logrus.WithFields(logrus.Fields{
-		"appToken":   appToken,
-		"testTable2": table,
-		"view":       view,
-		"filter":     filter,
-	})
-	pageToken := ""
-	count := int64(0)
-	for i := 0; i < maxLoopTimes; i++ {
-		pageSize := DefaultPageSize
-		if limit > 0 && pageSize > limit {
-			pageSize = limit
-		}
-		res, err := stmt.conn.ListRecords(ctx, appToken, table, view, "", filter, "", pageToken, pageSize)
+}
+
+// changePlan is the records changed by an UPDATE or DELETE, the records of recordIDs when byID,
+// or the records of view listed by filter, both matching rest in driver.
+type changePlan struct {
+	view      string
+	recordIDs []string
+	byID      bool
+	filter    string
+	rest      ast.ExprNode
+	fields    map[string]lark.Field
+	empty     bool
+}
+
+// planChanges plan the records of an UPDATE or DELETE matching where.
+func (stmt *bitableStatement) planChanges(r *rows, table, view string, where ast.ExprNode) (*changePlan, error) {
+	plan := &changePlan{}
+	if view != "" {
+		// the list api ignores the view with a filter, the records of the view are matched in driver like SELECT
+		fields, order, err := stmt.loadFields(r, table)
 		if err != nil {
+			return nil, err
+		}
+		if plan.view, plan.fields, _, err = stmt.viewFields(r, table, view, fields, order); err != nil {
 			return nil, fmt.Errorf("[bitable driver] %w", err)
 		}
-		if res.Total == 0 {
-			return nil, nil
+		plan.rest = where
+		return plan, nil
+	}
+	// the records of record_id equality or IN are read by id, the other conditions are evaluated in driver
+	plan.recordIDs, plan.rest, plan.byID = stmt.recordIDsOf(where)
+	if plan.byID {
+		where = nil
+	} else {
+		plan.recordIDs, plan.rest = nil, nil
+	}
+	var err error
+	if hasNullTest(where) || plan.rest != nil {
+		if plan.fields, _, err = stmt.loadFields(r, table); err != nil {
+			return nil, err
 		}
-		pageToken = res.PageToken
-		data := make(map[string]map[string]interface{}, len(res.Items))
-		for _, item := range res.Items {
-			record := item.(*lark.Record)
-			count++
-			if limit > 0 && limit <= count {
-				continue
-			}
-			data[record.RecordID] = fields
+	}
+	filter, residual, err := stmt.buildWhere(r.ctx, where, plan.fields)
+	if err != nil {
+		return nil, fmt.Errorf("[bitable driver] %w", err)
+	}
+	if residual != nil && plan.fields == nil {
+		if plan.fields, _, err = stmt.loadFields(r, table); err != nil {
+			return nil, err
 		}
-		if len(data) == 0 {
-			break
+	}
+	plan.filter = filter
+	plan.rest = joinConjuncts(append(splitConjuncts(plan.rest), splitConjuncts(residual)...))
+	plan.empty = filter == filterFalse || (plan.byID && len(plan.recordIDs) == 0)
+	return plan, nil
+}
+
+// changeRecords call callback with the records of plan, a page a call.
+// The records are matched before the first change, the pages of the list api don't shift under the changes.
+func (stmt *bitableStatement) changeRecords(r *rows, table string, plan *changePlan,
+	data map[string]interface{}, limit int64,
+	callback func(context.Context, map[string]map[string]interface{}) (int, error)) (driver.Rows, error) {
+	matched, err := stmt.matchRecords(r, table, plan, limit)
+	if err != nil {
+		return nil, err
+	}
+	pageSize := int(stmt.conn.config().PageSize)
+	for start := 0; start < len(matched); start += pageSize {
+		end := start + pageSize
+		if end > len(matched) {
+			end = len(matched)
 		}
-		n, err := callback(ctx, data)
+		page := make(map[string]map[string]interface{}, end-start)
+		for _, recordID := range matched[start:end] {
+			page[recordID] = data
+		}
+		n, err := callback(r.ctx, page)
 		if err != nil {
 			return nil, fmt.Errorf("[bitable driver] %w", err)
 		}
-		count += int64(n)
-		if !res.HasMore {
-			return nil, nil
-		}
-	}
-	log.Debugf("update %d records", count)
-	if count == 0 {
-		return nil, errors.New("not record changed")
+		stmt.affected += int64(n)
 	}
+	// the filter has the values of the statement, it isn't logged
+	stmt.conn.log(r.ctx, LevelDebug, "[bitable driver] change records", "app_token", r.appToken,
+		"table", table, "view", plan.view, "count", stmt.affected)
 	return nil, nil
 }
 
@@ -743,71 +1177,239 @@ func (stmt *bitableStatement) getFieldType(_ context.Context, tp *types.FieldTyp
 	return int64(fieldType)
 }
 
-func (stmt *bitableStatement) buildFieldNames(_ context.Context, node *ast.FieldList) []string {
+// selectColumn a column of the select field list.
+type selectColumn struct {
+	name  string       // output column name, the alias if present
+	field string       // field name of the table, empty for an expression
+	expr  ast.ExprNode // expression evaluated in driver, nil for a plain field
+}
+
+// buildSelectColumns expand the select field list, `*` follow the field order of the table.
+func (stmt *bitableStatement) buildSelectColumns(_ context.Context, node *ast.FieldList, fieldOrder []string) []selectColumn {
 	if node == nil {
 		return nil
 	}
-	tmp := make([]string, 0, len(node.Fields))
+	columns := make([]selectColumn, 0, len(node.Fields))
 	for _, f := range node.Fields {
-		switch v := f.Expr.(type) {
-		case *test_driver.ValueExpr:
-			tmp = append(tmp, v.GetString())
-		case *ast.ColumnNameExpr:
-			tmp = append(tmp, v.Name.Name.O)
-		default:
-			tmp = append(tmp, f.Text())
-		}
 		if f.WildCard != nil {
-			return nil
+			for _, name := range fieldOrder {
+				columns = append(columns, selectColumn{name: name, field: name})
+			}
+			continue
+		}
+		column := selectColumn{name: f.Text(), expr: f.Expr}
+		if v, ok := f.Expr.(*ast.ColumnNameExpr); ok {
+			column = selectColumn{name: v.Name.Name.O, field: v.Name.Name.O}
 		}
+		if f.AsName.O != "" {
+			column.name = f.AsName.O
+		}
+		columns = append(columns, column)
 	}
-	return tmp
+	return columns
 }
 
-func (stmt *bitableStatement) buildSort(_ context.Context, s *ast.SelectStmt) string {
-	if s.OrderBy == nil || len(s.OrderBy.Items) == 0 {
-		return ""
+// isPlainColumns report whether columns are the fields in the same order without alias.
+func isPlainColumns(columns []selectColumn, fields []string) bool {
+	for i, column := range columns {
+		if column.expr != nil || column.name != fields[i] || column.field != fields[i] {
+			return false
+		}
 	}
-	tmp := make([]string, 0, len(s.OrderBy.Items))
-	for _, i := range s.OrderBy.Items {
-		if n, ok := i.Expr.(*ast.ColumnNameExpr); ok {
-			name := n.Name.Name.O
-			if name == `""` || len(name) == 0 {
-				continue
+	return true
+}
+
+// columnCollector collect the names of ColumnNameExpr in order of appearance.
+type columnCollector struct {
+	names []string
+	seen  map[string]bool
+}
+
+func (c *columnCollector) Enter(n ast.Node) (ast.Node, bool) {
+	// columns of a subquery belong to its own table
+	if _, ok := n.(*ast.SubqueryExpr); ok {
+		return n, true
+	}
+	if v, ok := n.(*ast.ColumnNameExpr); ok {
+		name := v.Name.Name.O
+		if !c.seen[name] {
+			c.seen[name] = true
+			c.names = append(c.names, name)
+		}
+	}
+	return n, false
+}
+
+func (c *columnCollector) Leave(n ast.Node) (ast.Node, bool) {
+	return n, true
+}
+
+func collectColumnNames(nodes ...ast.Node) []string {
+	c := &columnCollector{seen: make(map[string]bool)}
+	for _, node := range nodes {
+		if node != nil {
+			node.Accept(c)
+		}
+	}
+	return c.names
+}
+
+// orderItem an ORDER BY item, aliases and ordinals are resolved to the select column.
+type orderItem struct {
+	field string       // field name of the table, empty for an expression
+	expr  ast.ExprNode // expression sorted in driver, nil for a plain field
+	desc  bool
+}
+
+// buildOrderBy resolve ORDER BY items, an alias or ordinal (`ORDER BY 2`) refer to the select column.
+func (stmt *bitableStatement) buildOrderBy(_ context.Context, node *ast.OrderByClause, columns []selectColumn) ([]orderItem, error) {
+	if node == nil {
+		return nil, nil
+	}
+	items := make([]orderItem, 0, len(node.Items))
+	fromColumn := func(column selectColumn, desc bool) orderItem {
+		return orderItem{field: column.field, expr: column.expr, desc: desc}
+	}
+	for _, by := range node.Items {
+		switch n := by.Expr.(type) {
+		case *ast.PositionExpr:
+			ordinal := int64(n.N)
+			if n.P != nil {
+				v, err := stmt.eval(n.P, nil)
+				if err != nil {
+					return nil, fmt.Errorf("[bitable driver] %w", err)
+				}
+				ordinal = toInt(v)
 			}
-			if i.Desc {
-				tmp = append(tmp, fmt.Sprintf("%s DESC", name))
-			} else {
-				tmp = append(tmp, fmt.Sprintf("%s ASC", name))
+			if ordinal < 1 || ordinal > int64(len(columns)) {
+				return nil, unknownColumn(ordinal, "order clause")
 			}
+			items = append(items, fromColumn(columns[ordinal-1], by.Desc))
+			continue
+		case *test_driver.ValueExpr:
+			// sort by a constant keep the order
+			continue
+		case *ast.ColumnNameExpr:
+			name := n.Name.Name.O
+			if n.Name.Table.O == "" {
+				if column, ok := findColumn(columns, name); ok {
+					items = append(items, fromColumn(column, by.Desc))
+					continue
+				}
+			}
+			items = append(items, orderItem{field: name, desc: by.Desc})
+			continue
 		}
+		items = append(items, orderItem{expr: by.Expr, desc: by.Desc})
 	}
-	if len(tmp) == 0 {
-		return ""
+	return items, nil
+}
+
+// findColumn find the select column by output name, like MySQL an alias is preferred to a field.
+func findColumn(columns []selectColumn, name string) (selectColumn, bool) {
+	for _, column := range columns {
+		if strings.EqualFold(column.name, name) {
+			return column, true
+		}
+	}
+	return selectColumn{}, false
+}
+
+// buildSort build the sort param of list records, pushed is false when the API can't sort the items.
+func (stmt *bitableStatement) buildSort(_ context.Context, items []orderItem, fields map[string]lark.Field) (sort string, pushed bool) {
+	if len(items) == 0 {
+		return "", true
+	}
+	// list records don't support sort on a table with formula or association fields
+	for _, field := range fields {
+		switch FieldType(field.Type) {
+		case FieldTypeFormula, FieldTypeReferenceLookup, FieldTypeOneWayAssociation, FieldTypeTwoWayAssociation:
+			return "", false
+		}
 	}
-	return oneLine(tmp)
+	tmp := make([]string, 0, len(items))
+	for _, item := range items {
+		if item.expr != nil {
+			return "", false
+		}
+		field, ok := fields[item.field]
+		if !ok || !isSortableField(field) {
+			return "", false
+		}
+		if item.desc {
+			tmp = append(tmp, fmt.Sprintf("%s DESC", item.field))
+		} else {
+			tmp = append(tmp, fmt.Sprintf("%s ASC", item.field))
+		}
+	}
+	return oneLine(tmp), true
+}
+
+// isSortableField report whether the API can sort by the field.
+func isSortableField(field lark.Field) bool {
+	switch FieldType(field.Type) {
+	case FieldTypeText, FieldTypeNumber, FieldTypeSelect, FieldTypeDate, FieldTypeCheckbox,
+		FieldTypeCreateTime, FieldTypeUpdateTime:
+		return true
+	}
+	return false
 }
 
-func (stmt *bitableStatement) buildFilter(ctx context.Context, node interface{}) (string, error) {
+// buildFilter build the filter formula of node, like MySQL a condition which is NULL matches nothing.
+// fields are used by the NULL tests of the columns, which depend on the field type.
+func (stmt *bitableStatement) buildFilter(ctx context.Context, node interface{}, fields map[string]lark.Field) (string, error) {
+	filter, err := stmt.buildFormula(ctx, node, fields)
+	if err == ErrNullValue {
+		return filterFalse, nil
+	}
+	return filter, err
+}
+
+// buildWhere split where into the filter formula and the residual conditions evaluated in driver,
+// the conjuncts the formula can't express are residual.
+func (stmt *bitableStatement) buildWhere(ctx context.Context, where ast.ExprNode, fields map[string]lark.Field) (
+	filter string, residual ast.ExprNode, err error) {
+	filter, err = stmt.buildFilter(ctx, where, fields)
+	if !errors.Is(err, errNotPushable) {
+		return filter, nil, err
+	}
+	var pushed, rest []ast.ExprNode
+	for _, cond := range splitConjuncts(where) {
+		if _, err := stmt.buildFormula(ctx, cond, fields); errors.Is(err, errNotPushable) {
+			rest = append(rest, cond)
+		} else {
+			pushed = append(pushed, cond)
+		}
+	}
+	filter, err = stmt.buildFilter(ctx, joinConjuncts(pushed), fields)
+	return filter, joinConjuncts(rest), err
+}
+
+// buildFormula build the formula of node, ErrNullValue means the value of node is NULL.
+func (stmt *bitableStatement) buildFormula(ctx context.Context, node interface{}, fields map[string]lark.Field) (string, error) {
 	if node == nil {
 		return "", nil
 	}
 	switch root := node.(type) {
 	case *ast.BinaryOperationExpr:
-		l, err := stmt.buildFilter(ctx, root.L)
-		if err != nil {
-			return "", err
+		l, lErr := stmt.buildFormula(ctx, root.L, fields)
+		if lErr != nil && lErr != ErrNullValue {
+			return "", lErr
 		}
-		r, err := stmt.buildFilter(ctx, root.R)
-		if err != nil {
-			// ignore null value
-			if err != ErrNullValue {
-				return "", err
-			}
+		r, rErr := stmt.buildFormula(ctx, root.R, fields)
+		if rErr != nil && rErr != ErrNullValue {
+			return "", rErr
 		}
 		buff := bytes.NewBuffer(nil)
 		switch root.Op {
 		case opcode.LogicAnd, opcode.LogicOr:
+			// a NULL condition never matches
+			if lErr != nil {
+				l = filterFalse
+			}
+			if rErr != nil {
+				r = filterFalse
+			}
 			root.Op.Format(buff)
 			buff.WriteByte('(')
 			buff.WriteString(l)
@@ -815,6 +1417,22 @@ func (stmt *bitableStatement) buildFilter(ctx context.Context, node interface{})
 			buff.WriteString(r)
 			buff.WriteByte(')')
 			return buff.String(), nil
+		case opcode.NullEQ:
+			switch {
+			case lErr != nil && rErr != nil:
+				return filterTrue, nil
+			case lErr != nil:
+				return isNullFormula(root.R, r, fields)
+			case rErr != nil:
+				return isNullFormula(root.L, l, fields)
+			}
+			return "", errNotPushable
+		}
+		// any other operation on NULL is NULL
+		if lErr != nil || rErr != nil {
+			return "", ErrNullValue
+		}
+		switch root.Op {
 		case opcode.Not:
 			return fmt.Sprintf("NOT(%s)", l), nil
 		case opcode.GE, opcode.LE, opcode.EQ, opcode.NE, opcode.LT, opcode.GT,
@@ -826,102 +1444,190 @@ func (stmt *bitableStatement) buildFilter(ctx context.Context, node interface{})
 		case opcode.Plus:
 			return fmt.Sprintf("%s%%2B%s", l, r), nil
 		case opcode.IsNull:
-			return fmt.Sprintf(`%s=""`, l), nil
-		case opcode.In:
-			return fmt.Sprintf(`%s.contains("%s")`, l, r), nil
-		case opcode.BitNeg, opcode.IntDiv, opcode.LogicXor, opcode.NullEQ,
-			opcode.Like, opcode.Case, opcode.Regexp, opcode.IsFalsity, opcode.IsTruth:
-			return "", fmt.Errorf("not supported op %s", root.Op)
+			return isNullFormula(root.L, l, fields)
 		}
+		return "", errNotPushable
+	case *ast.ParenthesesExpr:
+		v, err := stmt.buildFormula(ctx, root.Expr, fields)
+		if err != nil {
+			return "", err
+		}
+		if b, ok := root.Expr.(*ast.BinaryOperationExpr); ok && !isPredicateOp(b.Op) {
+			// the parentheses of arithmetic change the precedence
+			return "(" + v + ")", nil
+		}
+		return v, nil
 	case *ast.PatternInExpr:
 		in := make([]string, 0, len(root.List))
+		hasNull := false
 		for _, l := range root.List {
-			v, err := stmt.buildFilter(ctx, l)
+			v, err := stmt.buildFormula(ctx, l, fields)
+			if err == ErrNullValue {
+				// x IN (1, NULL) is true or NULL, x NOT IN (1, NULL) is false or NULL
+				hasNull = true
+				continue
+			}
 			if err != nil {
 				return "", err
 			}
 			in = append(in, v)
 		}
-		v, err := stmt.buildFilter(ctx, root.Expr)
+		if len(in) == 0 || (root.Not && hasNull) {
+			return "", ErrNullValue
+		}
+		v, err := stmt.buildFormula(ctx, root.Expr, fields)
 		if err != nil {
 			return "", err
 		}
-		return fmt.Sprintf(`%s.contains(%s)`, v, strings.Join(in, ",")), nil
+		// contains is a substring match of a text, the values are compared one by one
+		op, join := " = ", "OR"
+		if root.Not {
+			op, join = " != ", "AND"
+		}
+		for i := range in {
+			in[i] = v + op + in[i]
+		}
+		if len(in) == 1 {
+			return in[0], nil
+		}
+		return fmt.Sprintf("%s(%s)", join, strings.Join(in, ",")), nil
 	case *ast.ColumnNameExpr:
-		return fmt.Sprintf(`CurrentValue.[%s]`, root.Name.Name.O), nil
+		return formulaField(root.Name.Name.O)
+	case *outerColumnExpr:
+		// the value of the outer row in a correlated subquery
+		v, err := root.env.lookup(root.Name)
+		if err != nil {
+			return "", err
+		}
+		return stmt.buildFormula(ctx, ast.NewValueExpr(v, "", ""), fields)
 	case *test_driver.ValueExpr:
 		// 数字和字符串处理方式不相同
-		switch root.Kind() {
-		case test_driver.KindString:
-			return fmt.Sprintf(`"%s"`, root.GetString()), nil
-		case test_driver.KindBytes:
-			return fmt.Sprintf(`"%s"`, string(root.GetBytes())), nil
-		case test_driver.KindNull:
-			return "", ErrNullValue
-		default:
-			return fmt.Sprintf(`%v`, root.GetValue()), nil
-		}
+		return formulaValue(root.GetValue())
 	case *ast.IsNullExpr:
-		v, err := stmt.buildFilter(ctx, root.Expr)
+		v, err := stmt.buildFormula(ctx, root.Expr, fields)
+		if err == ErrNullValue {
+			if root.Not {
+				return filterFalse, nil
+			}
+			return filterTrue, nil
+		}
+		if err != nil {
+			return "", err
+		}
+		isNull, err := isNullFormula(root.Expr, v, fields)
 		if err != nil {
 			return "", err
 		}
-		isNull := fmt.Sprintf(`%s=""`, v)
 		if root.Not {
-			isNull = fmt.Sprintf(`Not(%s)`, isNull)
+			isNull = fmt.Sprintf(`NOT(%s)`, isNull)
 		}
 		return isNull, nil
 	case *ast.FuncCallExpr:
 		args := make([]string, 0, len(root.Args))
 		for _, a := range root.Args {
-			v, err := stmt.buildFilter(ctx, a)
+			v, err := stmt.buildFormula(ctx, a, fields)
 			if err != nil {
 				return "", err
 			}
 			args = append(args, v)
 		}
-		switch root.FnName.L {
-		case "date":
+		// the other arities, like DATE(x) and WEEKDAY(x) of MySQL, aren't the formula functions and are evaluated in driver
+		switch {
+		case root.FnName.L == "date" && len(args) == 3:
 			return fmt.Sprintf("DATE(%s, %s, %s)", args[0], args[1], args[2]), nil
-		case "day", "month", "year", "todate":
-			if len(args) != 1 {
-				return "", errors.New("TODATE only accepts one argument")
-			}
+		case isFormulaDatePart(root.FnName.L) && len(args) == 1:
 			return fmt.Sprintf("%s(%s)", root.FnName.O, args[0]), nil
-		case "today":
-			return fmt.Sprintf("TODAY()"), nil
-		case "weekday":
-			if len(root.Args) != 2 {
-				return "", errors.New("TODATE only accepts two argument")
-			}
+		case root.FnName.L == "today" && len(args) == 0:
+			return "TODAY()", nil
+		case root.FnName.L == "weekday" && len(args) == 2:
 			return fmt.Sprintf("WEEKDAY(%s, %s)", args[0], args[1]), nil
 		}
+		return "", errNotPushable
 	case *test_driver.ParamMarkerExpr:
-		v, err := stmt.buildFilter(ctx, &root.ValueExpr)
-		if err != nil {
-			if err == ErrNullValue {
-				v, ok := stmt.args[root.Offset]
-				if ok {
-					switch s := v.Value.(type) {
-					case string:
-						return fmt.Sprintf(`"%s"`, s), nil
-					default:
-						return fmt.Sprint(s), nil
-					}
-				}
-			}
-			return "", err
+		// the argument is always a literal, it can't change the formula
+		if v, ok := stmt.args[root.Offset]; ok {
+			return formulaValue(v.Value)
 		}
-		return v, nil
-	default:
-		logrus.Errorf("filter not supported %+v", node)
+		return stmt.buildFormula(ctx, &root.ValueExpr, fields)
+	}
+	// NOT, LIKE, BETWEEN and the others are filtered in driver
+	return "", errNotPushable
+}
+
+// isFormulaDatePart report whether name is a formula function of one date argument.
+func isFormulaDatePart(name string) bool {
+	switch name {
+	case "day", "month", "year", "todate":
+		return true
+	}
+	return false
+}
+
+// isPredicateOp report whether op is a comparison or a logic operator, whose formula is a function or a condition.
+func isPredicateOp(op opcode.Op) bool {
+	switch op {
+	case opcode.LogicAnd, opcode.LogicOr, opcode.GE, opcode.LE, opcode.EQ, opcode.NE, opcode.LT, opcode.GT,
+		opcode.NullEQ, opcode.IsNull, opcode.In, opcode.Not:
+		return true
+	}
+	return false
+}
+
+// isNullFormula return the formula testing whether expr is empty, v is the formula of expr.
+// The empty arrays and objects of the other field types have no formula, they are tested in driver.
+func isNullFormula(expr ast.ExprNode, v string, fields map[string]lark.Field) (string, error) {
+	if column, ok := expr.(*ast.ColumnNameExpr); ok {
+		switch FieldType(fields[column.Name.Name.O].Type) {
+		case FieldTypeCheckbox:
+			// an unchecked checkbox is absent from the record
+			return fmt.Sprintf("NOT(%s)", v), nil
+		case FieldTypeNumber, FieldTypeDate, FieldTypeCreateTime, FieldTypeUpdateTime:
+			return fmt.Sprintf("ISBLANK(%s)", v), nil
+		case 0, FieldTypeText, FieldTypeSelect:
+		default:
+			return "", errNotPushable
+		}
+	}
+	return fmt.Sprintf(`%s=""`, v), nil
+}
+
+// hasNullTest report whether node tests NULL, whose formula depends on the field types.
+func hasNullTest(node ast.Node) bool {
+	c := &nullTestCollector{}
+	if node != nil {
+		node.Accept(c)
 	}
+	return c.found
+}
 
-	return "", nil
+type nullTestCollector struct {
+	found bool
 }
 
-func (stmt *bitableStatement) loadFields(r *rows, table string) (map[string]lark.Field, error) {
+func (c *nullTestCollector) Enter(n ast.Node) (ast.Node, bool) {
+	switch v := n.(type) {
+	case *ast.IsNullExpr:
+		c.found = true
+	case *ast.BinaryOperationExpr:
+		c.found = c.found || v.Op == opcode.NullEQ || v.Op == opcode.IsNull
+	}
+	return n, c.found
+}
+
+func (c *nullTestCollector) Leave(n ast.Node) (ast.Node, bool) {
+	return n, true
+}
+
+// loadFields return fields by name, and the field names in the order of the table.
+func (stmt *bitableStatement) loadFields(r *rows, table string) (map[string]lark.Field, []string, error) {
+	version := atomic.LoadUint64(&stmt.conn.schemaVersion)
+	if stmt.plan != nil {
+		if f, ok := stmt.plan.tableFields(r.appToken, table, version); ok {
+			return f.fields, f.order, nil
+		}
+	}
 	fields := make(map[string]lark.Field, 16)
+	order := make([]string, 0, 16)
 	row := newFieldRows(r, table, "")
 	cols := row.Columns()
 	for {
@@ -930,21 +1636,26 @@ func (stmt *bitableStatement) loadFields(r *rows, table string) (map[string]lark
 			if io.EOF == err {
 				break
 			}
-			return nil, err
+			return nil, nil, err
 		}
 		var p lark.FieldProperty
 		if err := json.Unmarshal([]byte(v[3].(string)), &p); err != nil {
-			return nil, fmt.Errorf("bitable %w", err)
+			return nil, nil, fmt.Errorf("bitable %w", err)
 		}
-		fields[v[2].(string)] = lark.Field{
+		name := v[2].(string)
+		fields[name] = lark.Field{
 			FieldID:   v[0].(string),
-			FieldName: v[2].(string),
+			FieldName: name,
 			Type:      v[1].(int64),
 			Property:  &p,
 		}
-
+		order = append(order, name)
+	}
+	if stmt.plan != nil {
+		stmt.plan.setTableFields(r.appToken, table,
+			planFields{version: version, loadedAt: time.Now(), fields: fields, order: order})
 	}
-	return fields, nil
+	return fields, order, nil
 }
 
 func (stmt *bitableStatement) UseStmt(r *rows, s *ast.UseStmt) (driver.Rows, error) {
@@ -952,7 +1663,31 @@ func (stmt *bitableStatement) UseStmt(r *rows, s *ast.UseStmt) (driver.Rows, err
 	if err != nil {
 		return nil, fmt.Errorf("change database[%s] error: %v", r.appToken, err)
 	}
-	r.conn.AppToken = s.DBName
+	r.conn.useApp(s.DBName)
+	return nil, nil
+}
+
+// setStmt change the session variables, only `SET @@user_access_token = 'u-xxx'` is supported,
+// an empty token use the tenant_access_token, DEFAULT restore the identity of the DSN.
+func (stmt *bitableStatement) setStmt(r *rows, s *ast.SetStmt) (driver.Rows, error) {
+	for _, v := range s.Variables {
+		if !v.IsSystem || !strings.EqualFold(v.Name, sessionUserToken) {
+			return nil, fmt.Errorf("[bitable driver] unknown variable '%s'", v.Name)
+		}
+		if v.IsGlobal {
+			return nil, fmt.Errorf("[bitable driver] variable '%s' is a SESSION variable", v.Name)
+		}
+		if _, ok := v.Value.(*ast.DefaultExpr); ok {
+			r.conn.setUserToken(nil)
+			continue
+		}
+		value, err := stmt.eval(v.Value, nil)
+		if err != nil {
+			return nil, fmt.Errorf("[bitable driver] %w", err)
+		}
+		token := toString(value)
+		r.conn.setUserToken(&token)
+	}
 	return nil, nil
 }
 
@@ -0,0 +1,147 @@
+package driver
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newSalesTable() *mockTable {
+	table := newMockTable()
+	table.addRecord("rec1", map[string]interface{}{"name": "apple", "amount": 3.0, "owner": "alice"})
+	table.addRecord("rec2", map[string]interface{}{"name": "banana", "amount": 1.0, "owner": "bob"})
+	table.addRecord("rec3", map[string]interface{}{"name": "cherry", "amount": 2.0, "owner": "alice"})
+	return table
+}
+
+// queryAll run query and return the columns and all rows.
+func queryAll(t *testing.T, db *sql.DB, query string, args ...interface{}) ([]string, [][]interface{}) {
+	t.Helper()
+	rows, err := db.Query(query, args...)
+	require.NoError(t, err)
+	defer rows.Close()
+	columns, err := rows.Columns()
+	require.NoError(t, err)
+	res := [][]interface{}{}
+	for rows.Next() {
+		values := make([]interface{}, len(columns))
+		scans := make([]interface{}, len(columns))
+		for i := range values {
+			scans[i] = &values[i]
+		}
+		require.NoError(t, rows.Scan(scans...))
+		res = append(res, values)
+	}
+	require.NoError(t, rows.Err())
+	return columns, res
+}
+
+func TestSelectProjection(t *testing.T) {
+	t.Run("wildcard follow field order", func(t *testing.T) {
+		table := newSalesTable()
+		columns, res := queryAll(t, newMockDB(t, table), "SELECT * FROM tbl")
+		assert.Equal(t, []string{"record_id", "name", "amount", "owner"}, columns)
+		assert.Len(t, res, 3)
+		assert.Equal(t, "", *table.listRequests()[0].FieldNames)
+	})
+
+	t.Run("only requested fields", func(t *testing.T) {
+		table := newSalesTable()
+		columns, res := queryAll(t, newMockDB(t, table), "SELECT owner AS who FROM tbl WHERE amount > 1")
+		assert.Equal(t, []string{"record_id", "who"}, columns)
+		assert.Equal(t, []interface{}{"rec1", "alice"}, res[0])
+		assert.Equal(t, `["owner","amount"]`, *table.listRequests()[0].FieldNames)
+	})
+}
+
+func TestNullSemantics(t *testing.T) {
+	newTable := func() *mockTable {
+		table := newMockTable()
+		table.fields = append(table.fields, newMockField("born", FieldTypeDate), newMockField("done", FieldTypeCheckbox))
+		table.addRecord("rec1", map[string]interface{}{"name": "apple", "amount": 3.0, "born": 1.6e12, "done": true})
+		table.addRecord("rec2", map[string]interface{}{"name": "banana"})
+		return table
+	}
+
+	t.Run("absent fields are NULL", func(t *testing.T) {
+		_, res := queryAll(t, newMockDB(t, newTable()), "SELECT name, amount, born, done, owner FROM tbl")
+		require.Len(t, res, 2)
+		assert.Equal(t, []interface{}{"rec2", "banana", nil, nil, nil, nil}, res[1])
+	})
+
+	t.Run("is null formulas", func(t *testing.T) {
+		table := newTable()
+		db := newMockDB(t, table)
+		queryAll(t, db, "SELECT name FROM tbl WHERE amount IS NULL AND born IS NOT NULL AND name IS NULL")
+		assert.Equal(t, `AND(AND(ISBLANK(CurrentValue.[amount]),NOT(ISBLANK(CurrentValue.[born]))),CurrentValue.[name]="")`,
+			*table.listRequests()[0].Filter)
+		queryAll(t, db, "SELECT name FROM tbl WHERE done IS NULL OR amount <=> ?", nil)
+		assert.Equal(t, `OR(NOT(CurrentValue.[done]),ISBLANK(CurrentValue.[amount]))`, *table.listRequests()[1].Filter)
+		queryAll(t, db, "SELECT name FROM tbl WHERE name IN ('apple', NULL)")
+		assert.Equal(t, `CurrentValue.[name] = "apple"`, *table.listRequests()[2].Filter)
+	})
+
+	t.Run("is null of arrays and objects", func(t *testing.T) {
+		table := newMockTable()
+		table.fields = append(table.fields, newMockField("tags", FieldTypeMultipleSelect),
+			newMockField("people", FieldTypePerson), newMockField("files", FieldTypeAttachment),
+			newMockField("site", FieldTypeLink))
+		table.addRecord("rec1", map[string]interface{}{"name": "apple", "tags": []interface{}{"red"},
+			"people": []interface{}{map[string]interface{}{"id": "ou_1"}},
+			"files":  []interface{}{map[string]interface{}{"file_token": "box1"}},
+			"site":   map[string]interface{}{"link": "https://a.com", "text": "a"}})
+		table.addRecord("rec2", map[string]interface{}{"name": "banana", "tags": []interface{}{}, "people": []interface{}{},
+			"site": map[string]interface{}{}})
+		db := newMockDB(t, table)
+		for _, c := range []struct {
+			where string
+			want  [][]interface{}
+		}{
+			{"tags IS NULL", [][]interface{}{{"rec2", "banana"}}},
+			{"people IS NULL", [][]interface{}{{"rec2", "banana"}}},
+			{"files IS NOT NULL", [][]interface{}{{"rec1", "apple"}}},
+			{"site <=> NULL", [][]interface{}{{"rec2", "banana"}}},
+			{"name = 'apple' AND site IS NULL", [][]interface{}{}},
+		} {
+			_, res := queryAll(t, db, "SELECT name FROM tbl WHERE "+c.where)
+			assert.Equal(t, c.want, res, c.where)
+		}
+		requests := table.listRequests()
+		assert.Empty(t, *requests[0].Filter, "the empty arrays are tested in driver")
+		assert.Equal(t, `CurrentValue.[name] = "apple"`, *requests[len(requests)-1].Filter)
+	})
+
+	t.Run("comparison with null matches nothing", func(t *testing.T) {
+		table := newTable()
+		db := newMockDB(t, table)
+		for _, query := range []string{
+			"SELECT name FROM tbl WHERE amount = NULL",
+			"SELECT name FROM tbl WHERE amount <> NULL",
+			"SELECT name FROM tbl WHERE name NOT IN ('apple', NULL)",
+		} {
+			_, res := queryAll(t, db, query)
+			assert.Empty(t, res, query)
+		}
+		_, res := queryAll(t, db, "SELECT name FROM tbl WHERE amount = ?", nil)
+		assert.Empty(t, res)
+		assert.Empty(t, table.listRequests())
+
+		queryAll(t, db, "SELECT name FROM tbl WHERE amount > 1 OR amount = NULL")
+		assert.Equal(t, `OR(CurrentValue.[amount] > 1,FALSE())`, *table.listRequests()[0].Filter)
+	})
+
+	t.Run("null clears the field", func(t *testing.T) {
+		table := newTable()
+		db := newMockDB(t, table)
+		_, err := db.Exec("INSERT INTO tbl (name, amount, owner) VALUES ('kiwi', NULL, ?)", nil)
+		require.NoError(t, err)
+		_, err = db.Exec("UPDATE tbl SET amount = NULL, owner = ? WHERE record_id = 'rec1'", nil)
+		require.NoError(t, err)
+		assert.Equal(t, []map[string]interface{}{
+			{"name": "kiwi"},
+			{"amount": nil, "owner": nil},
+		}, table.writeFields())
+	})
+}
@@ -0,0 +1,520 @@
+package driver
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/pingcap/parser/ast"
+	"github.com/pingcap/parser/opcode"
+
+	"github.com/luw2007/bitable-mysql-driver/internal/lark"
+)
+
+var (
+	// maxSubqueryPushDown the max values of an uncorrelated IN subquery pushed down to the filter formula.
+	maxSubqueryPushDown = 100
+	// maxSubqueryCache the max results of a correlated subquery cached by the values of the outer columns.
+	maxSubqueryCache = 1024
+)
+
+// outerColumnExpr a column of the outer query referenced by a correlated subquery,
+// it's evaluated as the value of the current outer row.
+type outerColumnExpr struct {
+	ast.ColumnNameExpr
+	env *evalEnv
+}
+
+// Accept keep the node, the children of ColumnNameExpr are not visited.
+func (n *outerColumnExpr) Accept(v ast.Visitor) (ast.Node, bool) {
+	newNode, _ := v.Enter(n)
+	return v.Leave(newNode)
+}
+
+// subqueryPlan a subquery with the outer columns it references, results are cached by the outer values.
+type subqueryPlan struct {
+	base  *rows
+	query ast.ResultSetNode
+	outer []*outerColumnExpr
+	cache map[string][][]driver.Value
+}
+
+func (p *subqueryPlan) correlated() bool {
+	return len(p.outer) > 0
+}
+
+// subqueryCollector collect the subqueries of the current query, nested subqueries are skipped.
+type subqueryCollector struct {
+	subqueries []*ast.SubqueryExpr
+}
+
+func (c *subqueryCollector) Enter(n ast.Node) (ast.Node, bool) {
+	if v, ok := n.(*ast.SubqueryExpr); ok {
+		c.subqueries = append(c.subqueries, v)
+		return n, true
+	}
+	return n, false
+}
+
+func (c *subqueryCollector) Leave(n ast.Node) (ast.Node, bool) {
+	return n, true
+}
+
+func collectSubqueries(nodes ...ast.Node) []*ast.SubqueryExpr {
+	c := &subqueryCollector{}
+	for _, node := range nodes {
+		if node != nil {
+			node.Accept(c)
+		}
+	}
+	return c.subqueries
+}
+
+// outerBinder replace the columns of the outer table in a subquery by outerColumnExpr.
+type outerBinder struct {
+	innerNames  []string
+	innerFields map[string]lark.Field
+	outerNames  []string
+	outerFields map[string]lark.Field
+	env         *evalEnv
+	bound       []*outerColumnExpr
+}
+
+func (b *outerBinder) Enter(n ast.Node) (ast.Node, bool) {
+	// nested subqueries bind to their own outer query
+	_, ok := n.(*ast.SubqueryExpr)
+	return n, ok
+}
+
+func (b *outerBinder) Leave(n ast.Node) (ast.Node, bool) {
+	if c, ok := n.(*ast.ColumnNameExpr); ok && b.isOuter(c.Name) {
+		column := &outerColumnExpr{ColumnNameExpr: *c, env: b.env}
+		b.bound = append(b.bound, column)
+		return column, true
+	}
+	return n, true
+}
+
+// isOuter resolve a column like MySQL, an unqualified column belongs to the inner table first.
+func (b *outerBinder) isOuter(name *ast.ColumnName) bool {
+	if b.env == nil {
+		return false
+	}
+	if name.Table.O != "" {
+		return containsFold(b.outerNames, name.Table.O) && !containsFold(b.innerNames, name.Table.O)
+	}
+	if _, ok := b.innerFields[name.Name.O]; ok || name.Name.O == FieldKeyRecordID {
+		return false
+	}
+	_, ok := b.outerFields[name.Name.O]
+	return ok
+}
+
+func containsFold(names []string, name string) bool {
+	for _, n := range names {
+		if n != "" && strings.EqualFold(n, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// bindSubqueries plan the subqueries of nodes, columns of the outer table are bound to env.
+// It returns the names of the outer columns referenced by the subqueries.
+func (stmt *bitableStatement) bindSubqueries(r *rows, outerNames []string, outerFields map[string]lark.Field,
+	env *evalEnv, nodes ...ast.Node) ([]string, error) {
+	var names []string
+	for _, sq := range collectSubqueries(nodes...) {
+		plan := &subqueryPlan{base: r, query: sq.Query}
+		for _, sel := range selectsOf(sq.Query) {
+			binder := &outerBinder{outerNames: outerNames, outerFields: outerFields, env: env}
+			if sel.From != nil {
+				appToken, table, _, err := stmt.getAppTableView(r.ctx, sel.From)
+				if err != nil {
+					return nil, fmt.Errorf("[bitable driver] %w", err)
+				}
+				inner := r
+				if appToken != "" {
+					appRows := *r
+					appRows.appToken = appToken
+					inner = &appRows
+				}
+				if binder.innerFields, _, err = stmt.loadFields(inner, table); err != nil {
+					return nil, err
+				}
+				binder.innerNames = tableNames(sel.From, table)
+			}
+			sel.Accept(binder)
+			plan.outer = append(plan.outer, binder.bound...)
+		}
+		for _, column := range plan.outer {
+			names = append(names, column.Name.Name.O)
+		}
+		if stmt.subqueries == nil {
+			stmt.subqueries = make(map[*ast.SubqueryExpr]*subqueryPlan)
+		}
+		stmt.subqueries[sq] = plan
+	}
+	return names, nil
+}
+
+func selectsOf(node ast.ResultSetNode) []*ast.SelectStmt {
+	switch n := node.(type) {
+	case *ast.SelectStmt:
+		return []*ast.SelectStmt{n}
+	case *ast.UnionStmt:
+		if n.SelectList != nil {
+			return n.SelectList.Selects
+		}
+	}
+	return nil
+}
+
+// tableNames the names to qualify the columns of the table, the table and its alias.
+func tableNames(from *ast.TableRefsClause, table string) []string {
+	names := []string{table}
+	if from != nil && from.TableRefs != nil {
+		if source, ok := from.TableRefs.Left.(*ast.TableSource); ok && source.AsName.O != "" {
+			names = append(names, source.AsName.O)
+		}
+	}
+	return names
+}
+
+// runSubquery return at most limit rows of the subquery, 0 means all rows.
+func (stmt *bitableStatement) runSubquery(sq *ast.SubqueryExpr, limit int64, oneColumn bool) ([][]driver.Value, error) {
+	plan, ok := stmt.subqueries[sq]
+	if !ok {
+		return nil, errors.New("not supported subquery")
+	}
+	var key string
+	if plan.correlated() {
+		values := make([]interface{}, 0, len(plan.outer))
+		for _, column := range plan.outer {
+			v, err := column.env.lookup(column.Name)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, v)
+		}
+		key = distinctKey(values)
+	}
+	if res, ok := plan.cache[key]; ok {
+		return res, nil
+	}
+
+	source, err := stmt.resultSetRows(plan.base, plan.query)
+	if err != nil {
+		return nil, err
+	}
+	defer source.Close()
+	if oneColumn && len(source.Columns()) != 1 {
+		return nil, errors.New("operand should contain 1 column(s)")
+	}
+	res := make([][]driver.Value, 0)
+	_, err = readSource(source, limit, func(src []driver.Value) error {
+		res = append(res, append([]driver.Value(nil), src...))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if plan.cache == nil || len(plan.cache) >= maxSubqueryCache {
+		plan.cache = make(map[string][][]driver.Value)
+	}
+	plan.cache[key] = res
+	return res, nil
+}
+
+// resultSetRows the rows of a subquery, without record_id.
+func (stmt *bitableStatement) resultSetRows(r *rows, node ast.ResultSetNode) (driver.Rows, error) {
+	switch n := node.(type) {
+	case *ast.SelectStmt:
+		return stmt.selectRows(r, n, false, false)
+	case *ast.UnionStmt:
+		return stmt.unionStmt(r, n, false)
+	}
+	return nil, fmt.Errorf("not supported subquery %T", node)
+}
+
+// subqueryValues the values of the one column subquery.
+func (stmt *bitableStatement) subqueryValues(sq *ast.SubqueryExpr) ([]interface{}, error) {
+	res, err := stmt.runSubquery(sq, 0, true)
+	if err != nil {
+		return nil, err
+	}
+	values := make([]interface{}, 0, len(res))
+	for _, row := range res {
+		values = append(values, normalizeValue(row[0]))
+	}
+	return values, nil
+}
+
+func (stmt *bitableStatement) evalScalarSubquery(sq *ast.SubqueryExpr) (interface{}, error) {
+	res, err := stmt.runSubquery(sq, 2, true)
+	if err != nil {
+		return nil, err
+	}
+	switch len(res) {
+	case 0:
+		return nil, nil
+	case 1:
+		return normalizeValue(res[0][0]), nil
+	}
+	return nil, errors.New("subquery returns more than 1 row")
+}
+
+func (stmt *bitableStatement) evalExists(e *ast.ExistsSubqueryExpr) (interface{}, error) {
+	sq, ok := e.Sel.(*ast.SubqueryExpr)
+	if !ok {
+		return nil, fmt.Errorf("not supported expression %T", e.Sel)
+	}
+	res, err := stmt.runSubquery(sq, 1, false)
+	if err != nil {
+		return nil, err
+	}
+	return boolValue((len(res) > 0) != e.Not), nil
+}
+
+// evalInSubquery `v IN (SELECT ...)`, NULL like a value list containing NULL.
+func (stmt *bitableStatement) evalInSubquery(e *ast.PatternInExpr, env *evalEnv) (interface{}, error) {
+	sq, ok := e.Sel.(*ast.SubqueryExpr)
+	if !ok {
+		return nil, fmt.Errorf("not supported expression %T", e.Sel)
+	}
+	v, err := stmt.eval(e.Expr, env)
+	if err != nil || v == nil {
+		return nil, err
+	}
+	values, err := stmt.subqueryValues(sq)
+	if err != nil {
+		return nil, err
+	}
+	hasNull := false
+	for _, item := range values {
+		if item == nil {
+			hasNull = true
+			continue
+		}
+		if compareValues(v, item) == 0 {
+			return boolValue(!e.Not), nil
+		}
+	}
+	if hasNull {
+		return nil, nil
+	}
+	return boolValue(e.Not), nil
+}
+
+// evalCompareSubquery `v > ANY (SELECT ...)` or `v > ALL (SELECT ...)`.
+func (stmt *bitableStatement) evalCompareSubquery(e *ast.CompareSubqueryExpr, env *evalEnv) (interface{}, error) {
+	sq, ok := e.R.(*ast.SubqueryExpr)
+	if !ok {
+		return nil, fmt.Errorf("not supported expression %T", e.R)
+	}
+	v, err := stmt.eval(e.L, env)
+	if err != nil {
+		return nil, err
+	}
+	values, err := stmt.subqueryValues(sq)
+	if err != nil {
+		return nil, err
+	}
+	hasNull := false
+	for _, item := range values {
+		res, err := compareWithOp(e.Op, v, item)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case res == nil:
+			hasNull = true
+		case e.All && !isTrue(res):
+			return int64(0), nil
+		case !e.All && isTrue(res):
+			return int64(1), nil
+		}
+	}
+	if hasNull {
+		return nil, nil
+	}
+	return boolValue(e.All), nil
+}
+
+func compareWithOp(op opcode.Op, l, r interface{}) (interface{}, error) {
+	if l == nil || r == nil {
+		return nil, nil
+	}
+	c := compareValues(l, r)
+	switch op {
+	case opcode.EQ:
+		return boolValue(c == 0), nil
+	case opcode.NE:
+		return boolValue(c != 0), nil
+	case opcode.LT:
+		return boolValue(c < 0), nil
+	case opcode.LE:
+		return boolValue(c <= 0), nil
+	case opcode.GT:
+		return boolValue(c > 0), nil
+	case opcode.GE:
+		return boolValue(c >= 0), nil
+	}
+	return nil, fmt.Errorf("not supported op %s", op)
+}
+
+// planWhere split WHERE into the conditions pushed down to the filter formula and the residual filtered in driver.
+// Uncorrelated subqueries run once and are pushed down as literal values, empty report WHERE is always false.
+func (stmt *bitableStatement) planWhere(where ast.ExprNode) (pushed, residual ast.ExprNode, empty bool, err error) {
+	if len(collectSubqueries(where)) == 0 {
+		return where, nil, false, nil
+	}
+	var pushedConds, residualConds []ast.ExprNode
+	for _, cond := range splitConjuncts(where) {
+		subqueries := collectSubqueries(cond)
+		if len(subqueries) == 0 {
+			pushedConds = append(pushedConds, cond)
+			continue
+		}
+		correlated := false
+		for _, sq := range subqueries {
+			if plan, ok := stmt.subqueries[sq]; ok && plan.correlated() {
+				correlated = true
+			}
+		}
+		if correlated {
+			residualConds = append(residualConds, cond)
+			continue
+		}
+
+		switch c := cond.(type) {
+		case *ast.ExistsSubqueryExpr:
+			v, err := stmt.evalExists(c)
+			if err != nil {
+				return nil, nil, false, err
+			}
+			if !isTrue(v) {
+				return nil, nil, true, nil
+			}
+			continue
+		case *ast.PatternInExpr:
+			sq, ok := c.Sel.(*ast.SubqueryExpr)
+			if _, isColumn := c.Expr.(*ast.ColumnNameExpr); !ok || !isColumn {
+				break
+			}
+			values, err := stmt.subqueryValues(sq)
+			if err != nil {
+				return nil, nil, false, err
+			}
+			if len(values) == 0 {
+				if c.Not {
+					continue
+				}
+				return nil, nil, true, nil
+			}
+			if len(values) > maxSubqueryPushDown || !isLiteralValues(values) {
+				residualConds = append(residualConds, cond)
+				continue
+			}
+			list := make([]ast.ExprNode, 0, len(values))
+			for _, v := range values {
+				list = append(list, ast.NewValueExpr(v, "", ""))
+			}
+			pushedConds = append(pushedConds, &ast.PatternInExpr{Expr: c.Expr, List: list, Not: c.Not})
+			continue
+		}
+
+		// scalar subqueries are replaced by the value
+		s := &scalarSubstituter{stmt: stmt}
+		node, _ := cond.Accept(s)
+		if s.err != nil {
+			return nil, nil, false, s.err
+		}
+		if s.residual {
+			residualConds = append(residualConds, node.(ast.ExprNode))
+			continue
+		}
+		pushedConds = append(pushedConds, node.(ast.ExprNode))
+	}
+	return joinConjuncts(pushedConds), joinConjuncts(residualConds), false, nil
+}
+
+// scalarSubstituter replace uncorrelated scalar subqueries by the value, residual report the condition can't push down.
+type scalarSubstituter struct {
+	stmt     *bitableStatement
+	residual bool
+	err      error
+}
+
+func (s *scalarSubstituter) Enter(n ast.Node) (ast.Node, bool) {
+	switch v := n.(type) {
+	case *ast.ExistsSubqueryExpr, *ast.CompareSubqueryExpr:
+		s.residual = true
+		return n, true
+	case *ast.PatternInExpr:
+		if v.Sel != nil {
+			s.residual = true
+			return n, true
+		}
+	case *ast.SubqueryExpr:
+		return n, true
+	}
+	return n, false
+}
+
+func (s *scalarSubstituter) Leave(n ast.Node) (ast.Node, bool) {
+	sq, ok := n.(*ast.SubqueryExpr)
+	if !ok || s.err != nil {
+		return n, true
+	}
+	v, err := s.stmt.evalScalarSubquery(sq)
+	if err != nil {
+		s.err = err
+		return n, false
+	}
+	if !isLiteralValues([]interface{}{v}) {
+		s.residual = true
+	}
+	return ast.NewValueExpr(v, "", ""), true
+}
+
+// isLiteralValues report whether the values can be literals of the filter formula.
+func isLiteralValues(values []interface{}) bool {
+	for _, v := range values {
+		switch v.(type) {
+		case string, int64, float64:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func splitConjuncts(expr ast.ExprNode) []ast.ExprNode {
+	switch e := expr.(type) {
+	case nil:
+		return nil
+	case *ast.BinaryOperationExpr:
+		if e.Op == opcode.LogicAnd {
+			return append(splitConjuncts(e.L), splitConjuncts(e.R)...)
+		}
+	case *ast.ParenthesesExpr:
+		if b, ok := e.Expr.(*ast.BinaryOperationExpr); ok && b.Op == opcode.LogicAnd {
+			return splitConjuncts(b)
+		}
+	}
+	return []ast.ExprNode{expr}
+}
+
+func joinConjuncts(conds []ast.ExprNode) ast.ExprNode {
+	var expr ast.ExprNode
+	for _, cond := range conds {
+		if expr == nil {
+			expr = cond
+			continue
+		}
+		expr = &ast.BinaryOperationExpr{Op: opcode.LogicAnd, L: expr, R: cond}
+	}
+	return expr
+}
@@ -0,0 +1,78 @@
+package driver
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newAdminsTable() *mockTable {
+	table := &mockTable{}
+	table.fields = append(table.fields, newMockField("name", FieldTypeText), newMockField("level", FieldTypeNumber))
+	table.addRecord("adm1", map[string]interface{}{"name": "alice", "level": 2.0})
+	table.addRecord("adm2", map[string]interface{}{"name": "carol", "level": 1.0})
+	return table
+}
+
+func TestSelectSubquery(t *testing.T) {
+	t.Run("uncorrelated in push down", func(t *testing.T) {
+		table := newSalesTable()
+		table.addTable("tblAdmins", newAdminsTable())
+		db := newMockDB(t, table)
+
+		queryAll(t, db, "SELECT name FROM tbl WHERE owner IN (SELECT name FROM tblAdmins) AND amount > 0")
+		assert.Equal(t, `AND(OR(CurrentValue.[owner] = "alice",CurrentValue.[owner] = "carol"),CurrentValue.[amount] > 0)`, *table.listRequests()[0].Filter)
+
+		queryAll(t, db, "SELECT name FROM tbl WHERE owner NOT IN (SELECT name FROM tblAdmins)")
+		assert.Equal(t, `AND(CurrentValue.[owner] != "alice",CurrentValue.[owner] != "carol")`, *table.listRequests()[1].Filter)
+	})
+
+	t.Run("uncorrelated in residual", func(t *testing.T) {
+		defer func(max int) { maxSubqueryPushDown = max }(maxSubqueryPushDown)
+		maxSubqueryPushDown = 1
+
+		table := newSalesTable()
+		table.addTable("tblAdmins", newAdminsTable())
+		_, res := queryAll(t, newMockDB(t, table), "SELECT name FROM tbl WHERE owner IN (SELECT name FROM tblAdmins) LIMIT 1")
+		assert.Equal(t, [][]interface{}{{"rec1", "apple"}}, res)
+		assert.Equal(t, "", *table.listRequests()[0].Filter)
+	})
+
+	t.Run("exists", func(t *testing.T) {
+		table := newSalesTable()
+		table.addTable("tblAdmins", newAdminsTable())
+		table.addTable("tblEmpty", &mockTable{fields: newAdminsTable().fields})
+		db := newMockDB(t, table)
+
+		_, res := queryAll(t, db, "SELECT name FROM tbl WHERE EXISTS (SELECT 1 FROM tblEmpty)")
+		assert.Empty(t, res)
+		assert.Empty(t, table.listRequests())
+
+		_, res = queryAll(t, db, "SELECT name FROM tbl t WHERE EXISTS (SELECT 1 FROM tblAdmins a WHERE a.name = t.owner)")
+		assert.Equal(t, [][]interface{}{{"rec1", "apple"}, {"rec3", "cherry"}}, res)
+
+		_, res = queryAll(t, db, "SELECT name FROM tbl WHERE NOT EXISTS (SELECT 1 FROM tblAdmins WHERE name = owner)")
+		assert.Equal(t, [][]interface{}{{"rec2", "banana"}}, res)
+	})
+
+	t.Run("scalar", func(t *testing.T) {
+		table := newSalesTable()
+		admins := newAdminsTable()
+		table.addTable("tblAdmins", admins)
+		db := newMockDB(t, table)
+
+		_, res := queryAll(t, db, "SELECT name, (SELECT level FROM tblAdmins WHERE name = owner) AS lvl FROM tbl")
+		assert.Equal(t, [][]interface{}{{"rec1", "apple", 2.0}, {"rec2", "banana", nil}, {"rec3", "cherry", 2.0}}, res)
+		// the correlated subquery is cached by the owner
+		assert.Len(t, admins.listRequests(), 2)
+
+		_, res = queryAll(t, db, "SELECT name FROM tbl WHERE amount = (SELECT level FROM tblAdmins WHERE name = 'carol')")
+		assert.Equal(t, [][]interface{}{{"rec2", "banana"}}, res)
+
+		_, res = queryAll(t, db, "SELECT name FROM tbl WHERE amount > ALL (SELECT level FROM tblAdmins)")
+		assert.Equal(t, [][]interface{}{{"rec1", "apple"}}, res)
+
+		_, err := db.Query("SELECT name FROM tbl WHERE amount = (SELECT level FROM tblAdmins)")
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,305 @@
+package driver
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/pingcap/parser/ast"
+
+	"github.com/luw2007/bitable-mysql-driver/internal/lark"
+)
+
+// findTable return the table of the app by id or name, nil when not found.
+func (stmt *bitableStatement) findTable(r *rows, table string) (*lark.Table, error) {
+	pageToken := ""
+	for i := 0; i < maxLoopTimes; i++ {
+		page, err := stmt.conn.ListTable(r.ctx, r.appToken, pageToken, DefaultPageSize)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range page.Items {
+			if t := item.(*lark.Table); t.TableID == table || t.Name == table {
+				return t, nil
+			}
+		}
+		if !page.HasMore {
+			break
+		}
+		pageToken = page.PageToken
+	}
+	return nil, nil
+}
+
+// tableID return the id of a table by id or name, the ids are used as is.
+func (stmt *bitableStatement) tableID(r *rows, table string) (string, error) {
+	if strings.HasPrefix(table, tableIDPrefix) {
+		return table, nil
+	}
+	t, err := stmt.findTable(r, table)
+	if err != nil {
+		return "", err
+	}
+	if t == nil {
+		return "", &Error{Message: fmt.Sprintf("unknown table '%s'", table), Err: ErrTableNotFound}
+	}
+	return t.TableID, nil
+}
+
+// createTableStmt create the table with its default view and fields in one request,
+// the table is dropped when it isn't created as requested. IF NOT EXISTS returns the table of the name.
+func (stmt *bitableStatement) createTableStmt(r *rows, s *ast.CreateTableStmt) (driver.Rows, error) {
+	defer stmt.conn.schemaChanged()
+	tableName, _, err := stmt.getTableView(r.ctx, s.Table)
+	if err != nil {
+		return nil, fmt.Errorf("[bitable driver] %w", err)
+	}
+	if s.ReferTable != nil || s.Select != nil {
+		return nil, errors.New("[bitable driver] CREATE TABLE ... LIKE and CREATE TABLE ... SELECT are not supported")
+	}
+	columns := []string{"table"}
+	if s.IfNotExists {
+		found, err := stmt.findTable(r, tableName)
+		if err != nil {
+			return nil, fmt.Errorf("[bitable driver] %w", err)
+		}
+		if found != nil {
+			return newRowsFactory(r.Clone(columns, []interface{}{[]interface{}{found.TableID}})), nil
+		}
+	}
+	fields, err := stmt.tableFields(r, s.Cols)
+	if err != nil {
+		return nil, fmt.Errorf("[bitable driver] %w", err)
+	}
+	// COMMENT is the name of the default view
+	viewName := stmt.getComment(s.Options)
+	created, err := stmt.conn.CreateTableWithFields(r.ctx, r.appToken, tableName, viewName, fields)
+	if err != nil {
+		return nil, fmt.Errorf("[bitable driver] %w", err)
+	}
+	if len(created.FieldIDList) != len(fields) {
+		err = fmt.Errorf("[bitable driver] table '%s' is created with %d of %d fields",
+			tableName, len(created.FieldIDList), len(fields))
+		return nil, stmt.rollbackTable(r, created.TableID, err)
+	}
+	return newRowsFactory(r.Clone(columns, []interface{}{[]interface{}{created.TableID}})), nil
+}
+
+// tableFields check the columns of CREATE TABLE before the table is created.
+func (stmt *bitableStatement) tableFields(r *rows, cols []*ast.ColumnDef) ([]*lark.TableField, error) {
+	fields := make([]*lark.TableField, 0, len(cols))
+	names := make(map[string]bool, len(cols))
+	for _, column := range cols {
+		fieldName := column.Name.Name.O
+		if names[column.Name.Name.L] {
+			return nil, fmt.Errorf("duplicate column name '%s'", fieldName)
+		}
+		names[column.Name.Name.L] = true
+		field := &lark.TableField{FieldName: fieldName, Type: stmt.getFieldType(r.ctx, column.Tp)}
+		if field.Type == 0 {
+			return nil, fmt.Errorf("the type %s of column '%s' is not supported", column.Tp, fieldName)
+		}
+		if comment := stmt.getComment(column.Options); comment != "" {
+			if !json.Valid([]byte(comment)) {
+				return nil, fmt.Errorf("the comment of column '%s' is not the json of a field property", fieldName)
+			}
+			field.Property = json.RawMessage(comment)
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// rollbackTable drop the table of a failed CREATE TABLE, err is returned with the error of the drop.
+func (stmt *bitableStatement) rollbackTable(r *rows, table string, err error) error {
+	if dropErr := stmt.conn.DropTable(r.ctx, r.appToken, table); dropErr != nil {
+		return fmt.Errorf("%w, drop the table '%s' error: %v", err, table, dropErr)
+	}
+	return err
+}
+
+// dropTableStmt drop the tables or views in order, IF EXISTS skips the missing ones.
+func (stmt *bitableStatement) dropTableStmt(r *rows, s *ast.DropTableStmt) (driver.Rows, error) {
+	defer stmt.conn.schemaChanged()
+	for _, t := range s.Tables {
+		table, view, err := stmt.getTableView(r.ctx, t)
+		if err != nil {
+			return nil, fmt.Errorf("[bitable driver] get table view error: %w", err)
+		}
+		if s.IsView {
+			if err := stmt.dropView(r, table, view, s.IfExists); err != nil {
+				return nil, fmt.Errorf("[bitable driver] %w", err)
+			}
+			continue
+		}
+		if s.IfExists {
+			found, err := stmt.findTable(r, table)
+			if err != nil {
+				return nil, fmt.Errorf("[bitable driver] %w", err)
+			}
+			if found == nil {
+				continue
+			}
+			table = found.TableID
+		} else if table, err = stmt.tableID(r, table); err != nil {
+			return nil, fmt.Errorf("[bitable driver] %w", err)
+		}
+		if err := stmt.conn.DropTable(r.ctx, r.appToken, table); err != nil {
+			return nil, fmt.Errorf("[bitable driver] %w", err)
+		}
+	}
+	return nil, nil
+}
+
+func (stmt *bitableStatement) dropView(r *rows, table, view string, ifExists bool) error {
+	if ifExists {
+		found, err := stmt.findView(r, table, view)
+		if err != nil || found == nil {
+			return err
+		}
+		view = found.ViewID
+	} else {
+		var err error
+		if view, err = stmt.viewID(r, table, view); err != nil {
+			return err
+		}
+	}
+	return stmt.conn.DropView(r.ctx, r.appToken, table, view)
+}
+
+// renameTableStmt rename the tables in order, `RENAME TABLE a TO b, c TO d`.
+func (stmt *bitableStatement) renameTableStmt(r *rows, s *ast.RenameTableStmt) (driver.Rows, error) {
+	defer stmt.conn.schemaChanged()
+	for _, t := range s.TableToTables {
+		table, _, err := stmt.getTableView(r.ctx, t.OldTable)
+		if err != nil {
+			return nil, fmt.Errorf("[bitable driver] %w", err)
+		}
+		if err := stmt.renameTable(r, table, t.NewTable); err != nil {
+			return nil, fmt.Errorf("[bitable driver] %w", err)
+		}
+	}
+	return nil, nil
+}
+
+func (stmt *bitableStatement) renameTable(r *rows, table string, newTable *ast.TableName) error {
+	if newTable.Schema.O != "" {
+		return fmt.Errorf("can't move table '%s' to '%s', RENAME TABLE renames a table of the app", table, newTable.Schema.O)
+	}
+	tableID, err := stmt.tableID(r, table)
+	if err != nil {
+		return err
+	}
+	return stmt.conn.UpdateTable(r.ctx, r.appToken, tableID, newTable.Name.O)
+}
+
+// alterTableStmt apply the specs in order, a failed spec stops the statement and the specs before it stay applied.
+func (stmt *bitableStatement) alterTableStmt(r *rows, s *ast.AlterTableStmt) (driver.Rows, error) {
+	defer stmt.conn.schemaChanged()
+	table, _, err := stmt.getTableView(r.ctx, s.Table)
+	if err != nil {
+		return nil, fmt.Errorf("[bitable driver] %w", err)
+	}
+	if table, err = stmt.tableID(r, table); err != nil {
+		return nil, fmt.Errorf("[bitable driver] %w", err)
+	}
+	var added []interface{}
+	for i, spec := range s.Specs {
+		items, err := stmt.alterTableSpec(r, table, spec)
+		if err != nil {
+			return nil, fmt.Errorf("[bitable driver] ALTER TABLE spec %d of %d `%s` failed, %d applied: %w",
+				i+1, len(s.Specs), restore(spec), i, err)
+		}
+		added = append(added, items...)
+	}
+	if len(added) == 0 {
+		return nil, nil
+	}
+	columns := []string{"field_id", "name", "type", "property"}
+	return newRowsFactory(r.Clone(columns, added)), nil
+}
+
+// alterTableSpec apply a spec of ALTER TABLE, the added fields are returned.
+func (stmt *bitableStatement) alterTableSpec(r *rows, table string, spec *ast.AlterTableSpec) ([]interface{}, error) {
+	switch spec.Tp {
+	case ast.AlterTableAddColumns:
+		items := make([]interface{}, 0, len(spec.NewColumns))
+		for _, column := range spec.NewColumns {
+			fieldName := column.Name.Name.O
+			fieldType := stmt.getFieldType(r.ctx, column.Tp)
+			comment := stmt.getComment(column.Options)
+			field, err := stmt.conn.AddField(r.ctx, r.appToken, table, fieldName, fieldType, comment)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, []interface{}{field.FieldID, field.FieldName, field.Type, oneLine(field.Property)})
+		}
+		return items, nil
+	case ast.AlterTableDropColumn:
+		fieldName := spec.OldColumnName.Name.O
+		fieldId, _, _, err := stmt.getFieldID(r.ctx, r.appToken, table, fieldName)
+		if err != nil {
+			return nil, err
+		}
+		_, err = stmt.conn.DeleteField(r.ctx, r.appToken, table, fieldId)
+		return nil, err
+	case ast.AlterTableRenameColumn:
+		oldFieldName := spec.OldColumnName.Name.O
+		fieldName := spec.NewColumnName.Name.O
+		fieldID, oldFieldType, oldComment, err := stmt.getFieldID(r.ctx, r.appToken, table, oldFieldName)
+		if err != nil {
+			return nil, err
+		}
+		_, err = stmt.conn.UpdateField(r.ctx, r.appToken, table, fieldID, fieldName, oldFieldType, oldComment)
+		return nil, err
+	case ast.AlterTableModifyColumn, ast.AlterTableChangeColumn:
+		for _, column := range spec.NewColumns {
+			fieldName := column.Name.Name.O
+			fieldType := stmt.getFieldType(r.ctx, column.Tp)
+			oldFieldName := fieldName
+			// modify column
+			if spec.OldColumnName != nil {
+				oldFieldName = spec.OldColumnName.Name.O
+			}
+			fieldID, oldFieldType, oldComment, err := stmt.getFieldID(r.ctx, r.appToken, table, oldFieldName)
+			if err != nil {
+				return nil, err
+			}
+			if fieldType == 0 {
+				fieldType = oldFieldType
+			}
+			comment := stmt.getComment(column.Options)
+			if comment == "" {
+				comment = oldComment
+			}
+			if _, err = stmt.conn.UpdateField(r.ctx, r.appToken, table, fieldID, fieldName, fieldType, comment); err != nil {
+				return nil, err
+			}
+		}
+		return nil, nil
+	case ast.AlterTableRenameTable:
+		return nil, stmt.renameTable(r, table, spec.NewTable)
+	case ast.AlterTableOption:
+		// COMMENT is the name of the default view like CREATE TABLE, the other options are ignored
+		if viewName := stmt.getComment(spec.Options); viewName != "" {
+			return nil, stmt.renameDefaultView(r, table, viewName)
+		}
+		return nil, nil
+	}
+	return nil, fmt.Errorf("the spec is not supported")
+}
+
+// renameDefaultView rename the first view of table.
+func (stmt *bitableStatement) renameDefaultView(r *rows, table, viewName string) error {
+	views, err := stmt.conn.ListViews(r.ctx, r.appToken, table, "", 1)
+	if err != nil {
+		return err
+	}
+	if len(views.Items) == 0 {
+		return &Error{Message: fmt.Sprintf("table '%s' has no view", table), Err: ErrViewNotFound}
+	}
+	_, err = stmt.conn.UpdateView(r.ctx, r.appToken, table, views.Items[0].(*lark.View).ViewID, viewName, nil)
+	return err
+}
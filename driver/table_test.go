@@ -0,0 +1,78 @@
+package driver
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/luw2007/bitable-mysql-driver/internal/lark"
+)
+
+func TestTableDDL(t *testing.T) {
+	table := newSalesTable()
+	table.tables = []*lark.Table{{TableID: "tbl", Name: "sales"}, {TableID: "tblold", Name: "old"}}
+	table.views = []*lark.View{{ViewID: "vew1", ViewName: "Grid", ViewType: "grid"}}
+	db := newMockDB(t, table)
+
+	t.Run("rename", func(t *testing.T) {
+		_, err := db.Exec("RENAME TABLE sales TO orders, tblold TO archive")
+		require.NoError(t, err)
+		_, err = db.Exec("ALTER TABLE orders RENAME TO sales")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"tbl:sales", "tblold:archive"}, table.tableNames())
+
+		_, err = db.Exec("RENAME TABLE missing TO other")
+		assert.ErrorIs(t, err, ErrTableNotFound)
+	})
+
+	t.Run("specs are applied in order", func(t *testing.T) {
+		columns, res := queryAll(t, db, "ALTER TABLE sales ADD COLUMN note text, DROP COLUMN owner, ADD COLUMN memo text, COMMENT 'Sales'")
+		assert.Equal(t, []string{"field_id", "name", "type", "property"}, columns)
+		assert.Equal(t, [][]interface{}{
+			{"fld_note", "note", int64(FieldTypeText), "null"},
+			{"fld_memo", "memo", int64(FieldTypeText), "null"},
+		}, res)
+		assert.Equal(t, []string{"name", "amount", "note", "memo"}, table.fieldNames())
+		assert.Equal(t, "Sales", table.view("vew1").ViewName)
+
+		_, err := db.Exec("ALTER TABLE tbl DROP COLUMN memo, DROP COLUMN owner, DROP COLUMN note")
+		assert.EqualError(t, err, "[bitable driver] ALTER TABLE spec 2 of 3 `DROP COLUMN `owner`` failed, "+
+			"1 applied: unknown column 'owner' in 'field list'")
+		assert.ErrorIs(t, err, ErrFieldNotFound)
+		assert.Equal(t, []string{"name", "amount", "note"}, table.fieldNames())
+	})
+
+	t.Run("create with the view and fields", func(t *testing.T) {
+		_, res := queryAll(t, db, "CREATE TABLE todo (title text, score int, tags varchar(4) COMMENT '{\"options\":[{\"name\":\"a\"}]}') COMMENT 'Board'")
+		assert.Equal(t, [][]interface{}{{"tblnew3"}}, res)
+		assert.Equal(t, []string{"title", "score", "tags"}, table.table("tblnew3").fieldNames())
+		assert.Equal(t, "Board", table.table("tblnew3").view("vewdefault").ViewName)
+
+		_, err := db.Exec("CREATE TABLE bad (title text, title int)")
+		assert.EqualError(t, err, "[bitable driver] duplicate column name 'title'")
+		_, err = db.Exec("CREATE TABLE bad (title text, tags varchar(4) COMMENT 'options')")
+		assert.EqualError(t, err, "[bitable driver] the comment of column 'tags' is not the json of a field property")
+
+		table.createdFields = 1
+		defer func() { table.createdFields = 0 }()
+		_, err = db.Exec("CREATE TABLE half (title text, score int)")
+		assert.EqualError(t, err, "[bitable driver] table 'half' is created with 1 of 2 fields")
+		assert.Equal(t, []string{"tbl:sales", "tblold:archive", "tblnew3:todo"}, table.tableNames(), "the half table is dropped")
+	})
+
+	t.Run("if exists", func(t *testing.T) {
+		_, res := queryAll(t, db, "CREATE TABLE IF NOT EXISTS sales (name text)")
+		assert.Equal(t, [][]interface{}{{"tbl"}}, res)
+
+		_, err := db.Exec("DROP TABLE IF EXISTS missing, archive, todo")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"tbl:sales"}, table.tableNames())
+		_, err = db.Exec("DROP TABLE archive")
+		assert.ErrorIs(t, err, ErrTableNotFound)
+
+		_, err = db.Exec("DROP VIEW IF EXISTS tbl.missing, tbl.Sales")
+		require.NoError(t, err)
+		assert.Empty(t, table.views)
+	})
+}
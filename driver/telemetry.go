@@ -0,0 +1,148 @@
+package driver
+
+import (
+	"context"
+	"sync"
+
+	"github.com/pingcap/parser/ast"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// statementSpan trace a statement, it ends when the rows are read or closed.
+type statementSpan struct {
+	span      trace.Span
+	operation string
+
+	once sync.Once
+	rows int64
+}
+
+// startSpan start the span of the statement, nil when tracing is disabled.
+func (stmt *bitableStatement) startSpan(ctx context.Context) (context.Context, *statementSpan) {
+	tracer := stmt.conn.Telemetry().Tracer()
+	if tracer == nil {
+		return ctx, nil
+	}
+	s := &statementSpan{operation: "UNKNOWN"}
+	attrs := []attribute.KeyValue{
+		attribute.String("db.system", "bitable"),
+		attribute.String("db.statement", redact(stmt.query)),
+	}
+	if stmt.plan != nil {
+		s.operation = statementType(stmt.plan.node)
+		if table := tableNameOf(statementTable(stmt.plan.node)); table != nil {
+			attrs = append(attrs, attribute.String("db.sql.table", table.Name.O))
+		}
+	}
+	attrs = append(attrs, attribute.String("db.operation", s.operation))
+	ctx, s.span = tracer.Start(ctx, "bitable "+s.operation,
+		trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+	return ctx, s
+}
+
+// next count a row of the statement.
+func (s *statementSpan) next() {
+	if s != nil {
+		s.rows++
+	}
+}
+
+// end record the rows and err of the statement, only the first call ends the span.
+func (s *statementSpan) end(err error) {
+	if s == nil {
+		return
+	}
+	s.once.Do(func() {
+		switch s.operation {
+		case "INSERT", "UPDATE", "DELETE":
+			s.span.SetAttributes(attribute.Int64("db.rows_affected", s.rows))
+		default:
+			s.span.SetAttributes(attribute.Int64("db.response.returned_rows", s.rows))
+		}
+		if err != nil {
+			s.span.RecordError(err)
+			s.span.SetStatus(codes.Error, err.Error())
+		}
+		s.span.End()
+	})
+}
+
+// statementType return the type of a statement, like SELECT.
+func statementType(node ast.StmtNode) string {
+	switch node.(type) {
+	case *ast.SelectStmt, *ast.UnionStmt:
+		return "SELECT"
+	case *ast.InsertStmt:
+		return "INSERT"
+	case *ast.UpdateStmt:
+		return "UPDATE"
+	case *ast.DeleteStmt:
+		return "DELETE"
+	case *ast.ShowStmt:
+		return "SHOW"
+	case *ast.UseStmt:
+		return "USE"
+	case *ast.SetStmt:
+		return "SET"
+	case *ast.CreateTableStmt:
+		return "CREATE TABLE"
+	case *ast.CreateViewStmt:
+		return "CREATE VIEW"
+	case *alterViewStmt:
+		return "ALTER VIEW"
+	case *renameViewStmt:
+		return "RENAME VIEW"
+	case *ast.RenameTableStmt:
+		return "RENAME TABLE"
+	case *showViewsStmt:
+		return "SHOW"
+	case *ast.DropTableStmt:
+		return "DROP TABLE"
+	case *ast.AlterTableStmt:
+		return "ALTER TABLE"
+	case *ast.ExplainStmt:
+		return "EXPLAIN"
+	}
+	return "UNKNOWN"
+}
+
+// statementTable return the table clause of a statement on a table.
+func statementTable(node ast.StmtNode) interface{} {
+	switch s := node.(type) {
+	case *ast.SelectStmt:
+		if s.From != nil {
+			return s.From
+		}
+	case *ast.InsertStmt:
+		return s.Table
+	case *ast.UpdateStmt:
+		return s.TableRefs
+	case *ast.DeleteStmt:
+		return s.TableRefs
+	case *ast.CreateTableStmt:
+		return s.Table
+	case *ast.CreateViewStmt:
+		return s.ViewName
+	case *alterViewStmt:
+		return s.ViewName
+	case *renameViewStmt:
+		return s.OldTable
+	case *ast.RenameTableStmt:
+		return s.OldTable
+	case *ast.AlterTableStmt:
+		return s.Table
+	case *ast.DropTableStmt:
+		if len(s.Tables) == 1 {
+			return s.Tables[0]
+		}
+	case *ast.ShowStmt:
+		if s.Table != nil {
+			return s.Table
+		}
+	case *ast.ExplainStmt:
+		return statementTable(s.Stmt)
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package driver
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/luw2007/bitable-mysql-driver/internal/lark"
+)
+
+func TestTelemetry(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	telemetry, err := lark.NewTelemetry(tp, mp)
+	require.NoError(t, err)
+
+	table := newSalesTable()
+	table.addRecord("rec4", map[string]interface{}{"name": "durian", "amount": 5.0, "owner": "carol"})
+	conn := newMockConn(t, table, lark.WithTelemetry(telemetry))
+	conn.cfg.PageSize = 2
+	db := sql.OpenDB(mockConnector{conn: conn})
+	db.SetMaxOpenConns(1)
+
+	_, res := queryAll(t, db, "SELECT name FROM tbl WHERE owner <> ?", "")
+	assert.Len(t, res, 4)
+	result, err := db.Exec("UPDATE tbl SET owner = 'dave' WHERE name = 'apple'")
+	require.NoError(t, err)
+	affected, err := result.RowsAffected()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), affected)
+
+	statements := map[string]sdktrace.ReadOnlySpan{}
+	calls := map[string]int{}
+	for _, span := range exporter.GetSpans().Snapshots() {
+		attrs := map[string]interface{}{}
+		for _, attr := range span.Attributes() {
+			attrs[string(attr.Key)] = attr.Value.AsInterface()
+		}
+		if api, ok := attrs["bitable.api"]; ok {
+			calls[api.(string)]++
+			assert.Contains(t, attrs, "bitable.retries")
+			continue
+		}
+		statements[span.Name()] = span
+		assert.Equal(t, "bitable", attrs["db.system"])
+		assert.Equal(t, "tbl", attrs["db.sql.table"])
+	}
+	require.Contains(t, statements, "bitable SELECT")
+	require.Contains(t, statements, "bitable UPDATE")
+	assert.Contains(t, statements["bitable SELECT"].Attributes(), attribute.Int64("db.response.returned_rows", 4))
+	assert.Contains(t, statements["bitable UPDATE"].Attributes(), attribute.Int64("db.rows_affected", 1))
+	assert.Contains(t, statements["bitable UPDATE"].Attributes(),
+		attribute.String("db.statement", "update tbl set owner = ? where name = ?"), "the values are not traced")
+	assert.Equal(t, 3, calls["GetBitableRecordList"], "two pages of the SELECT and one of the UPDATE")
+	assert.Equal(t, 1, calls["BatchUpdateBitableRecord"])
+
+	// the api calls of a statement are its children
+	for _, span := range exporter.GetSpans().Snapshots() {
+		if span.Name() == "bitable BatchUpdateBitableRecord" {
+			assert.Equal(t, statements["bitable UPDATE"].SpanContext().SpanID(), span.Parent().SpanID())
+		}
+	}
+
+	var metrics metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(context.Background(), &metrics))
+	pages := int64(0)
+	names := map[string]bool{}
+	for _, scope := range metrics.ScopeMetrics {
+		for _, m := range scope.Metrics {
+			names[m.Name] = true
+			if m.Name == "bitable.client.pages" {
+				pages += sumOf(m.Data, "GetBitableRecordList")
+			}
+		}
+	}
+	assert.True(t, names["bitable.client.request.duration"])
+	assert.Equal(t, int64(3), pages)
+}
+
+// sumOf return the sum of the int64 points of api, the generic types of metricdata are read by reflection.
+func sumOf(data metricdata.Aggregation, api string) int64 {
+	points := reflect.ValueOf(data).FieldByName("DataPoints")
+	sum := int64(0)
+	for i := 0; i < points.Len(); i++ {
+		point := points.Index(i)
+		attrs := point.FieldByName("Attributes").Interface().(attribute.Set)
+		if v, _ := attrs.Value("bitable.api"); v.AsString() == api {
+			sum += point.FieldByName("Value").Int()
+		}
+	}
+	return sum
+}
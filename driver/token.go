@@ -0,0 +1,63 @@
+package driver
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/luw2007/bitable-mysql-driver/internal/lark"
+)
+
+// userTokenRefreshAhead refresh the user_access_token before it expires.
+const userTokenRefreshAhead = time.Minute
+
+// userTokenSource refresh the user_access_token of the DSN, shared by the Conns of a connector
+// because a refresh token can only be used once.
+type userTokenSource struct {
+	client    *lark.BiTable
+	onRefresh func(ctx context.Context, refreshToken string) error
+
+	mu           sync.Mutex
+	accessToken  string
+	refreshToken string
+	expireAt     time.Time
+}
+
+func newUserTokenSource(client *lark.BiTable, cfg *Config) *userTokenSource {
+	if cfg.UserRefreshToken == "" {
+		return nil
+	}
+	return &userTokenSource{client: client, onRefresh: cfg.OnUserRefreshToken, refreshToken: cfg.UserRefreshToken}
+}
+
+// token return a valid user_access_token, the token of unknown expiration is refreshed first.
+func (s *userTokenSource) token(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.accessToken != "" && time.Now().Add(userTokenRefreshAhead).Before(s.expireAt) {
+		return s.accessToken, nil
+	}
+	t, err := s.client.RefreshUserToken(ctx, s.refreshToken)
+	if err != nil {
+		return "", err
+	}
+	s.accessToken, s.expireAt = t.AccessToken, time.Now().Add(t.ExpiresIn)
+	if t.RefreshToken != "" && t.RefreshToken != s.refreshToken {
+		s.refreshToken = t.RefreshToken
+		if s.onRefresh != nil {
+			// the new access token works, the request goes on
+			if err := s.onRefresh(ctx, t.RefreshToken); err != nil {
+				s.client.Logger().Log(ctx, LevelError, "[bitable driver] save the rotated refresh token error",
+					"error", err)
+			}
+		}
+	}
+	return s.accessToken, nil
+}
+
+// reset drop the user_access_token rejected by the api, the next request refresh it.
+func (s *userTokenSource) reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.accessToken = ""
+}
@@ -0,0 +1,541 @@
+package driver
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pingcap/parser/ast"
+	"github.com/pingcap/parser/opcode"
+	"github.com/pingcap/parser/test_driver"
+
+	"github.com/luw2007/bitable-mysql-driver/internal/lark"
+)
+
+// viewIDPrefix is the prefix of the view ids, other view names are looked up in the views of the table.
+const viewIDPrefix = "vew"
+
+// alterViewStmt is `ALTER VIEW [type.]name AS SELECT ...`, parsed as CREATE OR REPLACE VIEW.
+type alterViewStmt struct {
+	*ast.CreateViewStmt
+}
+
+// renameViewStmt is `RENAME VIEW table.view TO name`, parsed as RENAME TABLE.
+type renameViewStmt struct {
+	*ast.RenameTableStmt
+}
+
+// showViewsStmt is `SHOW [FULL] VIEWS FROM table`, parsed as SHOW TABLES.
+type showViewsStmt struct {
+	*ast.ShowStmt
+}
+
+// viewRewrite is a view statement the parser doesn't support, the keywords are parsed as text.
+type viewRewrite struct {
+	keywords []string
+	text     string
+	wrap     func(ast.StmtNode) ast.StmtNode
+}
+
+var viewRewrites = []viewRewrite{
+	{[]string{"alter", "view"}, "CREATE OR REPLACE VIEW", func(node ast.StmtNode) ast.StmtNode {
+		if s, ok := node.(*ast.CreateViewStmt); ok {
+			return &alterViewStmt{s}
+		}
+		return node
+	}},
+	{[]string{"rename", "view"}, "RENAME TABLE", func(node ast.StmtNode) ast.StmtNode {
+		if s, ok := node.(*ast.RenameTableStmt); ok {
+			return &renameViewStmt{s}
+		}
+		return node
+	}},
+	{[]string{"show", "full", "views"}, "SHOW FULL TABLES", wrapShowViews},
+	{[]string{"show", "views"}, "SHOW TABLES", wrapShowViews},
+}
+
+func wrapShowViews(node ast.StmtNode) ast.StmtNode {
+	if s, ok := node.(*ast.ShowStmt); ok {
+		return &showViewsStmt{s}
+	}
+	return node
+}
+
+// rewriteView rewrite the keywords of a view statement the parser doesn't support, wrap return the statement
+// of the parsed query, nil when query is not rewritten. delta is the length added before the param markers.
+func rewriteView(query string) (main string, wrap func(ast.StmtNode) ast.StmtNode, delta int) {
+	for _, rw := range viewRewrites {
+		l := &withLexer{query: query}
+		start := -1
+		matched := true
+		for _, keyword := range rw.keywords {
+			l.skipSpace()
+			if start < 0 {
+				start = l.pos
+			}
+			if !strings.EqualFold(l.word(), keyword) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return query[:start] + rw.text + query[l.pos:], rw.wrap, len(rw.text) - (l.pos - start)
+		}
+	}
+	return query, nil, 0
+}
+
+// paramShifter move the param markers back by delta, so the offsets match the query before rewriting.
+type paramShifter struct {
+	delta int
+}
+
+func (s paramShifter) Enter(n ast.Node) (ast.Node, bool) {
+	if v, ok := n.(*test_driver.ParamMarkerExpr); ok {
+		v.Offset -= s.delta
+	}
+	return n, false
+}
+
+func (s paramShifter) Leave(n ast.Node) (ast.Node, bool) {
+	return n, true
+}
+
+// viewOperators are the operators of the view filter for the comparisons.
+var viewOperators = map[opcode.Op]string{
+	opcode.EQ: "is",
+	opcode.NE: "isNot",
+	opcode.GT: "isGreater",
+	opcode.GE: "isGreaterEqual",
+	opcode.LT: "isLess",
+	opcode.LE: "isLessEqual",
+}
+
+// reversedOps are the comparisons with the value on the left.
+var reversedOps = map[opcode.Op]opcode.Op{
+	opcode.EQ: opcode.EQ,
+	opcode.NE: opcode.NE,
+	opcode.GT: opcode.LT,
+	opcode.GE: opcode.LE,
+	opcode.LT: opcode.GT,
+	opcode.LE: opcode.GE,
+}
+
+// viewProperty build the property of a view from the SELECT of CREATE VIEW, the fields not selected are hidden,
+// and WHERE is the filter of the view, comparisons, `LIKE '%text%'` and `IS [NOT] NULL` joined by AND or by OR.
+// The open api can't save the sort of a view, so ORDER BY is an error.
+func (stmt *bitableStatement) viewProperty(r *rows, s *ast.SelectStmt) (string, *lark.ViewProperty, error) {
+	table, _, err := stmt.getTableView(r.ctx, s.From)
+	if err != nil {
+		return "", nil, err
+	}
+	switch {
+	case s.OrderBy != nil:
+		return "", nil, errors.New("the open api can't save the sort of a view, sort it in bitable")
+	case s.Distinct, s.GroupBy != nil, s.Having != nil, s.Limit != nil, len(s.WindowSpecs) > 0:
+		return "", nil, errors.New("a view only has the selected fields and a filter")
+	}
+	fields, order, err := stmt.loadFields(r, table)
+	if err != nil {
+		return "", nil, err
+	}
+	property := &lark.ViewProperty{HiddenFields: []string{}}
+	shown := make(map[string]bool, len(order))
+	for _, field := range s.Fields.Fields {
+		if field.WildCard != nil {
+			for _, name := range order {
+				shown[name] = true
+			}
+			continue
+		}
+		column, ok := field.Expr.(*ast.ColumnNameExpr)
+		if !ok || (field.AsName.O != "" && field.AsName.O != column.Name.Name.O) {
+			return "", nil, errors.New("a view only shows the fields, expressions and aliases are not supported")
+		}
+		name := column.Name.Name.O
+		if _, ok := fields[name]; !ok && name != FieldKeyRecordID {
+			return "", nil, unknownColumn(name, "field list")
+		}
+		shown[name] = true
+	}
+	for _, name := range order {
+		if !shown[name] {
+			property.HiddenFields = append(property.HiddenFields, fields[name].FieldID)
+		}
+	}
+	if s.Where != nil {
+		if property.FilterInfo, err = stmt.viewFilter(s.Where, fields); err != nil {
+			return "", nil, err
+		}
+	}
+	return table, property, nil
+}
+
+// viewFilter build the filter of a view, the conditions are joined by AND or by OR, not both.
+func (stmt *bitableStatement) viewFilter(where ast.ExprNode, fields map[string]lark.Field) (*lark.ViewFilterInfo, error) {
+	info := &lark.ViewFilterInfo{Conjunction: "and"}
+	conds := splitConjuncts(where)
+	if len(conds) == 1 {
+		if disjuncts := splitDisjuncts(where); len(disjuncts) > 1 {
+			info.Conjunction, conds = "or", disjuncts
+		}
+	}
+	for _, cond := range conds {
+		condition, err := stmt.viewCondition(cond, fields)
+		if err != nil {
+			return nil, err
+		}
+		info.Conditions = append(info.Conditions, condition)
+	}
+	return info, nil
+}
+
+// splitDisjuncts split the conditions joined by OR.
+func splitDisjuncts(expr ast.ExprNode) []ast.ExprNode {
+	switch e := expr.(type) {
+	case *ast.BinaryOperationExpr:
+		if e.Op == opcode.LogicOr {
+			return append(splitDisjuncts(e.L), splitDisjuncts(e.R)...)
+		}
+	case *ast.ParenthesesExpr:
+		if b, ok := e.Expr.(*ast.BinaryOperationExpr); ok && b.Op == opcode.LogicOr {
+			return splitDisjuncts(b)
+		}
+	}
+	return []ast.ExprNode{expr}
+}
+
+// viewCondition build a condition of the view filter from a comparison of a field and a value.
+func (stmt *bitableStatement) viewCondition(expr ast.ExprNode, fields map[string]lark.Field) (*lark.ViewCondition, error) {
+	unsupported := fmt.Errorf("the condition `%s` is not supported by a view filter", restore(expr))
+	var column, value ast.ExprNode
+	var operator string
+	switch e := expr.(type) {
+	case *ast.ParenthesesExpr:
+		return stmt.viewCondition(e.Expr, fields)
+	case *ast.BinaryOperationExpr:
+		op, ok := viewOperators[e.Op]
+		if !ok {
+			return nil, unsupported
+		}
+		column, value, operator = e.L, e.R, op
+		if _, ok := e.L.(*ast.ColumnNameExpr); !ok {
+			column, value, operator = e.R, e.L, viewOperators[reversedOps[e.Op]]
+		}
+	case *ast.PatternLikeExpr:
+		column, value, operator = e.Expr, e.Pattern, "contains"
+		if e.Not {
+			operator = "doesNotContain"
+		}
+	case *ast.IsNullExpr:
+		column, operator = e.Expr, "isEmpty"
+		if e.Not {
+			operator = "isNotEmpty"
+		}
+	default:
+		return nil, unsupported
+	}
+	name, ok := column.(*ast.ColumnNameExpr)
+	if !ok {
+		return nil, unsupported
+	}
+	field, ok := fields[name.Name.Name.O]
+	if !ok {
+		return nil, unknownColumn(name.Name.Name.O, "where clause")
+	}
+	condition := &lark.ViewCondition{FieldID: field.FieldID, Operator: operator}
+	if value == nil {
+		return condition, nil
+	}
+	switch value.(type) {
+	case *test_driver.ValueExpr, *test_driver.ParamMarkerExpr:
+	default:
+		return nil, unsupported
+	}
+	v, err := stmt.eval(value, nil)
+	if err != nil {
+		return nil, err
+	}
+	if v == nil {
+		return nil, errors.New("NULL in a view filter matches nothing, use IS NULL")
+	}
+	text := toString(v)
+	if t, ok := v.(time.Time); ok {
+		text = strconv.FormatInt(t.UnixNano()/1e6, 10)
+	}
+	if operator == "contains" || operator == "doesNotContain" {
+		// only `%text%` is a contains
+		inner := strings.TrimSuffix(strings.TrimPrefix(text, "%"), "%")
+		if len(text) < 2 || inner != text[1:len(text)-1] || strings.ContainsAny(inner, "%_") {
+			return nil, unsupported
+		}
+		text = inner
+	}
+	b, err := json.Marshal([]string{text})
+	if err != nil {
+		return nil, err
+	}
+	condition.Value = string(b)
+	return condition, nil
+}
+
+// viewSQLOperators are the comparisons of the view filter operators.
+var viewSQLOperators = map[string]string{
+	"is":             "=",
+	"isNot":          "!=",
+	"isGreater":      ">",
+	"isGreaterEqual": ">=",
+	"isLess":         "<",
+	"isLessEqual":    "<=",
+}
+
+// viewSQL format the filter of a view as a WHERE condition, the fields are named by names.
+func viewSQL(info *lark.ViewFilterInfo, names map[string]string) string {
+	if info == nil || len(info.Conditions) == 0 {
+		return ""
+	}
+	quote := func(s string) string {
+		return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+	}
+	conds := make([]string, 0, len(info.Conditions))
+	for _, condition := range info.Conditions {
+		name, ok := names[condition.FieldID]
+		if !ok {
+			name = condition.FieldID
+		}
+		name = "`" + strings.ReplaceAll(name, "`", "``") + "`"
+		var values []string
+		if err := json.Unmarshal([]byte(condition.Value), &values); err != nil {
+			values = []string{condition.Value}
+		}
+		value := ""
+		if len(values) > 0 {
+			value = values[0]
+		}
+		switch condition.Operator {
+		case "isEmpty":
+			conds = append(conds, name+" IS NULL")
+		case "isNotEmpty":
+			conds = append(conds, name+" IS NOT NULL")
+		case "contains":
+			conds = append(conds, name+" LIKE "+quote("%"+value+"%"))
+		case "doesNotContain":
+			conds = append(conds, name+" NOT LIKE "+quote("%"+value+"%"))
+		default:
+			op, ok := viewSQLOperators[condition.Operator]
+			if !ok {
+				op = condition.Operator
+			}
+			conds = append(conds, name+" "+op+" "+quote(value))
+		}
+	}
+	return strings.Join(conds, " "+strings.ToUpper(info.Conjunction)+" ")
+}
+
+// findView find a view of table by id or name, nil when there is no such view.
+func (stmt *bitableStatement) findView(r *rows, table, view string) (*lark.View, error) {
+	pageToken := ""
+	for i := 0; i < maxLoopTimes; i++ {
+		page, err := stmt.conn.ListViews(r.ctx, r.appToken, table, pageToken, DefaultPageSize)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range page.Items {
+			if v := item.(*lark.View); v.ViewID == view || v.ViewName == view {
+				return v, nil
+			}
+		}
+		if !page.HasMore {
+			break
+		}
+		pageToken = page.PageToken
+	}
+	return nil, nil
+}
+
+// resolveView return the view of table by id or name.
+func (stmt *bitableStatement) resolveView(r *rows, table, view string) (*lark.View, error) {
+	v, err := stmt.findView(r, table, view)
+	if err != nil {
+		return nil, err
+	}
+	if v == nil {
+		return nil, &Error{Message: fmt.Sprintf("unknown view '%s' of table '%s'", view, table), Err: ErrViewNotFound}
+	}
+	return v, nil
+}
+
+// viewID return the id of a view by id or name, the ids are used as is.
+func (stmt *bitableStatement) viewID(r *rows, table, view string) (string, error) {
+	if strings.HasPrefix(view, viewIDPrefix) {
+		return view, nil
+	}
+	v, err := stmt.resolveView(r, table, view)
+	if err != nil {
+		return "", err
+	}
+	return v.ViewID, nil
+}
+
+// viewFields return the id of view and the fields it shows, the hidden fields are removed.
+func (stmt *bitableStatement) viewFields(r *rows, table, view string, fields map[string]lark.Field, order []string) (
+	string, map[string]lark.Field, []string, error) {
+	viewID, err := stmt.viewID(r, table, view)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	v, err := stmt.conn.GetView(r.ctx, r.appToken, table, viewID)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	if v == nil || v.Property == nil || len(v.Property.HiddenFields) == 0 {
+		return viewID, fields, order, nil
+	}
+	hidden := make(map[string]bool, len(v.Property.HiddenFields))
+	for _, id := range v.Property.HiddenFields {
+		hidden[id] = true
+	}
+	// the fields are cached by the plan, copy them
+	shown := make(map[string]lark.Field, len(fields))
+	shownOrder := make([]string, 0, len(order))
+	for _, name := range order {
+		if !hidden[fields[name].FieldID] {
+			shown[name] = fields[name]
+			shownOrder = append(shownOrder, name)
+		}
+	}
+	return viewID, shown, shownOrder, nil
+}
+
+// viewNameOf return the type and name of `[type.]name`, the type is empty without a schema.
+func (stmt *bitableStatement) viewNameOf(r *rows, name *ast.TableName) (viewType, viewName string, err error) {
+	viewType, viewName, err = stmt.getTableView(r.ctx, name)
+	if err != nil {
+		return "", "", err
+	}
+	if viewName == "" {
+		viewType, viewName = "", viewType
+	}
+	return strings.ToLower(viewType), viewName, nil
+}
+
+func (stmt *bitableStatement) createViewStmt(r *rows, s *ast.CreateViewStmt) (driver.Rows, error) {
+	viewType, viewName, err := stmt.viewNameOf(r, s.ViewName)
+	if err != nil {
+		return nil, fmt.Errorf("[bitable driver] %w", err)
+	}
+	sel, ok := s.Select.(*ast.SelectStmt)
+	if !ok {
+		return nil, errors.New("[bitable driver] a view is a SELECT of a table")
+	}
+	table, property, err := stmt.viewProperty(r, sel)
+	if err != nil {
+		return nil, fmt.Errorf("[bitable driver] %w", err)
+	}
+	if s.OrReplace {
+		old, err := stmt.findView(r, table, viewName)
+		if err != nil {
+			return nil, fmt.Errorf("[bitable driver] %w", err)
+		}
+		if old != nil {
+			return stmt.replaceView(r, table, old, viewType, property)
+		}
+	}
+	if viewType == "" {
+		viewType = string(ViewTypeGrid)
+	}
+	newView, err := stmt.conn.CreateView(r.ctx, r.appToken, table, viewName, viewType)
+	if err != nil {
+		return nil, fmt.Errorf("[bitable driver] %w", err)
+	}
+	if property.FilterInfo != nil || len(property.HiddenFields) > 0 {
+		if _, err := stmt.conn.UpdateView(r.ctx, r.appToken, table, newView.ViewID, "", property); err != nil {
+			// drop the view without its filter, the statement has no effect
+			_ = stmt.conn.DropView(r.ctx, r.appToken, table, newView.ViewID)
+			return nil, fmt.Errorf("[bitable driver] %w", err)
+		}
+	}
+	return newRowsFactory(r.Clone(viewColumns, []interface{}{[]interface{}{newView.ViewID, newView.ViewName, newView.ViewType}})), nil
+}
+
+// alterView replace the fields and filter of a view, the type of a view can't change.
+func (stmt *bitableStatement) alterView(r *rows, s *alterViewStmt) (driver.Rows, error) {
+	viewType, viewName, err := stmt.viewNameOf(r, s.ViewName)
+	if err != nil {
+		return nil, fmt.Errorf("[bitable driver] %w", err)
+	}
+	sel, ok := s.Select.(*ast.SelectStmt)
+	if !ok {
+		return nil, errors.New("[bitable driver] a view is a SELECT of a table")
+	}
+	table, property, err := stmt.viewProperty(r, sel)
+	if err != nil {
+		return nil, fmt.Errorf("[bitable driver] %w", err)
+	}
+	old, err := stmt.resolveView(r, table, viewName)
+	if err != nil {
+		return nil, fmt.Errorf("[bitable driver] %w", err)
+	}
+	return stmt.replaceView(r, table, old, viewType, property)
+}
+
+// replaceView replace the property of view, a view without WHERE has no filter.
+func (stmt *bitableStatement) replaceView(r *rows, table string, view *lark.View, viewType string,
+	property *lark.ViewProperty) (driver.Rows, error) {
+	if viewType != "" && !strings.EqualFold(viewType, view.ViewType) {
+		return nil, fmt.Errorf("[bitable driver] can't change the type of view '%s' from %s to %s",
+			view.ViewName, view.ViewType, viewType)
+	}
+	if property.FilterInfo == nil {
+		property.FilterInfo = &lark.ViewFilterInfo{Conjunction: "and", Conditions: []*lark.ViewCondition{}}
+	}
+	if _, err := stmt.conn.UpdateView(r.ctx, r.appToken, table, view.ViewID, "", property); err != nil {
+		return nil, fmt.Errorf("[bitable driver] %w", err)
+	}
+	return newRowsFactory(r.Clone(viewColumns, []interface{}{[]interface{}{view.ViewID, view.ViewName, view.ViewType}})), nil
+}
+
+// renameView rename the views of `RENAME VIEW table.view TO name`, the new name may have the same table.
+func (stmt *bitableStatement) renameView(r *rows, s *renameViewStmt) (driver.Rows, error) {
+	for _, t := range s.TableToTables {
+		table, view, err := stmt.getTableView(r.ctx, t.OldTable)
+		if err != nil {
+			return nil, fmt.Errorf("[bitable driver] %w", err)
+		}
+		if view == "" {
+			return nil, fmt.Errorf("[bitable driver] the view of RENAME VIEW is table.view, got '%s'", table)
+		}
+		newName := t.NewTable.Name.O
+		if schema := t.NewTable.Schema.O; schema != "" && schema != table {
+			return nil, fmt.Errorf("[bitable driver] can't move view '%s' to table '%s'", view, schema)
+		}
+		viewID, err := stmt.viewID(r, table, view)
+		if err != nil {
+			return nil, fmt.Errorf("[bitable driver] %w", err)
+		}
+		if _, err := stmt.conn.UpdateView(r.ctx, r.appToken, table, viewID, newName, nil); err != nil {
+			return nil, fmt.Errorf("[bitable driver] %w", err)
+		}
+	}
+	return nil, nil
+}
+
+// showViews list the views of a table, FULL has the filter and hidden fields of every view.
+func (stmt *bitableStatement) showViews(r *rows, s *showViewsStmt) (driver.Rows, error) {
+	table := s.DBName
+	if table == "" {
+		return nil, errors.New("[bitable driver] SHOW VIEWS needs FROM table")
+	}
+	if !s.Full {
+		return newViewRows(r, table, nil), nil
+	}
+	fields, _, err := stmt.loadFields(r, table)
+	if err != nil {
+		return nil, err
+	}
+	return newViewRows(r, table, fields), nil
+}
@@ -0,0 +1,110 @@
+package driver
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/luw2007/bitable-mysql-driver/internal/lark"
+)
+
+func TestViewDDL(t *testing.T) {
+	table := newSalesTable()
+	db := newMockDB(t, table)
+
+	_, res := queryAll(t, db, "CREATE VIEW grid.`alice fruits` AS SELECT name, amount FROM tbl WHERE owner = ?", "alice")
+	assert.Equal(t, [][]interface{}{{"vewnew1", "alice fruits", "grid"}}, res)
+	assert.Equal(t, &lark.ViewProperty{
+		FilterInfo: &lark.ViewFilterInfo{Conjunction: "and", Conditions: []*lark.ViewCondition{
+			{FieldID: "fld3", Operator: "is", Value: `["alice"]`},
+		}},
+		HiddenFields: []string{"fld3"},
+	}, table.views[0].Property)
+
+	t.Run("a view shows its records and fields", func(t *testing.T) {
+		columns, res := queryAll(t, db, "SELECT * FROM tbl.`alice fruits`")
+		assert.Equal(t, []string{"record_id", "name", "amount"}, columns)
+		assert.Equal(t, [][]interface{}{{"rec1", "apple", 3.0}, {"rec3", "cherry", 2.0}}, res)
+
+		_, res = queryAll(t, db, "SELECT name FROM tbl.vewnew1 WHERE name != 'cherry' ORDER BY amount")
+		assert.Equal(t, [][]interface{}{{"rec1", "apple"}}, res)
+		requests := table.listRequests()
+		last := requests[len(requests)-1]
+		assert.Equal(t, "vewnew1", *last.ViewID)
+		assert.Empty(t, *last.Filter, "the list api ignores the view with a filter")
+		assert.Empty(t, *last.Sort)
+
+		_, err := db.Query("SELECT owner FROM tbl.vewnew1")
+		assert.ErrorIs(t, err, ErrFieldNotFound)
+		_, err = db.Query("SELECT * FROM tbl.missing")
+		assert.ErrorIs(t, err, ErrViewNotFound)
+	})
+
+	t.Run("alter and show", func(t *testing.T) {
+		_, err := db.Exec("ALTER VIEW `alice fruits` AS SELECT * FROM tbl WHERE owner = ? OR amount >= 3", "bob")
+		require.NoError(t, err)
+		_, err = db.Exec("RENAME VIEW tbl.`alice fruits` TO fruits")
+		require.NoError(t, err)
+		columns, res := queryAll(t, db, "SHOW FULL VIEWS FROM tbl")
+		assert.Equal(t, []string{"id", "name", "type", "filter", "hidden_fields"}, columns)
+		assert.Equal(t, [][]interface{}{{"vewnew1", "fruits", "grid", "`owner` = 'bob' OR `amount` >= '3'", nil}}, res)
+		_, res = queryAll(t, db, "SHOW VIEWS FROM tbl")
+		assert.Equal(t, [][]interface{}{{"vewnew1", "fruits", "grid"}}, res)
+	})
+
+	t.Run("unsupported views", func(t *testing.T) {
+		_, err := db.Exec("CREATE VIEW grid.sorted AS SELECT * FROM tbl ORDER BY amount")
+		assert.EqualError(t, err, "[bitable driver] the open api can't save the sort of a view, sort it in bitable")
+		_, err = db.Exec("CREATE VIEW grid.mixed AS SELECT * FROM tbl WHERE owner = 'bob' AND (amount > 1 OR name = 'x')")
+		assert.Error(t, err)
+		_, err = db.Exec("CREATE OR REPLACE VIEW kanban.fruits AS SELECT * FROM tbl")
+		assert.EqualError(t, err, "[bitable driver] can't change the type of view 'fruits' from grid to kanban")
+		assert.Len(t, table.views, 1, "the failed views are not created")
+	})
+
+	_, err := db.Exec("DROP VIEW tbl.fruits")
+	require.NoError(t, err)
+	assert.Empty(t, table.views)
+}
+
+func TestViewDML(t *testing.T) {
+	newTable := func() *mockTable {
+		table := newSalesTable()
+		table.views = []*lark.View{{ViewID: "vew1", ViewName: "mine", ViewType: "grid", Property: &lark.ViewProperty{
+			FilterInfo: &lark.ViewFilterInfo{Conjunction: "and", Conditions: []*lark.ViewCondition{
+				{FieldID: "fld3", Operator: "is", Value: `["alice"]`},
+			}},
+		}}}
+		return table
+	}
+
+	t.Run("delete", func(t *testing.T) {
+		table := newTable()
+		res, err := newMockDB(t, table).Exec("DELETE FROM tbl.mine WHERE amount < 3")
+		require.NoError(t, err)
+		n, _ := res.RowsAffected()
+		assert.Equal(t, int64(1), n)
+		assert.Equal(t, []string{"rec3"}, table.deletedRecords(), "rec2 is hidden by the view")
+		for _, req := range table.listRequests() {
+			assert.Equal(t, "vew1", *req.ViewID)
+			assert.Empty(t, *req.Filter, "the list api ignores the view with a filter")
+		}
+	})
+
+	t.Run("update", func(t *testing.T) {
+		table := newTable()
+		res, err := newMockDB(t, table).Exec("UPDATE tbl.vew1 SET amount = 0 WHERE name != 'apple'")
+		require.NoError(t, err)
+		n, _ := res.RowsAffected()
+		assert.Equal(t, int64(1), n)
+		assert.Equal(t, []map[string]interface{}{{"amount": int64(0)}}, table.writeFields())
+	})
+
+	t.Run("by record id", func(t *testing.T) {
+		table := newTable()
+		_, err := newMockDB(t, table).Exec("DELETE FROM tbl.mine WHERE record_id IN ('rec1', 'rec2')")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"rec1"}, table.deletedRecords())
+	})
+}
@@ -0,0 +1,274 @@
+package driver
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/pingcap/parser/ast"
+)
+
+// commonTableText a common table expression of the WITH clause, the parser doesn't support WITH.
+// body is the query with everything except the CTE blanked, so the offsets of param markers don't change.
+type commonTableText struct {
+	name    string
+	columns []string
+	body    string
+}
+
+// splitWith split the WITH clause from query, main is the query with the WITH clause blanked.
+func splitWith(query string) (main string, ctes []commonTableText, err error) {
+	l := &withLexer{query: query}
+	l.skipSpace()
+	start := l.pos
+	if !strings.EqualFold(l.word(), "with") {
+		return query, nil, nil
+	}
+	l.skipSpace()
+	save := l.pos
+	if strings.EqualFold(l.word(), "recursive") {
+		return "", nil, errors.New("recursive common table expression is not supported")
+	}
+	l.pos = save
+	for {
+		l.skipSpace()
+		cte := commonTableText{name: l.identifier()}
+		if cte.name == "" {
+			return "", nil, fmt.Errorf("syntax error near offset %d of WITH", l.pos)
+		}
+		l.skipSpace()
+		if l.peek() == '(' {
+			l.pos++
+			for {
+				l.skipSpace()
+				column := l.identifier()
+				if column == "" {
+					return "", nil, fmt.Errorf("syntax error in the column list of '%s'", cte.name)
+				}
+				cte.columns = append(cte.columns, column)
+				l.skipSpace()
+				if l.peek() == ',' {
+					l.pos++
+					continue
+				}
+				if l.peek() != ')' {
+					return "", nil, fmt.Errorf("syntax error in the column list of '%s'", cte.name)
+				}
+				l.pos++
+				break
+			}
+			l.skipSpace()
+		}
+		if !strings.EqualFold(l.word(), "as") {
+			return "", nil, fmt.Errorf("syntax error, expect AS after '%s'", cte.name)
+		}
+		l.skipSpace()
+		if l.peek() != '(' {
+			return "", nil, fmt.Errorf("syntax error, expect ( after '%s' AS", cte.name)
+		}
+		open := l.pos
+		end, err := l.closeParen()
+		if err != nil {
+			return "", nil, err
+		}
+		cte.body = blankExcept(query, open+1, end)
+		ctes = append(ctes, cte)
+		l.skipSpace()
+		if l.peek() != ',' {
+			break
+		}
+		l.pos++
+	}
+	main = query[:start] + blankExcept(query[start:l.pos], 0, 0) + query[l.pos:]
+	return main, ctes, nil
+}
+
+// blankExcept replace the bytes of query outside [start, end) by spaces, but keep the lines.
+func blankExcept(query string, start, end int) string {
+	b := []byte(query)
+	for i := range b {
+		if (i < start || i >= end) && b[i] != '\n' {
+			b[i] = ' '
+		}
+	}
+	return string(b)
+}
+
+type withLexer struct {
+	query string
+	pos   int
+}
+
+func (l *withLexer) peek() byte {
+	if l.pos < len(l.query) {
+		return l.query[l.pos]
+	}
+	return 0
+}
+
+// skipSpace skip spaces and comments.
+func (l *withLexer) skipSpace() {
+	for l.pos < len(l.query) {
+		switch {
+		case strings.ContainsRune(" \t\r\n", rune(l.query[l.pos])):
+			l.pos++
+		case strings.HasPrefix(l.query[l.pos:], "#"), strings.HasPrefix(l.query[l.pos:], "-- "):
+			if i := strings.IndexByte(l.query[l.pos:], '\n'); i >= 0 {
+				l.pos += i + 1
+			} else {
+				l.pos = len(l.query)
+			}
+		case strings.HasPrefix(l.query[l.pos:], "/*"):
+			if i := strings.Index(l.query[l.pos+2:], "*/"); i >= 0 {
+				l.pos += i + 4
+			} else {
+				l.pos = len(l.query)
+			}
+		default:
+			return
+		}
+	}
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || c == '$' || c >= 0x80 || ('0' <= c && c <= '9') || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
+}
+
+func (l *withLexer) word() string {
+	start := l.pos
+	for l.pos < len(l.query) && isWordByte(l.query[l.pos]) {
+		l.pos++
+	}
+	return l.query[start:l.pos]
+}
+
+// identifier read a bare or `quoted` identifier.
+func (l *withLexer) identifier() string {
+	if l.peek() != '`' {
+		return l.word()
+	}
+	var b strings.Builder
+	for l.pos++; l.pos < len(l.query); l.pos++ {
+		c := l.query[l.pos]
+		if c == '`' {
+			if strings.HasPrefix(l.query[l.pos:], "``") {
+				b.WriteByte('`')
+				l.pos++
+				continue
+			}
+			l.pos++
+			return b.String()
+		}
+		b.WriteByte(c)
+	}
+	return ""
+}
+
+// closeParen find the parenthesis matching the one at pos, and move after it.
+func (l *withLexer) closeParen() (int, error) {
+	depth := 0
+	for l.pos < len(l.query) {
+		c := l.query[l.pos]
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				end := l.pos
+				l.pos++
+				return end, nil
+			}
+		case '\'', '"', '`':
+			l.skipQuoted(c)
+			continue
+		case '#', '-', '/':
+			before := l.pos
+			l.skipSpace()
+			if l.pos != before {
+				continue
+			}
+		}
+		l.pos++
+	}
+	return 0, errors.New("syntax error, unclosed ( in WITH")
+}
+
+func (l *withLexer) skipQuoted(quote byte) {
+	for l.pos++; l.pos < len(l.query); l.pos++ {
+		switch l.query[l.pos] {
+		case '\\':
+			if quote != '`' {
+				l.pos++
+			}
+		case quote:
+			if l.pos+1 < len(l.query) && l.query[l.pos+1] == quote {
+				l.pos++
+				continue
+			}
+			l.pos++
+			return
+		}
+	}
+}
+
+// bindCommonTables parse the CTEs, and replace the references in the CTEs after them and in node by derived tables.
+// queries are the parsed CTEs, include the ones not referenced.
+func (stmt *bitableStatement) bindCommonTables(ctes []commonTableText, node ast.StmtNode) ([]ast.ResultSetNode, error) {
+	binder := &commonTableBinder{tables: make(map[string]ast.ResultSetNode, len(ctes))}
+	queries := make([]ast.ResultSetNode, 0, len(ctes))
+	for _, cte := range ctes {
+		nodes, _, err := stmt.conn.parser.Parse(cte.body, "", "")
+		if err != nil {
+			return nil, fmt.Errorf("parser WITH %s: %w", cte.name, err)
+		}
+		var query ast.ResultSetNode
+		if len(nodes) == 1 {
+			query, _ = nodes[0].(ast.ResultSetNode)
+		}
+		switch query.(type) {
+		case *ast.SelectStmt, *ast.UnionStmt:
+		default:
+			return nil, fmt.Errorf("common table expression '%s' must be a SELECT", cte.name)
+		}
+		query.Accept(binder)
+		if len(cte.columns) > 0 {
+			if stmt.derivedColumns == nil {
+				stmt.derivedColumns = make(map[ast.ResultSetNode][]string)
+			}
+			stmt.derivedColumns[query] = cte.columns
+		}
+		binder.tables[strings.ToLower(cte.name)] = query
+		queries = append(queries, query)
+	}
+	switch node.(type) {
+	case *ast.SelectStmt, *ast.UnionStmt:
+	default:
+		return nil, errors.New("WITH is only supported by SELECT")
+	}
+	node.Accept(binder)
+	return queries, nil
+}
+
+// commonTableBinder replace the table names referring to a CTE by the query of the CTE.
+type commonTableBinder struct {
+	tables map[string]ast.ResultSetNode
+}
+
+func (b *commonTableBinder) Enter(n ast.Node) (ast.Node, bool) {
+	return n, false
+}
+
+func (b *commonTableBinder) Leave(n ast.Node) (ast.Node, bool) {
+	if ts, ok := n.(*ast.TableSource); ok {
+		if name, ok := ts.Source.(*ast.TableName); ok && name.Schema.O == "" {
+			if query, ok := b.tables[name.Name.L]; ok {
+				ts.Source = query
+				if ts.AsName.O == "" {
+					ts.AsName = name.Name
+				}
+			}
+		}
+	}
+	return n, true
+}
@@ -0,0 +1,43 @@
+package driver
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitWith(t *testing.T) {
+	query := "WITH a AS (SELECT ')' FROM t WHERE x = ?), `b c` (n) AS (SELECT * FROM a) SELECT * FROM `b c` WHERE y = ?"
+	main, ctes, err := splitWith(query)
+	assert.NoError(t, err)
+	assert.Len(t, main, len(query))
+	assert.Equal(t, "SELECT * FROM `b c` WHERE y = ?", main[len(query)-len("SELECT * FROM `b c` WHERE y = ?"):])
+	assert.Len(t, ctes, 2)
+	assert.Equal(t, "a", ctes[0].name)
+	assert.Equal(t, "b c", ctes[1].name)
+	assert.Equal(t, []string{"n"}, ctes[1].columns)
+	assert.Equal(t, len(query), len(ctes[0].body))
+	assert.Equal(t, query[11:40], ctes[0].body[11:40])
+
+	_, _, err = splitWith("WITH RECURSIVE a AS (SELECT 1) SELECT * FROM a")
+	assert.Error(t, err)
+}
+
+func TestSelectDerived(t *testing.T) {
+	table := newSalesTable()
+	db := newMockDB(t, table)
+
+	columns, res := queryAll(t, db, "SELECT who, total FROM (SELECT owner AS who, amount * 10 AS total FROM tbl) AS s WHERE total > 15 ORDER BY total")
+	assert.Equal(t, []string{"who", "total"}, columns)
+	assert.Equal(t, [][]interface{}{{"alice", 20.0}, {"alice", 30.0}}, res)
+
+	columns, res = queryAll(t, db, `WITH big AS (SELECT name, amount FROM tbl WHERE amount > ?),
+		named (n) AS (SELECT UPPER(name) FROM big)
+		SELECT n FROM named ORDER BY n DESC LIMIT 1`, 1)
+	assert.Equal(t, []string{"n"}, columns)
+	assert.Equal(t, [][]interface{}{{"CHERRY"}}, res)
+	assert.Equal(t, "CurrentValue.[amount] > 1", *table.listRequests()[1].Filter)
+
+	_, res = queryAll(t, db, "WITH o AS (SELECT DISTINCT owner FROM tbl) SELECT * FROM o LIMIT 1")
+	assert.Equal(t, [][]interface{}{{"alice"}}, res)
+}
@@ -0,0 +1,46 @@
+// Package zaplog adapt a zap logger to the Logger of the driver, zap is only a dependency of its importers.
+package zaplog
+
+import (
+	"context"
+	"fmt"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/luw2007/bitable-mysql-driver/driver"
+)
+
+// levels are the levels of zap, trace is debug.
+var levels = map[driver.LogLevel]zapcore.Level{
+	driver.LevelTrace: zapcore.DebugLevel,
+	driver.LevelDebug: zapcore.DebugLevel,
+	driver.LevelInfo:  zapcore.InfoLevel,
+	driver.LevelWarn:  zapcore.WarnLevel,
+	driver.LevelError: zapcore.ErrorLevel,
+}
+
+type zapLogger struct {
+	logger *zap.Logger
+}
+
+// New return a driver.Logger writing to a zap logger.
+func New(logger *zap.Logger) driver.Logger {
+	return zapLogger{logger: logger}
+}
+
+func (l zapLogger) Log(_ context.Context, level driver.LogLevel, msg string, fields ...interface{}) {
+	ce := l.logger.Check(levels[level], msg)
+	if ce == nil {
+		return
+	}
+	zapFields := make([]zap.Field, 0, (len(fields)+1)/2)
+	for i := 0; i < len(fields); i += 2 {
+		if i+1 == len(fields) {
+			zapFields = append(zapFields, zap.Any("!BADKEY", fields[i]))
+			break
+		}
+		zapFields = append(zapFields, zap.Any(fmt.Sprint(fields[i]), fields[i+1]))
+	}
+	ce.Write(zapFields...)
+}
@@ -0,0 +1,25 @@
+package zaplog
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/luw2007/bitable-mysql-driver/driver"
+)
+
+func TestLogger(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	logger := New(zap.New(core))
+	logger.Log(context.Background(), driver.LevelDebug, "dropped")
+	logger.Log(context.Background(), driver.LevelWarn, "slow", "table", "tbl", "odd")
+	if assert.Equal(t, 1, logs.Len()) {
+		entry := logs.All()[0]
+		assert.Equal(t, zapcore.WarnLevel, entry.Level)
+		assert.Equal(t, map[string]interface{}{"table": "tbl", "!BADKEY": "odd"}, entry.ContextMap())
+	}
+}
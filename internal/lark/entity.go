@@ -38,10 +38,35 @@ type Table struct {
 	Name     string `json:"name,omitempty"`     // 数据表名字
 }
 
+// CreatedTable is the table created with its default view and fields.
+type CreatedTable struct {
+	TableID       string   `json:"table_id,omitempty"`        // 数据表 id
+	DefaultViewID string   `json:"default_view_id,omitempty"` // 默认视图 id
+	FieldIDList   []string `json:"field_id_list,omitempty"`   // 字段 id 列表, 和创建的字段顺序一致
+}
+
 type View struct {
-	ViewID   string `json:"view_id,omitempty"`   // 视图Id
-	ViewName string `json:"view_name,omitempty"` // 视图名字
-	ViewType string `json:"view_type,omitempty"` // 视图类型
+	ViewID   string        `json:"view_id,omitempty"`   // 视图Id
+	ViewName string        `json:"view_name,omitempty"` // 视图名字
+	ViewType string        `json:"view_type,omitempty"` // 视图类型
+	Property *ViewProperty `json:"property,omitempty"`  // 视图属性, 只有获取单个视图时返回
+}
+
+// ViewProperty 视图的筛选条件和隐藏字段, 更新时空的 HiddenFields 显示全部字段
+type ViewProperty struct {
+	FilterInfo   *ViewFilterInfo `json:"filter_info,omitempty"` // 筛选条件
+	HiddenFields []string        `json:"hidden_fields"`         // 隐藏字段的 id
+}
+
+type ViewFilterInfo struct {
+	Conjunction string           `json:"conjunction"` // 条件之间的关系, and 或 or
+	Conditions  []*ViewCondition `json:"conditions"`  // 筛选条件
+}
+
+type ViewCondition struct {
+	FieldID  string `json:"field_id"`        // 字段 id
+	Operator string `json:"operator"`        // 操作符, 如 is, isGreater, contains, isEmpty
+	Value    string `json:"value,omitempty"` // 条件值, json 数组, 如 ["a"]
 }
 
 type Record struct {
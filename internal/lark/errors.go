@@ -0,0 +1,69 @@
+package lark
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/chyroc/lark"
+)
+
+// APIError is a failed request of the open api, the code is 0 unless the api returned one.
+type APIError struct {
+	Code       int64
+	Msg        string
+	LogID      string // the log id of the request, for the support of the open platform
+	Method     string
+	Path       string // the path of the request without the query
+	StatusCode int
+	Err        error
+}
+
+func (e *APIError) Error() string {
+	msg := e.Method + " " + e.Path + " failed"
+	if e.Code != 0 {
+		msg += ", code: " + strconv.FormatInt(e.Code, 10) + ", msg: " + e.Msg
+	} else if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	if e.LogID != "" {
+		msg += ", log_id: " + e.LogID
+	}
+	return msg
+}
+
+func (e *APIError) Unwrap() error {
+	return e.Err
+}
+
+// RateLimited report whether the request is rejected by the rate limit.
+func (e *APIError) RateLimited() bool {
+	return e.StatusCode == http.StatusTooManyRequests
+}
+
+// apiError attach the request of response to err, err is returned when there is no response.
+func apiError(response *lark.Response, err error) error {
+	if err == nil || response == nil {
+		return err
+	}
+	e := &APIError{StatusCode: response.StatusCode, Err: err}
+	e.Method, e.Path, e.LogID = requestOf(response)
+	var larkErr *lark.Error
+	if errors.As(err, &larkErr) {
+		e.Code, e.Msg = larkErr.Code, larkErr.Msg
+	}
+	return e
+}
+
+// requestOf return the method, the path without the query and the log id of the request of response.
+func requestOf(response *lark.Response) (method, path, logID string) {
+	method, path = response.Method, response.URL
+	if u, err := url.Parse(response.URL); err == nil {
+		path = u.Path
+	}
+	if logID = response.Header.Get("X-Tt-Logid"); logID == "" {
+		logID = response.RequestID
+	}
+	return method, path, logID
+}
@@ -2,9 +2,13 @@ package lark
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"fmt"
+	"net/http"
 	"reflect"
+	"strings"
 	"sync"
 	"time"
 
@@ -15,7 +19,10 @@ import (
 
 type BiTable struct {
 	*lark.Lark
-	appID string
+	appID     string
+	baseURL   string
+	telemetry *Telemetry
+	logger    *LevelLogger
 }
 
 var (
@@ -26,24 +33,179 @@ const (
 	DefaultPageSize = 50
 )
 
-func getStoreSlot(appID string) lark.Store {
-	s, _ := larkStoreMap.LoadOrStore(appID, lark.NewStoreMemory())
-	return s.(lark.Store)
+func getStoreSlot(appID string) *tokenStore {
+	s, _ := larkStoreMap.LoadOrStore(appID, newTokenStore())
+	return s.(*tokenStore)
+}
+
+// NewLarkClient create a BiTable client, options change the other ClientConfig, like the http client.
+func NewLarkClient(appID, appSecret, apiDomain, logLevel string, timeout time.Duration, options ...ClientOption) *BiTable {
+	cfg := ClientConfig{
+		AppID:     appID,
+		AppSecret: appSecret,
+		BaseURL:   apiDomain,
+		LogLevel:  logLevel,
+		Timeout:   timeout,
+	}
+	for _, option := range options {
+		option(&cfg)
+	}
+	return NewClient(cfg)
+}
+
+// ClientOption change a ClientConfig.
+type ClientOption func(*ClientConfig)
+
+// WithHTTPClient send the requests by client.
+func WithHTTPClient(client *http.Client) ClientOption {
+	return func(cfg *ClientConfig) {
+		cfg.HTTPClient = client
+	}
+}
+
+// WithRootCAs trust the CAs of pool, like the CA of a private deployment.
+func WithRootCAs(pool *x509.CertPool) ClientOption {
+	return func(cfg *ClientConfig) {
+		cfg.RootCAs = pool
+	}
+}
+
+// WithMaxRetries retry the requests failed by network errors or rate limit.
+func WithMaxRetries(retries int) ClientOption {
+	return func(cfg *ClientConfig) {
+		cfg.MaxRetries = retries
+	}
+}
+
+// WithLogger write the logs of the client to logger.
+func WithLogger(logger Logger) ClientOption {
+	return func(cfg *ClientConfig) {
+		cfg.Logger = logger
+	}
+}
+
+// WithTelemetry trace and measure the api calls by t.
+func WithTelemetry(t *Telemetry) ClientOption {
+	return func(cfg *ClientConfig) {
+		cfg.Telemetry = t
+	}
+}
+
+// the open api domains of the region presets.
+var regionDomains = map[string]string{
+	"feishu": "open.feishu.cn",
+	"lark":   "open.larksuite.com",
+}
+
+// RegionDomain return the open api domain of a region preset, `feishu` or `lark`, other domains are returned as is.
+func RegionDomain(domain string) string {
+	if d, ok := regionDomains[strings.ToLower(domain)]; ok {
+		return d
+	}
+	return domain
+}
+
+// BaseURL return the open api url of a region preset, a host[:port][/path] or a url.
+func BaseURL(domain string) string {
+	if strings.Contains(domain, "://") {
+		return strings.TrimRight(domain, "/")
+	}
+	return "https://" + strings.TrimRight(RegionDomain(domain), "/")
+}
+
+// ClientConfig the options of a BiTable client.
+type ClientConfig struct {
+	AppID      string
+	AppSecret  string
+	BaseURL    string // scheme://host[:port][/path] of the open api
+	LogLevel   string
+	Timeout    time.Duration
+	MaxRetries int // retries of a request failed by network errors or rate limit
+
+	HTTPClient *http.Client   // the client sending the requests, default a client with Timeout
+	RootCAs    *x509.CertPool // the CAs trusted by the client, default the CAs of the system
+	Telemetry  *Telemetry     // trace and measure the api calls, nil is disabled
+	Logger     Logger         // the logs at LogLevel or above, default the standard logger of logrus
+}
+
+// logger return the logger of cfg dropping the logs below LogLevel.
+func (cfg ClientConfig) logger() *LevelLogger {
+	logger := cfg.Logger
+	if logger == nil {
+		logger = NewLogrusLogger(logrus.StandardLogger())
+	}
+	return NewLevelLogger(logger, ParseLevel(cfg.LogLevel))
+}
+
+// clientOptions build the lark options of cfg, the logs of the sdk are written to logger.
+func clientOptions(cfg ClientConfig, logger *LevelLogger) []lark.ClientOptionFunc {
+	options := []lark.ClientOptionFunc{
+		lark.WithAppCredential(cfg.AppID, cfg.AppSecret),
+		lark.WithStore(getStoreSlot(cfg.AppID)),
+		lark.WithLogger(&larkLogger{logger: logger, secret: cfg.AppSecret}, getLarkLogLevel(logger.level)),
+		lark.WithOpenBaseURL(cfg.BaseURL),
+	}
+	if cfg.Timeout > 0 {
+		options = append(options, lark.WithTimeout(cfg.Timeout))
+	}
+	if client := newHTTPClient(cfg); client != nil {
+		options = append(options, lark.WithNetHttpClient(client))
+	}
+	return options
+}
+
+// newHTTPClient wrap the http client of cfg with the CAs and retries, nil for the default client of lark.
+func newHTTPClient(cfg ClientConfig) *http.Client {
+	if cfg.HTTPClient == nil && cfg.RootCAs == nil && cfg.MaxRetries <= 0 {
+		return nil
+	}
+	client := &http.Client{Timeout: cfg.Timeout}
+	if cfg.HTTPClient != nil {
+		c := *cfg.HTTPClient
+		client = &c
+	}
+	transport := client.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	if cfg.RootCAs != nil {
+		if t, ok := transport.(*http.Transport); ok {
+			t = t.Clone()
+			if t.TLSClientConfig == nil {
+				t.TLSClientConfig = &tls.Config{}
+			}
+			t.TLSClientConfig.RootCAs = cfg.RootCAs
+			transport = t
+		}
+	}
+	if cfg.MaxRetries > 0 {
+		transport = &retryTransport{base: transport, retries: cfg.MaxRetries, backoff: retryBackoff}
+	}
+	client.Transport = transport
+	return client
 }
 
-func NewLarkClient(appID, appSecret, apiDomain, logLevel string, timeout time.Duration) *BiTable {
-	logger := &larkLogger{logger: logrus.StandardLogger()}
+func NewClient(cfg ClientConfig) *BiTable {
+	logger := cfg.logger()
 	return &BiTable{
-		Lark: lark.New(lark.WithAppCredential(appID, appSecret),
-			lark.WithStore(getStoreSlot(appID)),
-			lark.WithLogger(logger, getLarkLogLevel(logLevel)),
-			lark.WithOpenBaseURL(apiDomain),
-			lark.WithTimeout(timeout),
-		),
-		appID: appID,
+		Lark:      lark.New(clientOptions(cfg, logger)...),
+		appID:     cfg.AppID,
+		baseURL:   strings.TrimRight(cfg.BaseURL, "/"),
+		telemetry: cfg.Telemetry,
+		logger:    logger,
 	}
 }
 
+// Logger return the logger of the client, the logs below the level of the client are dropped.
+func (b *BiTable) Logger() *LevelLogger {
+	return b.logger
+}
+
+// Telemetry return the telemetry of the client, nil when it's disabled.
+func (b *BiTable) Telemetry() *Telemetry {
+	return b.telemetry
+}
+
 type contextKey string
 
 const (
@@ -54,13 +216,50 @@ func WithUserToken(ctx context.Context, userToken string) context.Context {
 	return context.WithValue(ctx, userTokenContextKey, userToken)
 }
 
+// UserTokenOf return the user_access_token of ctx, empty for the tenant_access_token.
+func UserTokenOf(ctx context.Context) string {
+	userToken, _ := ctx.Value(userTokenContextKey).(string)
+	return userToken
+}
+
+// UserToken is a user_access_token refreshed by a refresh token.
+type UserToken struct {
+	AccessToken  string
+	RefreshToken string // the refresh token is rotated, the old one can't be used again
+	ExpiresIn    time.Duration
+}
+
+// RefreshUserToken get a new user_access_token by refreshToken.
+func (b *BiTable) RefreshUserToken(ctx context.Context, refreshToken string) (*UserToken, error) {
+	var resp *lark.RefreshAccessTokenResp
+	err := b.do(ctx, "RefreshAccessToken", func(ctx context.Context) (response *lark.Response, err error) {
+		resp, response, err = b.Auth.RefreshAccessToken(ctx, &lark.RefreshAccessTokenReq{
+			GrantType:    "refresh_token",
+			RefreshToken: refreshToken,
+		})
+		return response, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &UserToken{
+		AccessToken:  resp.AccessToken,
+		RefreshToken: resp.RefreshToken,
+		ExpiresIn:    time.Duration(resp.ExpiresIn) * time.Second,
+	}, nil
+}
+
 func (b *BiTable) GetApp(ctx context.Context, appToken string) (*AppMeta, error) {
 	req := &lark.GetBitableMetaReq{
 		AppToken: appToken,
 	}
-	resp, _, err := b.Bitable.GetBitableMeta(ctx, req, buildMethodOptions(ctx)...)
+	var resp *lark.GetBitableMetaResp
+	err := b.do(ctx, "GetBitableMeta", func(ctx context.Context) (response *lark.Response, err error) {
+		resp, response, err = b.Bitable.GetBitableMeta(ctx, req, buildMethodOptions(ctx)...)
+		return response, err
+	})
 	if err != nil {
-		return nil, fmt.Errorf("bitable %w", err)
+		return nil, err
 	}
 	app := resp.App
 	return &AppMeta{
@@ -77,34 +276,105 @@ func (b *BiTable) CreateTable(ctx context.Context, appToken, tableName string) (
 			Name: &tableName,
 		},
 	}
-	resp, _, err := b.Bitable.CreateBitableTable(ctx, req, buildMethodOptions(ctx)...)
+	var resp *lark.CreateBitableTableResp
+	err := b.do(ctx, "CreateBitableTable", func(ctx context.Context) (response *lark.Response, err error) {
+		resp, response, err = b.Bitable.CreateBitableTable(ctx, req, buildMethodOptions(ctx)...)
+		return response, err
+	})
 	if err != nil {
 		return "", err
 	}
 	return resp.TableID, nil
 }
 
+// TableField is a field of CreateTableWithFields, Property is the json of the field property.
+type TableField struct {
+	FieldName string          `json:"field_name"`
+	Type      int64           `json:"type"`
+	Property  json.RawMessage `json:"property,omitempty"`
+}
+
+type createTableReq struct {
+	AppToken string `path:"app_token" json:"-"`
+	Table    struct {
+		Name            string        `json:"name"`
+		DefaultViewName string        `json:"default_view_name,omitempty"`
+		Fields          []*TableField `json:"fields,omitempty"`
+	} `json:"table"`
+}
+
+type createTableResp struct {
+	Code int64        `json:"code,omitempty"`
+	Msg  string       `json:"msg,omitempty"`
+	Data CreatedTable `json:"data"`
+}
+
+// CreateTableWithFields create a table with its default view and fields in one request,
+// the sdk only sends the name of the table.
+func (b *BiTable) CreateTableWithFields(ctx context.Context, appToken, tableName, defaultViewName string,
+	fields []*TableField) (*CreatedTable, error) {
+	req := &createTableReq{AppToken: appToken}
+	req.Table.Name = tableName
+	req.Table.DefaultViewName = defaultViewName
+	req.Table.Fields = fields
+	resp := new(createTableResp)
+	err := b.rawRequest(ctx, "CreateBitableTable", "POST", "/open-apis/bitable/v1/apps/:app_token/tables", req, resp)
+	if err != nil {
+		return nil, err
+	}
+	return &resp.Data, nil
+}
+
 func (b *BiTable) DropTable(ctx context.Context, appToken, tableID string) error {
-	_, _, err := b.Bitable.DeleteBitableTable(ctx, &lark.DeleteBitableTableReq{
-		AppToken: appToken,
-		TableID:  tableID,
+	err := b.do(ctx, "DeleteBitableTable", func(ctx context.Context) (response *lark.Response, err error) {
+		_, response, err = b.Bitable.DeleteBitableTable(ctx, &lark.DeleteBitableTableReq{
+			AppToken: appToken,
+			TableID:  tableID,
+		}, buildMethodOptions(ctx)...)
+		return response, err
 	})
 	if err != nil {
-		return fmt.Errorf("bitable %w", err)
+		return err
 	}
 	return nil
 }
 
+type tableReq struct {
+	AppToken string `path:"app_token" json:"-"`
+	TableID  string `path:"table_id" json:"-"`
+	Name     string `json:"name"`
+}
+
+type tableResp struct {
+	Code int64  `json:"code,omitempty"`
+	Msg  string `json:"msg,omitempty"`
+	Data struct {
+		Name string `json:"name"`
+	} `json:"data"`
+}
+
+// UpdateTable rename a table, the sdk has no api to update a table.
+func (b *BiTable) UpdateTable(ctx context.Context, appToken, tableID, tableName string) error {
+	req := &tableReq{AppToken: appToken, TableID: tableID, Name: tableName}
+	return b.rawRequest(ctx, "UpdateBitableTable", "PATCH",
+		"/open-apis/bitable/v1/apps/:app_token/tables/:table_id", req, new(tableResp))
+}
+
 func (b *BiTable) ListTable(ctx context.Context, appToken string, pageToken string, pageSize int64) (*PageList, error) {
 	req := &lark.GetBitableTableListReq{
 		PageSize:  &pageSize,
 		PageToken: &pageToken,
 		AppToken:  appToken,
 	}
-	resp, _, err := b.Bitable.GetBitableTableList(ctx, req, buildMethodOptions(ctx)...)
+	var resp *lark.GetBitableTableListResp
+	err := b.do(ctx, "GetBitableTableList", func(ctx context.Context) (response *lark.Response, err error) {
+		resp, response, err = b.Bitable.GetBitableTableList(ctx, req, buildMethodOptions(ctx)...)
+		return response, err
+	})
 	if err != nil {
-		return nil, fmt.Errorf("bitable %w", err)
+		return nil, err
 	}
+	b.telemetry.page(ctx, "GetBitableTableList")
 	return buildPageList(resp)
 }
 
@@ -131,9 +401,13 @@ func (b *BiTable) CreateView(ctx context.Context, appToken, table, viewName stri
 		ViewName: viewName,
 		ViewType: &viewType,
 	}
-	resp, _, err := b.Bitable.CreateBitableView(ctx, req, buildMethodOptions(ctx)...)
+	var resp *lark.CreateBitableViewResp
+	err := b.do(ctx, "CreateBitableView", func(ctx context.Context) (response *lark.Response, err error) {
+		resp, response, err = b.Bitable.CreateBitableView(ctx, req, buildMethodOptions(ctx)...)
+		return response, err
+	})
 	if err != nil {
-		return nil, fmt.Errorf("bitable %w", err)
+		return nil, err
 	}
 	view := resp.View
 	return &View{
@@ -149,13 +423,56 @@ func (b *BiTable) DropView(ctx context.Context, appToken string, table string, v
 		TableID:  table,
 		ViewID:   view,
 	}
-	_, _, err := b.Bitable.DeleteBitableView(ctx, req, buildMethodOptions(ctx)...)
+	err := b.do(ctx, "DeleteBitableView", func(ctx context.Context) (response *lark.Response, err error) {
+		_, response, err = b.Bitable.DeleteBitableView(ctx, req, buildMethodOptions(ctx)...)
+		return response, err
+	})
 	if err != nil {
-		return fmt.Errorf("bitable %w", err)
+		return err
 	}
 	return nil
 }
 
+type viewReq struct {
+	AppToken string        `path:"app_token" json:"-"`
+	TableID  string        `path:"table_id" json:"-"`
+	ViewID   string        `path:"view_id" json:"-"`
+	ViewName string        `json:"view_name,omitempty"`
+	Property *ViewProperty `json:"property,omitempty"`
+}
+
+type viewResp struct {
+	Code int64  `json:"code,omitempty"`
+	Msg  string `json:"msg,omitempty"`
+	Data struct {
+		View *View `json:"view"`
+	} `json:"data"`
+}
+
+// GetView get a view with its property, the sdk has no api to get a view.
+func (b *BiTable) GetView(ctx context.Context, appToken, table, view string) (*View, error) {
+	req := &viewReq{AppToken: appToken, TableID: table, ViewID: view}
+	resp := new(viewResp)
+	err := b.rawRequest(ctx, "GetBitableView", "GET",
+		"/open-apis/bitable/v1/apps/:app_token/tables/:table_id/views/:view_id", req, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Data.View, nil
+}
+
+// UpdateView rename a view or replace its property, an empty viewName or nil property is unchanged.
+func (b *BiTable) UpdateView(ctx context.Context, appToken, table, view, viewName string, property *ViewProperty) (*View, error) {
+	req := &viewReq{AppToken: appToken, TableID: table, ViewID: view, ViewName: viewName, Property: property}
+	resp := new(viewResp)
+	err := b.rawRequest(ctx, "UpdateBitableView", "PATCH",
+		"/open-apis/bitable/v1/apps/:app_token/tables/:table_id/views/:view_id", req, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Data.View, nil
+}
+
 func (b *BiTable) ListViews(ctx context.Context, appToken string, table string, pageToken string, pageSize int64) (*PageList, error) {
 	req := &lark.GetBitableViewListReq{
 		PageSize:  &pageSize,
@@ -163,11 +480,15 @@ func (b *BiTable) ListViews(ctx context.Context, appToken string, table string,
 		AppToken:  appToken,
 		TableID:   table,
 	}
-	resp, _, err := b.Bitable.GetBitableViewList(ctx, req, buildMethodOptions(ctx)...)
+	var resp *lark.GetBitableViewListResp
+	err := b.do(ctx, "GetBitableViewList", func(ctx context.Context) (response *lark.Response, err error) {
+		resp, response, err = b.Bitable.GetBitableViewList(ctx, req, buildMethodOptions(ctx)...)
+		return response, err
+	})
 	if err != nil {
-		return nil, fmt.Errorf("bitable %w", err)
+		return nil, err
 	}
-
+	b.telemetry.page(ctx, "GetBitableViewList")
 	return buildPageList(resp)
 }
 
@@ -186,9 +507,13 @@ func (b *BiTable) AddField(ctx context.Context, appToken, table, fieldName strin
 		req.Property = &p
 	}
 
-	resp, _, err := b.Bitable.CreateBitableField(ctx, req, buildMethodOptions(ctx)...)
+	var resp *lark.CreateBitableFieldResp
+	err := b.do(ctx, "CreateBitableField", func(ctx context.Context) (response *lark.Response, err error) {
+		resp, response, err = b.Bitable.CreateBitableField(ctx, req, buildMethodOptions(ctx)...)
+		return response, err
+	})
 	if err != nil {
-		return nil, fmt.Errorf("bitable %w", err)
+		return nil, err
 	}
 	return buildField(resp.Field)
 }
@@ -199,7 +524,11 @@ func (b *BiTable) DeleteField(ctx context.Context, appToken, table, fieldID stri
 		TableID:  table,
 		FieldID:  fieldID,
 	}
-	resp, _, err := b.Bitable.DeleteBitableField(ctx, req, buildMethodOptions(ctx)...)
+	var resp *lark.DeleteBitableFieldResp
+	err := b.do(ctx, "DeleteBitableField", func(ctx context.Context) (response *lark.Response, err error) {
+		resp, response, err = b.Bitable.DeleteBitableField(ctx, req, buildMethodOptions(ctx)...)
+		return response, err
+	})
 	if err != nil {
 		return false, err
 	}
@@ -221,9 +550,13 @@ func (b *BiTable) UpdateField(ctx context.Context, appToken, table, fieldID, fie
 		}
 		req.Property = &p
 	}
-	resp, _, err := b.Bitable.UpdateBitableField(ctx, req, buildMethodOptions(ctx)...)
+	var resp *lark.UpdateBitableFieldResp
+	err := b.do(ctx, "UpdateBitableField", func(ctx context.Context) (response *lark.Response, err error) {
+		resp, response, err = b.Bitable.UpdateBitableField(ctx, req, buildMethodOptions(ctx)...)
+		return response, err
+	})
 	if err != nil {
-		return nil, fmt.Errorf("bitable %w", err)
+		return nil, err
 	}
 	return buildField(resp.Field)
 }
@@ -236,10 +569,15 @@ func (b *BiTable) ListFields(ctx context.Context, appToken, table, view, pageTok
 		AppToken:  appToken,
 		TableID:   table,
 	}
-	resp, _, err := b.Bitable.GetBitableFieldList(ctx, req, buildMethodOptions(ctx)...)
+	var resp *lark.GetBitableFieldListResp
+	err := b.do(ctx, "GetBitableFieldList", func(ctx context.Context) (response *lark.Response, err error) {
+		resp, response, err = b.Bitable.GetBitableFieldList(ctx, req, buildMethodOptions(ctx)...)
+		return response, err
+	})
 	if err != nil {
-		return nil, fmt.Errorf("bitable %w", err)
+		return nil, err
 	}
+	b.telemetry.page(ctx, "GetBitableFieldList")
 	return buildPageList(resp)
 }
 
@@ -255,9 +593,13 @@ func (b *BiTable) InsertRecords(ctx context.Context, appToken, table string, dat
 		TableID:  table,
 		Records:  records,
 	}
-	resp, _, err := b.Bitable.BatchCreateBitableRecord(ctx, req, buildMethodOptions(ctx)...)
+	var resp *lark.BatchCreateBitableRecordResp
+	err := b.do(ctx, "BatchCreateBitableRecord", func(ctx context.Context) (response *lark.Response, err error) {
+		resp, response, err = b.Bitable.BatchCreateBitableRecord(ctx, req, buildMethodOptions(ctx)...)
+		return response, err
+	})
 	if err != nil {
-		return nil, fmt.Errorf("bitable %w", err)
+		return nil, err
 	}
 	return buildRecords(resp.Records)
 }
@@ -268,7 +610,11 @@ func (b *BiTable) DeleteRecord(ctx context.Context, appToken, table, recordID st
 		TableID:  table,
 		RecordID: recordID,
 	}
-	resp, _, err := b.Bitable.DeleteBitableRecord(ctx, req, buildMethodOptions(ctx)...)
+	var resp *lark.DeleteBitableRecordResp
+	err := b.do(ctx, "DeleteBitableRecord", func(ctx context.Context) (response *lark.Response, err error) {
+		resp, response, err = b.Bitable.DeleteBitableRecord(ctx, req, buildMethodOptions(ctx)...)
+		return response, err
+	})
 	if err != nil {
 		return false, err
 	}
@@ -282,9 +628,13 @@ func (b *BiTable) UpdateRecord(ctx context.Context, appToken, table, recordID st
 		RecordID: recordID,
 		Fields:   fields,
 	}
-	resp, _, err := b.Bitable.UpdateBitableRecord(ctx, req, buildMethodOptions(ctx)...)
+	var resp *lark.UpdateBitableRecordResp
+	err := b.do(ctx, "UpdateBitableRecord", func(ctx context.Context) (response *lark.Response, err error) {
+		resp, response, err = b.Bitable.UpdateBitableRecord(ctx, req, buildMethodOptions(ctx)...)
+		return response, err
+	})
 	if err != nil {
-		return nil, fmt.Errorf("bitable %w", err)
+		return nil, err
 	}
 	record := resp.Record
 	return &Record{
@@ -306,9 +656,13 @@ func (b *BiTable) UpdateRecords(ctx context.Context, appToken, table string, dat
 		TableID:  table,
 		Records:  records,
 	}
-	resp, _, err := b.Bitable.BatchUpdateBitableRecord(ctx, req, buildMethodOptions(ctx)...)
+	var resp *lark.BatchUpdateBitableRecordResp
+	err := b.do(ctx, "BatchUpdateBitableRecord", func(ctx context.Context) (response *lark.Response, err error) {
+		resp, response, err = b.Bitable.BatchUpdateBitableRecord(ctx, req, buildMethodOptions(ctx)...)
+		return response, err
+	})
 	if err != nil {
-		return nil, fmt.Errorf("bitable %w", err)
+		return nil, err
 	}
 	return buildRecords(resp.Records)
 }
@@ -319,9 +673,13 @@ func (b *BiTable) GetRecord(ctx context.Context, appToken, table, recordID strin
 		TableID:  table,
 		RecordID: recordID,
 	}
-	resp, _, err := b.Bitable.GetBitableRecord(ctx, req, buildMethodOptions(ctx)...)
+	var resp *lark.GetBitableRecordResp
+	err := b.do(ctx, "GetBitableRecord", func(ctx context.Context) (response *lark.Response, err error) {
+		resp, response, err = b.Bitable.GetBitableRecord(ctx, req, buildMethodOptions(ctx)...)
+		return response, err
+	})
 	if err != nil {
-		return nil, fmt.Errorf("bitable %w", err)
+		return nil, err
 	}
 	record := resp.Record
 	return &Record{
@@ -330,6 +688,73 @@ func (b *BiTable) GetRecord(ctx context.Context, appToken, table, recordID strin
 	}, nil
 }
 
+// MaxBatchGetRecords is the most record ids of a batch_get request.
+const MaxBatchGetRecords = 100
+
+type batchGetRecordsReq struct {
+	AppToken  string   `path:"app_token" json:"-"`
+	TableID   string   `path:"table_id" json:"-"`
+	RecordIDs []string `json:"record_ids"`
+}
+
+type batchGetRecordsResp struct {
+	Code int64  `json:"code,omitempty"`
+	Msg  string `json:"msg,omitempty"`
+	Data struct {
+		Records []*Record `json:"records"`
+	} `json:"data"`
+}
+
+// BatchGetRecords get the records of recordIDs in order, the absent or forbidden records are skipped.
+// The sdk has no batch_get api, 100 ids a request.
+func (b *BiTable) BatchGetRecords(ctx context.Context, appToken, table string, recordIDs []string) ([]*Record, error) {
+	records := make([]*Record, 0, len(recordIDs))
+	for start := 0; start < len(recordIDs); start += MaxBatchGetRecords {
+		end := start + MaxBatchGetRecords
+		if end > len(recordIDs) {
+			end = len(recordIDs)
+		}
+		req := &batchGetRecordsReq{
+			AppToken:  appToken,
+			TableID:   table,
+			RecordIDs: recordIDs[start:end],
+		}
+		resp := new(batchGetRecordsResp)
+		err := b.rawRequest(ctx, "BatchGetBitableRecord", "POST",
+			"/open-apis/bitable/v1/apps/:app_token/tables/:table_id/records/batch_get", req, resp)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, resp.Data.Records...)
+	}
+	return records, nil
+}
+
+// rawRequest call an open api of bitable missing in the sdk, resp has the Code, Msg and Data of the response.
+func (b *BiTable) rawRequest(ctx context.Context, api, method, path string, body, resp interface{}) error {
+	baseURL := b.baseURL
+	if baseURL == "" {
+		baseURL = BaseURL(regionDomains["feishu"])
+	}
+	option := new(lark.MethodOption)
+	for _, f := range buildMethodOptions(ctx) {
+		f(option)
+	}
+	req := &lark.RawRequestReq{
+		Scope:                 "Bitable",
+		API:                   api,
+		Method:                method,
+		URL:                   baseURL + path,
+		Body:                  body,
+		MethodOption:          option,
+		NeedTenantAccessToken: true,
+		NeedUserAccessToken:   true,
+	}
+	return b.do(ctx, api, func(ctx context.Context) (*lark.Response, error) {
+		return b.RawRequest(ctx, req, resp)
+	})
+}
+
 func (b *BiTable) ListRecords(ctx context.Context, appToken, table, view, fieldNames, filter, sort, pageToken string, pageSize int64) (*PageList, error) {
 	req := &lark.GetBitableRecordListReq{
 		ViewID:     &view,
@@ -341,10 +766,15 @@ func (b *BiTable) ListRecords(ctx context.Context, appToken, table, view, fieldN
 		AppToken:   appToken,
 		TableID:    table,
 	}
-	resp, _, err := b.Bitable.GetBitableRecordList(ctx, req, buildMethodOptions(ctx)...)
+	var resp *lark.GetBitableRecordListResp
+	err := b.do(ctx, "GetBitableRecordList", func(ctx context.Context) (response *lark.Response, err error) {
+		resp, response, err = b.Bitable.GetBitableRecordList(ctx, req, buildMethodOptions(ctx)...)
+		return response, err
+	})
 	if err != nil {
-		return nil, fmt.Errorf("bitable %w", err)
+		return nil, err
 	}
+	b.telemetry.page(ctx, "GetBitableRecordList")
 	return buildPageList(resp)
 }
 
@@ -428,7 +858,7 @@ func buildPageList(resp interface{}) (*PageList, error) {
 
 func buildMethodOptions(ctx context.Context) []lark.MethodOptionFunc {
 	options := make([]lark.MethodOptionFunc, 0)
-	if userToken, ok := ctx.Value(userTokenContextKey).(string); ok && userToken != "" {
+	if userToken := UserTokenOf(ctx); userToken != "" {
 		options = append(options, lark.WithUserAccessToken(userToken))
 	}
 	return options
@@ -443,9 +873,9 @@ func (s *Sheets) GetAPP(ctx context.Context, shetToken string) ([]*Sheet, error)
 	req := &lark.GetSheetMetaReq{
 		SpreadSheetToken: shetToken,
 	}
-	resp, _, err := s.Drive.GetSheetMeta(ctx, req)
+	resp, response, err := s.Drive.GetSheetMeta(ctx, req)
 	if err != nil {
-		return nil, err
+		return nil, apiError(response, err)
 	}
 	sheets := []*Sheet{}
 	if err := copier.Copy(&sheets, &resp.Sheets); err != nil {
@@ -455,12 +885,18 @@ func (s *Sheets) GetAPP(ctx context.Context, shetToken string) ([]*Sheet, error)
 }
 
 func NewSheetsClient(appID, appSecret, apiDomain, logLevel string) *Sheets {
-	logger := &larkLogger{logger: logrus.StandardLogger()}
+	return NewSheets(ClientConfig{
+		AppID:     appID,
+		AppSecret: appSecret,
+		BaseURL:   apiDomain,
+		LogLevel:  logLevel,
+	})
+}
+
+// NewSheets create a Sheets client of cfg.
+func NewSheets(cfg ClientConfig) *Sheets {
 	return &Sheets{
-		Lark: lark.New(lark.WithAppCredential(appID, appSecret),
-			lark.WithStore(getStoreSlot(appID)),
-			lark.WithLogger(logger, getLarkLogLevel(logLevel)),
-			lark.WithOpenBaseURL(apiDomain)),
-		appID: appID,
+		Lark:  lark.New(clientOptions(cfg, cfg.logger())...),
+		appID: cfg.AppID,
 	}
 }
@@ -2,45 +2,155 @@ package lark
 
 import (
 	"context"
+	"fmt"
+	"strings"
 
 	"github.com/chyroc/lark"
 	"github.com/sirupsen/logrus"
 )
 
+// Level is the level of a log.
+type Level int8
+
+// the levels of the logs, from the most verbose.
+const (
+	LevelTrace Level = iota
+	LevelDebug
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+var levelNames = []string{"trace", "debug", "info", "warn", "error"}
+
+func (l Level) String() string {
+	if l < LevelTrace || l > LevelError {
+		return fmt.Sprintf("level(%d)", int8(l))
+	}
+	return levelNames[l]
+}
+
+// ParseLevel parse trace, debug, info, warn or error, other levels are info.
+func ParseLevel(level string) Level {
+	for i, name := range levelNames {
+		if strings.EqualFold(level, name) {
+			return Level(i)
+		}
+	}
+	return LevelInfo
+}
+
+// Logger write the logs of the driver, fields are pairs of key and value.
+type Logger interface {
+	Log(ctx context.Context, level Level, msg string, fields ...interface{})
+}
+
+// LevelLogger drop the logs below its level, so the level of a DSN doesn't change the other loggers.
+type LevelLogger struct {
+	logger Logger
+	level  Level
+}
+
+// NewLevelLogger return a logger writing the logs at level or above to logger.
+func NewLevelLogger(logger Logger, level Level) *LevelLogger {
+	return &LevelLogger{logger: logger, level: level}
+}
+
+// Enabled report whether the logs of level are written.
+func (l *LevelLogger) Enabled(level Level) bool {
+	return l != nil && l.logger != nil && level >= l.level
+}
+
+// Log implement Logger.
+func (l *LevelLogger) Log(ctx context.Context, level Level, msg string, fields ...interface{}) {
+	if l.Enabled(level) {
+		l.logger.Log(ctx, level, msg, fields...)
+	}
+}
+
+// larkLogger forward the logs of the lark sdk.
 type larkLogger struct {
-	logger *logrus.Logger
+	logger *LevelLogger
+	// secret is masked in the logs, the trace logs have the body of the token requests
+	secret string
 }
 
 var (
-	levelMap = map[lark.LogLevel]logrus.Level{
-		lark.LogLevelTrace: logrus.TraceLevel,
-		lark.LogLevelDebug: logrus.DebugLevel,
-		lark.LogLevelInfo:  logrus.InfoLevel,
-		lark.LogLevelWarn:  logrus.WarnLevel,
-		lark.LogLevelError: logrus.ErrorLevel,
+	levelMap = map[lark.LogLevel]Level{
+		lark.LogLevelTrace: LevelTrace,
+		lark.LogLevelDebug: LevelDebug,
+		lark.LogLevelInfo:  LevelInfo,
+		lark.LogLevelWarn:  LevelWarn,
+		lark.LogLevelError: LevelError,
 	}
 )
 
 func (l larkLogger) Log(ctx context.Context, larkLevel lark.LogLevel, msg string, args ...interface{}) {
-	if level, ok := levelMap[larkLevel]; ok {
-		l.logger.Logf(level, msg, args...)
+	level, ok := levelMap[larkLevel]
+	if !ok {
+		level = LevelInfo
+	}
+	if !l.logger.Enabled(level) {
 		return
 	}
-	l.logger.Logf(logrus.InfoLevel, msg, args...)
+	msg = fmt.Sprintf(msg, args...)
+	if l.secret != "" {
+		msg = strings.ReplaceAll(msg, l.secret, "******")
+	}
+	l.logger.Log(ctx, level, msg)
 }
 
-func getLarkLogLevel(level string) lark.LogLevel {
-	switch level {
-	case "trace":
-		return lark.LogLevelTrace
-	case "debug":
-		return lark.LogLevelDebug
-	case "info":
-		return lark.LogLevelInfo
-	case "warn":
-		return lark.LogLevelWarn
-	case "error":
-		return lark.LogLevelError
+func getLarkLogLevel(level Level) lark.LogLevel {
+	for larkLevel, l := range levelMap {
+		if l == level {
+			return larkLevel
+		}
 	}
 	return lark.LogLevelInfo
 }
+
+// logrusLogger write the logs to a logrus logger, the fields are logrus fields.
+type logrusLogger struct {
+	logger *logrus.Logger
+}
+
+// NewLogrusLogger return a Logger writing to logger.
+func NewLogrusLogger(logger *logrus.Logger) Logger {
+	return logrusLogger{logger: logger}
+}
+
+var logrusLevels = map[Level]logrus.Level{
+	LevelTrace: logrus.TraceLevel,
+	LevelDebug: logrus.DebugLevel,
+	LevelInfo:  logrus.InfoLevel,
+	LevelWarn:  logrus.WarnLevel,
+	LevelError: logrus.ErrorLevel,
+}
+
+func (l logrusLogger) Log(ctx context.Context, level Level, msg string, fields ...interface{}) {
+	logrusLevel, ok := logrusLevels[level]
+	if !ok {
+		logrusLevel = logrus.InfoLevel
+	}
+	if !l.logger.IsLevelEnabled(logrusLevel) {
+		return
+	}
+	entry := logrus.NewEntry(l.logger).WithContext(ctx)
+	if len(fields) > 0 {
+		entry = entry.WithFields(FieldMap(fields))
+	}
+	entry.Log(logrusLevel, msg)
+}
+
+// FieldMap convert the pairs of key and value to a map, a key without value is `!BADKEY` like slog.
+func FieldMap(fields []interface{}) map[string]interface{} {
+	m := make(map[string]interface{}, len(fields)/2)
+	for i := 0; i < len(fields); i += 2 {
+		if i+1 == len(fields) {
+			m["!BADKEY"] = fields[i]
+			break
+		}
+		m[fmt.Sprint(fields[i])] = fields[i+1]
+	}
+	return m
+}
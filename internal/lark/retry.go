@@ -0,0 +1,68 @@
+package lark
+
+import (
+	"net/http"
+	"time"
+)
+
+// retryBackoff is the wait before the first retry, it doubles for each retry.
+var retryBackoff = 200 * time.Millisecond
+
+// retryTransport retry the requests failed by network errors or the server, only rate limited
+// requests are retried unless the method is idempotent, the others may have changed the records.
+type retryTransport struct {
+	base    http.RoundTripper
+	retries int
+	backoff time.Duration
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	wait := t.backoff
+	for i := 0; ; i++ {
+		resp, err := t.base.RoundTrip(req)
+		if i >= t.retries || !t.retryable(req, resp, err) {
+			return resp, err
+		}
+		if req.Body != nil {
+			if req.GetBody == nil {
+				return resp, err
+			}
+			body, bodyErr := req.GetBody()
+			if bodyErr != nil {
+				return resp, err
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+		throttled := resp != nil && resp.StatusCode == http.StatusTooManyRequests
+		if resp != nil {
+			_ = resp.Body.Close()
+		}
+		if stats := callStatsOf(req.Context()); stats != nil {
+			stats.retries++
+			if throttled {
+				stats.throttle += wait
+			}
+		}
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-time.After(wait):
+		}
+		wait *= 2
+	}
+}
+
+func (t *retryTransport) retryable(req *http.Request, resp *http.Response, err error) bool {
+	idempotent := req.Method == http.MethodGet || req.Method == http.MethodHead
+	if err != nil {
+		return idempotent && req.Context().Err() == nil
+	}
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return true
+	case resp.StatusCode >= http.StatusInternalServerError:
+		return idempotent
+	}
+	return false
+}
@@ -0,0 +1,130 @@
+package lark
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/chyroc/lark"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// InstrumentationName is the name of the tracer and meter of the driver.
+const InstrumentationName = "github.com/luw2007/bitable-mysql-driver"
+
+// Telemetry trace the open api calls and record their metrics, a nil Telemetry is disabled.
+type Telemetry struct {
+	tracer   trace.Tracer
+	duration metric.Float64Histogram
+	throttle metric.Float64Counter
+	pages    metric.Int64Counter
+}
+
+// NewTelemetry create the instruments of the providers, nil providers are disabled.
+func NewTelemetry(tp trace.TracerProvider, mp metric.MeterProvider) (*Telemetry, error) {
+	if tp == nil && mp == nil {
+		return nil, nil
+	}
+	t := &Telemetry{}
+	if tp != nil {
+		t.tracer = tp.Tracer(InstrumentationName)
+	}
+	if mp == nil {
+		return t, nil
+	}
+	meter := mp.Meter(InstrumentationName)
+	var err error
+	if t.duration, err = meter.Float64Histogram("bitable.client.request.duration",
+		metric.WithUnit("s"), metric.WithDescription("duration of the open api calls")); err != nil {
+		return nil, err
+	}
+	if t.throttle, err = meter.Float64Counter("bitable.client.throttle.wait",
+		metric.WithUnit("s"), metric.WithDescription("time waiting for the rate limit of the open api")); err != nil {
+		return nil, err
+	}
+	if t.pages, err = meter.Int64Counter("bitable.client.pages",
+		metric.WithDescription("pages fetched by the list apis")); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Tracer return the tracer, nil when tracing is disabled.
+func (t *Telemetry) Tracer() trace.Tracer {
+	if t == nil {
+		return nil
+	}
+	return t.tracer
+}
+
+// callStats are the retries of an api call, counted by the retryTransport.
+type callStats struct {
+	retries  int
+	throttle time.Duration
+}
+
+type callStatsKey struct{}
+
+func callStatsOf(ctx context.Context) *callStats {
+	s, _ := ctx.Value(callStatsKey{}).(*callStats)
+	return s
+}
+
+// startCall start the span of an api call, the ctx count the retries of the call.
+func (t *Telemetry) startCall(ctx context.Context, api string) (context.Context, trace.Span, *callStats) {
+	stats := &callStats{}
+	ctx = context.WithValue(ctx, callStatsKey{}, stats)
+	if t == nil || t.tracer == nil {
+		return ctx, nil, stats
+	}
+	ctx, span := t.tracer.Start(ctx, "bitable "+api, trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("bitable.api", api)))
+	return ctx, span, stats
+}
+
+// endCall record the result of an api call.
+func (t *Telemetry) endCall(ctx context.Context, span trace.Span, api string, start time.Time,
+	stats *callStats, response *lark.Response, err error) {
+	if t == nil {
+		return
+	}
+	var attrs []attribute.KeyValue
+	var code int64
+	var larkErr *lark.Error
+	if response != nil {
+		method, path, logID := requestOf(response)
+		attrs = append(attrs, attribute.String("http.request.method", method),
+			attribute.String("url.path", path), attribute.String("bitable.log_id", logID))
+	}
+	if errors.As(err, &larkErr) {
+		code = larkErr.Code
+	}
+	attrs = append(attrs, attribute.Int64("bitable.code", code), attribute.Int("bitable.retries", stats.retries))
+	if t.duration != nil {
+		t.duration.Record(ctx, time.Since(start).Seconds(), metric.WithAttributes(
+			attribute.String("bitable.api", api), attribute.Int64("bitable.code", code)))
+		if stats.throttle > 0 {
+			t.throttle.Add(ctx, stats.throttle.Seconds(), metric.WithAttributes(attribute.String("bitable.api", api)))
+		}
+	}
+	if span == nil {
+		return
+	}
+	span.SetAttributes(attrs...)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// page count a page fetched by a list api.
+func (t *Telemetry) page(ctx context.Context, api string) {
+	if t == nil || t.pages == nil {
+		return
+	}
+	t.pages.Add(ctx, 1, metric.WithAttributes(attribute.String("bitable.api", api)))
+}
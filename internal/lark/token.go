@@ -0,0 +1,126 @@
+package lark
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/chyroc/lark"
+)
+
+// the codes of the rejected access tokens.
+var (
+	// staleTokenCodes are the tenant and app tokens rejected before the expiration, the cached tokens are cleared
+	staleTokenCodes = map[int64]bool{99991663: true, 99991664: true}
+	// invalidTokenCodes include the user tokens and the missing tokens
+	invalidTokenCodes = map[int64]bool{99991661: true, 99991663: true, 99991664: true, 99991668: true, 99991677: true}
+)
+
+// tenantTokenFuncs are the functions of the sdk getting the tenant and app tokens.
+var tenantTokenFuncs = map[string]bool{"GetTenantAccessToken": true, "GetAppAccessToken": true}
+
+// tokenScopes are the scopes of the lark errors getting the tenant, app and user tokens.
+var tokenScopes = map[string]bool{"Auth": true, "Token": true}
+
+// InvalidToken report whether the access token of err is rejected, or the app failed to get a token,
+// like a rotated app secret.
+func InvalidToken(err error) bool {
+	var e *lark.Error
+	if !errors.As(err, &e) {
+		return false
+	}
+	return tokenScopes[e.Scope] || invalidTokenCodes[e.Code]
+}
+
+// TenantTokenRejected report whether the tenant or app access token of err is rejected after getting new tokens,
+// or the app failed to get them. The rejected user tokens are the identity of the caller, they are not.
+func TenantTokenRejected(err error) bool {
+	var e *lark.Error
+	return errors.As(err, &e) && (tenantTokenFuncs[e.FuncName] || staleTokenCodes[e.Code])
+}
+
+func staleToken(err error) bool {
+	var e *lark.Error
+	return errors.As(err, &e) && staleTokenCodes[e.Code]
+}
+
+// tokenStore cache the tokens of an appID, shared by the clients of the appID.
+type tokenStore struct {
+	mu    sync.RWMutex
+	store lark.Store
+}
+
+func newTokenStore() *tokenStore {
+	return &tokenStore{store: lark.NewStoreMemory()}
+}
+
+func (s *tokenStore) current() lark.Store {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.store
+}
+
+func (s *tokenStore) Get(ctx context.Context, key string) (string, time.Duration, error) {
+	return s.current().Get(ctx, key)
+}
+
+func (s *tokenStore) Set(ctx context.Context, key, val string, ttl time.Duration) error {
+	return s.current().Set(ctx, key, val, ttl)
+}
+
+// reset drop the cached tokens, the next request get new tokens.
+func (s *tokenStore) reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.store = lark.NewStoreMemory()
+}
+
+// ResetToken drop the tokens of the app cached by all the clients of the appID.
+func (b *BiTable) ResetToken() {
+	getStoreSlot(b.appID).reset()
+}
+
+// writesKey is the context key of the writes of a statement.
+type writesKey struct{}
+
+// WithWrites track the requests of ctx which may have changed something, see Written.
+func WithWrites(ctx context.Context) context.Context {
+	return context.WithValue(ctx, writesKey{}, new(int32))
+}
+
+// Written report whether a request of ctx may have changed something.
+func Written(ctx context.Context) bool {
+	n, ok := ctx.Value(writesKey{}).(*int32)
+	return ok && atomic.LoadInt32(n) > 0
+}
+
+// wrote record a request of api, the reads and the requests rejected by the token changed nothing.
+func wrote(ctx context.Context, api string, err error) {
+	if strings.HasPrefix(api, "Get") || strings.HasPrefix(api, "BatchGet") || api == "RefreshAccessToken" ||
+		InvalidToken(err) {
+		return
+	}
+	if n, ok := ctx.Value(writesKey{}).(*int32); ok {
+		atomic.StoreInt32(n, 1)
+	}
+}
+
+// do call the api by fn, the request is sent again with new tokens when the cached token is rejected,
+// the rejected request didn't change anything.
+func (b *BiTable) do(ctx context.Context, api string, fn func(ctx context.Context) (*lark.Response, error)) error {
+	start := time.Now()
+	ctx, span, stats := b.telemetry.startCall(ctx, api)
+	response, err := fn(ctx)
+	if staleToken(err) {
+		b.ResetToken()
+		stats.retries++
+		response, err = fn(ctx)
+	}
+	wrote(ctx, api, err)
+	err = apiError(response, err)
+	b.telemetry.endCall(ctx, span, api, start, stats, response, err)
+	return err
+}